@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/resty.v1"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestAPI_Keys(t *testing.T) {
+	created := types.CreatedAPIKey{}
+	resp, err := resty.SetDebug(false).R().
+		SetAuthToken(adminKey).
+		SetBody(types.KeyCreateParams{Scopes: []types.Scope{types.ScopeServerWrite("s9.example.com:7777")}}).
+		SetResult(&created).
+		Post("http://localhost:8080/admin/keys")
+	assert.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode())
+	assert.NotEmpty(t, created.Key)
+
+	tests := []struct {
+		name       string
+		key        string
+		address    string
+		wantStatus int
+	}{
+		{"scoped", created.Key, "s9.example.com", 200},
+		{"other server", created.Key, "s8.example.com", 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := types.Server{}.Example()
+			server.Core.Address = tt.address
+			resp, err := resty.SetDebug(false).R().SetAuthToken(tt.key).SetBody(server).Patch("http://localhost:8080/v2/server")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode(), string(resp.Body()))
+		})
+	}
+
+	// server keys can't use the admin routes
+	resp, err = resty.SetDebug(false).R().SetAuthToken(created.Key).Get("http://localhost:8080/admin/keys")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).Delete(fmt.Sprintf("http://localhost:8080/admin/keys/%s", created.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().SetAuthToken(created.Key).SetBody(types.Server{}.Example()).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).Delete(fmt.Sprintf("http://localhost:8080/admin/keys/%s", created.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode())
+}
+
+func TestAPI_Moderation(t *testing.T) {
+	server := types.Server{}.Example()
+	server.Core.Address = "s7.example.com"
+	resp, err := resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+
+	description := "edited by an admin"
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).
+		SetBody(types.ServerMeta{Description: &description}).
+		Patch("http://localhost:8080/admin/server/s7.example.com:7777")
+	assert.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode(), string(resp.Body()))
+
+	got := types.Server{}
+	resp, err = resty.SetDebug(false).R().SetResult(&got).Get("http://localhost:8080/v2/server/s7.example.com:7777")
+	assert.NoError(t, err)
+	assert.Equal(t, description, got.Description)
+	assert.Equal(t, server.Banner, got.Banner)
+
+	statuses := []types.ServerStatus{}
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).SetResult(&statuses).Get("http://localhost:8080/admin/servers")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Contains(t, statusAddresses(statuses), "s7.example.com:7777")
+
+	result := types.BanResult{}
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).
+		SetBody(types.BanParams{Kind: types.BanAddress, Value: "s7.example.com:7777"}).
+		SetResult(&result).
+		Post("http://localhost:8080/admin/bans")
+	assert.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode(), string(resp.Body()))
+	assert.Equal(t, []string{"s7.example.com:7777"}, result.Removed)
+
+	statuses = []types.ServerStatus{}
+	_, err = resty.SetDebug(false).R().SetAuthToken(adminKey).SetResult(&statuses).Get("http://localhost:8080/admin/servers")
+	assert.NoError(t, err)
+	assert.NotContains(t, statusAddresses(statuses), "s7.example.com:7777")
+
+	// adding servers requires a key with the write scope
+	resp, err = resty.SetDebug(false).R().SetFormData(map[string]string{"address": "s7.example.com:7777"}).Post("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode())
+
+	// banned servers can't come back
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).SetFormData(map[string]string{"address": "s7.example.com:7777"}).Post("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+
+	hostname := types.BanResult{}
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).
+		SetBody(types.BanParams{Kind: types.BanRegex, Value: "(?i)free money"}).
+		SetResult(&hostname).
+		Post("http://localhost:8080/admin/bans")
+	assert.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode(), string(resp.Body()))
+
+	spam := types.Server{}.Example()
+	spam.Core.Address = "s6.example.com:7777"
+	spam.Core.Hostname = "FREE MONEY every hour"
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(spam).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+	assert.Contains(t, string(resp.Body()), "banned")
+
+	for _, id := range []string{result.ID, hostname.ID} {
+		resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).Delete(fmt.Sprintf("http://localhost:8080/admin/bans/%s", id))
+		assert.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode())
+	}
+}
+
+func statusAddresses(statuses []types.ServerStatus) (addresses []string) {
+	for _, status := range statuses {
+		addresses = append(addresses, status.Core.Address)
+	}
+	return
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"testing"
 	"time"
@@ -12,9 +13,12 @@ import (
 
 var app *server.App
 
+const adminKey = "test-admin-key"
+
 func TestMain(m *testing.M) {
 	config := types.Config{
 		Bind:            "localhost:8080",
+		Storage:         "memory",
 		MongoHost:       "localhost",
 		MongoPort:       "27017",
 		MongoName:       "samplist",
@@ -22,8 +26,10 @@ func TestMain(m *testing.M) {
 		MongoPass:       "",
 		MongoCollection: "servers",
 		QueryInterval:   time.Hour, // don't query during tests
+		QueryTimeout:    time.Second,
 		MaxFailedQuery:  0,
 		VerifyByHost:    false,
+		AdminKey:        adminKey,
 		LegacyList:      false,
 	}
 
@@ -36,6 +42,16 @@ func TestMain(m *testing.M) {
 	}
 	go app.Start() // start the server in a goroutine
 
+	// wait for the listener to come up before running any tests against it
+	for i := 0; i < 50; i++ {
+		conn, err := net.Dial("tcp", config.Bind)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+
 	ret := m.Run() // run the tests against the server
 	os.Exit(ret)
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/resty.v1"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestAPI_Masterlist(t *testing.T) {
+	for _, address := range []string{"s8.example.com:7777", "s9.example.com:7777"} {
+		server := types.Server{}.Example()
+		server.Core.Address = address
+		server.Core.Password = address == "s9.example.com:7777"
+		resp, err := resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode())
+	}
+
+	resp, err := resty.SetDebug(false).R().
+		SetAuthToken(adminKey).
+		SetBody(types.KeyCreateParams{Scopes: []types.Scope{types.ScopeServerWrite("s9.example.com:7777")}}).
+		Post("http://localhost:8080/admin/keys")
+	assert.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode())
+
+	tests := []struct {
+		name        string
+		path        string
+		wantHas     []string
+		wantMissing []string
+	}{
+		{"servers", "/0.3.7/servers", []string{"s8.example.com:7777", "s9.example.com:7777"}, nil},
+		{"servers filtered", "/0.3.7/servers?filters=password", []string{"s8.example.com:7777"}, []string{"s9.example.com:7777"}},
+		{"hosted", "/0.3.7/hosted", []string{"s9.example.com:7777"}, []string{"s8.example.com:7777"}},
+		{"hosted filtered", "/0.3.7/hosted?filters=password", nil, []string{"s8.example.com:7777", "s9.example.com:7777"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := resty.SetDebug(false).R().Get("http://localhost:8080" + tt.path)
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode())
+			assert.Equal(t, "text/plain; charset=utf-8", resp.Header().Get("Content-Type"))
+
+			lines := strings.Split(strings.TrimSuffix(string(resp.Body()), "\n"), "\n")
+			for _, address := range tt.wantHas {
+				assert.Contains(t, lines, address)
+			}
+			for _, address := range tt.wantMissing {
+				assert.NotContains(t, lines, address)
+			}
+		})
+	}
+}
+
+func TestAPI_MasterlistAnnounce(t *testing.T) {
+	tests := []struct {
+		name       string
+		port       string
+		wantStatus int
+	}{
+		{"not a number", "abc", 400},
+		{"reserved port", "80", 400},
+		{"no server", "7777", 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := resty.SetDebug(false).R().Get("http://localhost:8080/0.3.7/announce/" + tt.port)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode(), string(resp.Body()))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/resty.v1"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestAPI_Probe(t *testing.T) {
+	server := types.Server{}.Example()
+	server.Core.Address = "s6.example.com:7777"
+	resp, err := resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+
+	// probe results can't be set by the server owner
+	got := types.Server{}
+	_, err = resty.SetDebug(false).R().SetResult(&got).Get("http://localhost:8080/v2/server/s6.example.com:7777")
+	assert.NoError(t, err)
+	assert.Empty(t, got.Regions)
+
+	probe := types.CreatedAPIKey{}
+	resp, err = resty.SetDebug(false).R().
+		SetAuthToken(adminKey).
+		SetBody(types.KeyCreateParams{Scopes: []types.Scope{types.ScopeProbeRegion("eu-west")}}).
+		SetResult(&probe).
+		Post("http://localhost:8080/admin/keys")
+	assert.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode())
+
+	read := types.CreatedAPIKey{}
+	resp, err = resty.SetDebug(false).R().
+		SetAuthToken(adminKey).
+		SetBody(types.KeyCreateParams{Scopes: []types.Scope{types.ScopeRead}}).
+		SetResult(&read).
+		Post("http://localhost:8080/admin/keys")
+	assert.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().Get("http://localhost:8080/v2/probe/assignments?region=eu-west")
+	assert.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().SetAuthToken(read.Key).Get("http://localhost:8080/v2/probe/assignments?region=eu-west")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().SetAuthToken(probe.Key).Get("http://localhost:8080/v2/probe/assignments?region=EU")
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode())
+
+	// keys are bound to the region they were created for
+	resp, err = resty.SetDebug(false).R().SetAuthToken(probe.Key).Get("http://localhost:8080/v2/probe/assignments?region=us-east")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+
+	resp, err = resty.SetDebug(false).R().
+		SetAuthToken(probe.Key).
+		SetBody(types.ProbeReport{Region: "us-east", Results: []types.ProbeResult{{Address: "s6.example.com:7777", Reachable: true, Ping: 1}}}).
+		Post("http://localhost:8080/v2/probe/results")
+	assert.NoError(t, err)
+	assert.Equal(t, 403, resp.StatusCode())
+
+	assignments := types.ProbeAssignments{}
+	resp, err = resty.SetDebug(false).R().SetAuthToken(probe.Key).SetResult(&assignments).Get("http://localhost:8080/v2/probe/assignments?region=eu-west")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, "eu-west", assignments.Region)
+	assert.Contains(t, assignments.Addresses, "s6.example.com:7777")
+
+	result := types.ProbeReportResult{}
+	resp, err = resty.SetDebug(false).R().
+		SetAuthToken(probe.Key).
+		SetBody(types.ProbeReport{
+			Region: "eu-west",
+			Results: []types.ProbeResult{
+				{Address: "s6.example.com:7777", Reachable: true, Ping: 112},
+				{Address: "gone.example.com:7777", Reachable: false},
+			},
+		}).
+		SetResult(&result).
+		Post("http://localhost:8080/v2/probe/results")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode(), string(resp.Body()))
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, []string{"gone.example.com:7777"}, result.Ignored)
+
+	got = types.Server{}
+	_, err = resty.SetDebug(false).R().SetResult(&got).Get("http://localhost:8080/v2/server/s6.example.com:7777")
+	assert.NoError(t, err)
+	if assert.Contains(t, got.Regions, "eu-west") {
+		assert.True(t, got.Regions["eu-west"].Reachable)
+		assert.Equal(t, 112, got.Regions["eu-west"].Ping)
+	}
+
+	// the owner can still update the server without losing the results
+	resp, err = resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+
+	got = types.Server{}
+	_, err = resty.SetDebug(false).R().SetResult(&got).Get("http://localhost:8080/v2/server/s6.example.com:7777")
+	assert.NoError(t, err)
+	assert.Contains(t, got.Regions, "eu-west")
+}
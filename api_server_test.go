@@ -27,6 +27,7 @@ func TestAPI_ServerPostAddress(t *testing.T) {
 				SetDebug(false).
 				SetRedirectPolicy(resty.FlexibleRedirectPolicy(2)).
 				R().
+				SetAuthToken(adminKey).
 				SetFormData(map[string]string{"address": tt.args.address}).
 				Post("http://localhost:8080/v2/server")
 			if err != nil {
@@ -52,9 +53,9 @@ func TestAPI_ServerPost(t *testing.T) {
 	}{
 		{
 			"valid 1",
-			args{"ss.southcla.ws", types.Server{
+			args{"ss.southcla.ws:7777", types.Server{
 				Core: types.ServerCore{
-					Address:    "ss.southcla.ws",
+					Address:    "ss.southcla.ws:7777",
 					Hostname:   "Scavenge and Survive Official",
 					Players:    4,
 					MaxPlayers: 32,
@@ -69,9 +70,9 @@ func TestAPI_ServerPost(t *testing.T) {
 		},
 		{
 			"valid 2",
-			args{"s2.example.com", types.Server{
+			args{"s2.example.com:7777", types.Server{
 				Core: types.ServerCore{
-					Address:    "s2.example.com",
+					Address:    "s2.example.com:7777",
 					Hostname:   "test server 2",
 					Players:    0,
 					MaxPlayers: 100,
@@ -85,9 +86,9 @@ func TestAPI_ServerPost(t *testing.T) {
 		},
 		{
 			"valid 3",
-			args{"s3.example.com", types.Server{
+			args{"s3.example.com:7777", types.Server{
 				Core: types.ServerCore{
-					Address:    "s3.example.com",
+					Address:    "s3.example.com:7777",
 					Hostname:   "test server 3",
 					Players:    948,
 					MaxPlayers: 1000,
@@ -101,9 +102,9 @@ func TestAPI_ServerPost(t *testing.T) {
 		},
 		{
 			"valid 4",
-			args{"s4.example.com", types.Server{
+			args{"s4.example.com:7777", types.Server{
 				Core: types.ServerCore{
-					Address:    "s4.example.com",
+					Address:    "s4.example.com:7777",
 					Hostname:   "test server 4",
 					Players:    50,
 					MaxPlayers: 50,
@@ -118,7 +119,7 @@ func TestAPI_ServerPost(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp, err := resty.SetDebug(false).R().SetBody(tt.args.server).Patch(fmt.Sprintf("http://localhost:8080/v2/server"))
+			resp, err := resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(tt.args.server).Patch(fmt.Sprintf("http://localhost:8080/v2/server"))
 			if err != nil {
 				t.Errorf("/server POST failed: %v", err)
 			}
@@ -131,6 +132,27 @@ func TestAPI_ServerPost(t *testing.T) {
 	time.Sleep(time.Second)
 }
 
+func TestAPI_ServerPostUnauthorised(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{"no key", "", 401},
+		{"unknown key", "not-a-key", 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := resty.SetDebug(false).R().
+				SetHeader("X-API-Key", tt.key).
+				SetBody(types.Server{}.Example()).
+				Patch("http://localhost:8080/v2/server")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode())
+		})
+	}
+}
+
 func TestAPI_ServerGet(t *testing.T) {
 	type args struct {
 		address string
@@ -140,9 +162,9 @@ func TestAPI_ServerGet(t *testing.T) {
 		args       args
 		wantServer types.Server
 	}{
-		{"valid", args{"ss.southcla.ws"}, types.Server{
+		{"valid", args{"ss.southcla.ws:7777"}, types.Server{
 			Core: types.ServerCore{
-				Address:    "ss.southcla.ws",
+				Address:    "ss.southcla.ws:7777",
 				Hostname:   "Scavenge and Survive Official",
 				Players:    4,
 				MaxPlayers: 32,
@@ -166,3 +188,29 @@ func TestAPI_ServerGet(t *testing.T) {
 		})
 	}
 }
+
+func TestAPI_ServerSearch(t *testing.T) {
+	server := types.Server{}.Example()
+	server.Core.Address = "search.example.com:7777"
+	server.Core.Hostname = "[RU] {00FF00}Quixotic Roleplay"
+	resp, err := resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+
+	results := []types.SearchResult{}
+	resp, err = resty.SetDebug(false).R().SetResult(&results).Get("http://localhost:8080/v2/servers/search?q=quixotik")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, server.Core.Address, results[0].Core.Address)
+		assert.Equal(t, []types.Highlight{
+			{Field: "hostname", Text: "[RU] Quixotic Roleplay", Matches: [][2]int{{5, 13}}},
+		}, results[0].Highlights)
+	}
+
+	for _, query := range []string{"", "?q=", "?q=roleplay&limit=500", "?q=one+two+three+four+five+six+seven+eight+nine"} {
+		resp, err = resty.SetDebug(false).R().Get("http://localhost:8080/v2/servers/search" + query)
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode(), query)
+	}
+}
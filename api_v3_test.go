@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/resty.v1"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// page is a v3 listing response
+type page struct {
+	Data []types.ServerCore `json:"data"`
+	Meta types.Meta         `json:"meta"`
+}
+
+func TestAPI_V3ServerList(t *testing.T) {
+	for _, address := range []string{"v3a.example.com:7777", "v3b.example.com:7777", "v3c.example.com:7777"} {
+		server := types.Server{}.Example()
+		server.Core.Address = address
+		resp, err := resty.SetDebug(false).R().SetAuthToken(adminKey).SetBody(server).Patch("http://localhost:8080/v2/server")
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode())
+	}
+
+	// following the cursors visits every server exactly once
+	seen := map[string]bool{}
+	cursor := ""
+	for i := 0; i < 100; i++ {
+		got := page{}
+		resp, err := resty.SetDebug(false).R().
+			SetQueryParams(map[string]string{"limit": "2", "cursor": cursor}).
+			SetResult(&got).
+			Get("http://localhost:8080/v3/servers")
+		assert.NoError(t, err)
+		if !assert.Equal(t, 200, resp.StatusCode(), string(resp.Body())) {
+			return
+		}
+		assert.NotZero(t, got.Meta.Generated)
+		if assert.NotNil(t, got.Meta.Total) {
+			assert.True(t, *got.Meta.Total >= 3)
+		}
+		assert.True(t, len(got.Data) <= 2)
+		for _, core := range got.Data {
+			assert.False(t, seen[core.Address], "%s seen twice", core.Address)
+			seen[core.Address] = true
+		}
+		if got.Meta.Next == "" {
+			assert.Len(t, seen, *got.Meta.Total)
+			break
+		}
+		cursor = got.Meta.Next
+	}
+	assert.Contains(t, seen, "v3b.example.com:7777")
+}
+
+func TestAPI_V3Problems(t *testing.T) {
+	first := page{}
+	_, err := resty.SetDebug(false).R().SetResult(&first).Get("http://localhost:8080/v3/servers?limit=1")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"limit too large", "/v3/servers?limit=5000", 400},
+		{"negative limit", "/v3/servers?limit=-1", 400},
+		{"invalid sort", "/v3/servers?by=colour", 400},
+		{"malformed cursor", "/v3/servers?cursor=nope", 400},
+		{"cursor for other filters", "/v3/servers?filters=password&cursor=" + first.Meta.Next, 400},
+		{"invalid condition", "/v3/servers?filters=players~1", 400},
+		{"unknown server", "/v3/server/missing.example.com:7777", 404},
+		{"invalid address", "/v3/server/1.2.3.4:80/players", 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := resty.SetDebug(false).R().Get("http://localhost:8080" + tt.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode())
+			assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+
+			problem := types.Problem{}
+			assert.NoError(t, json.Unmarshal(resp.Body(), &problem))
+			assert.Equal(t, tt.wantStatus, problem.Status)
+			assert.NotEmpty(t, problem.Title)
+			assert.NotEmpty(t, problem.Detail)
+		})
+	}
+}
+
+func TestAPI_V3ServerGet(t *testing.T) {
+	got := struct {
+		Data types.Server `json:"data"`
+		Meta types.Meta   `json:"meta"`
+	}{}
+	resp, err := resty.SetDebug(false).R().SetResult(&got).Get("http://localhost:8080/v3/server/v3a.example.com:7777")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode())
+	assert.Equal(t, "v3a.example.com:7777", got.Data.Core.Address)
+	assert.Nil(t, got.Meta.Total)
+
+	resp, err = resty.SetDebug(false).R().SetHeader("X-API-Key", "not-a-key").Get("http://localhost:8080/v3/stats")
+	assert.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode())
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+}
@@ -0,0 +1,171 @@
+// Package cluster partitions the scraping work between API replicas. Each replica holds a lease in
+// storage that it renews with a heartbeat, the live replicas form a consistent hash ring and each
+// address is queried by the replica that owns it on the ring.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Config contains the membership settings of a replica
+type Config struct {
+	ID       string        // unique name of the replica, generated from the hostname if empty
+	Lease    time.Duration // how long a replica is considered alive after its last heartbeat
+	OnChange func()        // called after the set of live replicas changes, may be nil
+	OnError  func(error)   // called when a heartbeat fails, may be nil
+}
+
+// Membership maintains the lease of this replica and the ring of live replicas
+type Membership struct {
+	store   storage.Store
+	config  Config
+	started time.Time
+	lock    sync.RWMutex
+	members []string
+	ring    *Ring
+}
+
+// New registers this replica and builds the initial ring
+func New(store storage.Store, config Config) (membership *Membership, err error) {
+	if config.ID == "" {
+		config.ID, err = generateID()
+		if err != nil {
+			return
+		}
+	}
+	if config.Lease <= 0 {
+		config.Lease = time.Second * 30
+	}
+
+	membership = &Membership{
+		store:   store,
+		config:  config,
+		started: time.Now().UTC(),
+		ring:    NewRing(nil),
+	}
+	_, err = membership.Heartbeat(time.Now())
+	return
+}
+
+// ID returns the name of this replica
+func (m *Membership) ID() string {
+	return m.config.ID
+}
+
+// Run renews the lease three times per lease period until the context is cancelled, then removes
+// the lease so the other replicas take over straight away instead of waiting for it to expire.
+func (m *Membership) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.store.RemoveMember(m.config.ID) // nolint:errcheck
+			return
+		case now := <-ticker.C:
+			changed, err := m.Heartbeat(now)
+			if err != nil {
+				if m.config.OnError != nil {
+					m.config.OnError(err)
+				}
+				continue
+			}
+			if changed && m.config.OnChange != nil {
+				m.config.OnChange()
+			}
+		}
+	}
+}
+
+// Heartbeat renews the lease of this replica, clears out expired leases and rebuilds the ring if
+// the set of live replicas has changed.
+func (m *Membership) Heartbeat(now time.Time) (changed bool, err error) {
+	err = m.store.PutMember(types.Member{
+		ID:      m.config.ID,
+		Started: m.started,
+		Expires: now.Add(m.config.Lease).UTC(),
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to renew lease")
+	}
+
+	all, err := m.store.GetMembers()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get members")
+	}
+
+	live := []string{}
+	for _, member := range all {
+		if member.Expires.After(now) {
+			live = append(live, member.ID)
+			continue
+		}
+		// any replica may clear out an expired lease, losing the race to another one is harmless
+		m.store.RemoveMember(member.ID) // nolint:errcheck
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if equal(live, m.members) {
+		return false, nil
+	}
+	m.members = live
+	m.ring = NewRing(live)
+	return true, nil
+}
+
+// Owns checks if this replica is responsible for a key such as a server address. A replica that
+// has lost contact with storage keeps its last view of the ring, its lease expires for the others
+// so there may be duplicate work but nothing is left unowned.
+func (m *Membership) Owns(key string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.ring.Owner(key) == m.config.ID
+}
+
+// Members returns the IDs of the live replicas
+func (m *Membership) Members() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return append([]string{}, m.members...)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// generateID names a replica after its host with a random suffix so restarted or duplicated
+// containers never share an ID
+func generateID() (string, error) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "api"
+	}
+	suffix := make([]byte, 3)
+	if _, err = rand.Read(suffix); err != nil {
+		return "", errors.Wrap(err, "failed to generate member ID")
+	}
+	return host + "-" + hex.EncodeToString(suffix), nil
+}
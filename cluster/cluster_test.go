@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/storage"
+)
+
+func TestMembership(t *testing.T) {
+	store := storage.NewMemory()
+	lease := time.Second * 30
+
+	a, err := New(store, Config{ID: "a", Lease: lease})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, a.Members())
+	for _, address := range addresses(100) {
+		assert.True(t, a.Owns(address))
+	}
+
+	b, err := New(store, Config{ID: "b", Lease: lease})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, b.Members())
+
+	now := time.Now()
+	changed, err := a.Heartbeat(now)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = a.Heartbeat(now)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// each address is queried by exactly one replica
+	owned := 0
+	for _, address := range addresses(100) {
+		assert.NotEqual(t, a.Owns(address), b.Owns(address), address)
+		if a.Owns(address) {
+			owned++
+		}
+	}
+	assert.True(t, owned > 0 && owned < 100)
+
+	// b stops sending heartbeats and a takes over once the lease expires
+	changed, err = a.Heartbeat(now.Add(lease / 2))
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	changed, err = a.Heartbeat(now.Add(lease * 2))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"a"}, a.Members())
+	for _, address := range addresses(100) {
+		assert.True(t, a.Owns(address))
+	}
+
+	members, err := store.GetMembers()
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "a", members[0].ID)
+	}
+}
+
+func TestGenerateID(t *testing.T) {
+	first, err := generateID()
+	assert.NoError(t, err)
+	second, err := generateID()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
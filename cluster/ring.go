@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// Replicas is the number of points each member has on the ring, more points spread the addresses
+// more evenly between members at the cost of a larger ring.
+const Replicas = 128
+
+// Ring is a consistent hash ring, each key belongs to the first member point clockwise from the
+// hash of the key. When a member joins or leaves only the keys next to its points change owner.
+type Ring struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+// NewRing builds a ring from a set of member IDs
+func NewRing(members []string) *Ring {
+	ring := &Ring{
+		points: make([]uint64, 0, len(members)*Replicas),
+		owners: make(map[uint64]string, len(members)*Replicas),
+	}
+	for _, member := range members {
+		for i := 0; i < Replicas; i++ {
+			point := hash(member + "#" + strconv.Itoa(i))
+			if owner, ok := ring.owners[point]; !ok {
+				ring.points = append(ring.points, point)
+			} else if owner < member {
+				continue // collisions go to the lowest ID so every replica builds the same ring
+			}
+			ring.owners[point] = member
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// Owner returns the member responsible for a key, or an empty string if the ring is empty
+func (ring *Ring) Owner(key string) string {
+	if len(ring.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(ring.points), func(i int) bool { return ring.points[i] >= h })
+	if i == len(ring.points) {
+		i = 0
+	}
+	return ring.owners[ring.points[i]]
+}
+
+func hash(key string) uint64 {
+	sum := sha1.Sum([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
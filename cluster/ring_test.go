@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addresses(n int) (result []string) {
+	for i := 0; i < n; i++ {
+		result = append(result, fmt.Sprintf("10.0.%d.%d:7777", i/256, i%256))
+	}
+	return
+}
+
+func TestRing_Owner(t *testing.T) {
+	assert.Equal(t, "", NewRing(nil).Owner("127.0.0.1:7777"))
+	assert.Equal(t, "a", NewRing([]string{"a"}).Owner("127.0.0.1:7777"))
+
+	// every replica builds the same ring regardless of the order it sees the members in
+	abc, cba := NewRing([]string{"a", "b", "c"}), NewRing([]string{"c", "b", "a"})
+	counts := make(map[string]int)
+	for _, address := range addresses(3000) {
+		owner := abc.Owner(address)
+		assert.Equal(t, owner, cba.Owner(address))
+		counts[owner]++
+	}
+
+	// the addresses are spread roughly evenly
+	assert.Len(t, counts, 3)
+	for member, count := range counts {
+		assert.InDelta(t, 1000, count, 250, member)
+	}
+}
+
+func TestRing_Rebalance(t *testing.T) {
+	before := NewRing([]string{"a", "b", "c"})
+	after := NewRing([]string{"a", "b", "c", "d"})
+
+	moved := 0
+	for _, address := range addresses(3000) {
+		from, to := before.Owner(address), after.Owner(address)
+		if from != to {
+			moved++
+			assert.Equal(t, "d", to, "addresses only move to the new member")
+		}
+	}
+	assert.InDelta(t, 750, moved, 250)
+
+	// when a member dies only its addresses move
+	without := NewRing([]string{"a", "c"})
+	for _, address := range addresses(3000) {
+		if owner := before.Owner(address); owner != "b" {
+			assert.Equal(t, owner, without.Owner(address))
+		}
+	}
+}
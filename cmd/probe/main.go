@@ -0,0 +1,85 @@
+// Command probe is a standalone agent that measures the reachability and latency of the servers in
+// the index from the region it runs in and reports the results back to the API.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	// loads environment variables from .env
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+
+	"github.com/Southclaws/samp-servers-api/probe"
+)
+
+var version = "master"
+
+// Config stores the agent configuration, read from environment variables prefixed with `PROBE_`
+type Config struct {
+	Endpoint string        `split_words:"true" required:"true"`
+	Key      string        `split_words:"true" required:"true"`
+	Region   string        `split_words:"true" required:"true"`
+	Interval time.Duration `split_words:"true" default:"30s"`
+	Timeout  time.Duration `split_words:"true" default:"10s"`
+	Workers  int           `split_words:"true" default:"16"`
+	Rate     float64       `split_words:"true" default:"200"`
+	HostRate float64       `split_words:"true" default:"20"`
+}
+
+func main() {
+	config := Config{}
+	err := envconfig.Process("PROBE", &config)
+	if err != nil {
+		panic(err)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	logger = logger.With(
+		zap.String("@version", version),
+		zap.String("region", config.Region))
+
+	engine, err := probe.NewEngine(probe.EngineConfig{
+		Timeout:  config.Timeout,
+		Rate:     config.Rate,
+		HostRate: config.HostRate,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer engine.Close() // nolint:errcheck
+
+	agent, err := probe.NewAgent(probe.Config{
+		Endpoint:     config.Endpoint,
+		Key:          config.Key,
+		Region:       config.Region,
+		Interval:     config.Interval,
+		Timeout:      config.Timeout,
+		Workers:      config.Workers,
+		Querier:      engine,
+		PingFunction: engine.GetPing,
+		Logger:       logger,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger.Info("starting probe agent",
+		zap.String("endpoint", config.Endpoint))
+	agent.Run(ctx)
+}
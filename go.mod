@@ -4,7 +4,6 @@ go 1.14
 
 require (
 	github.com/Southclaws/go-samp-query v1.1.2
-	github.com/Southclaws/tickerpool v0.0.0-20170828114622-8030a05342dc
 	github.com/alecthomas/template v0.0.0-20160405071501-a0175ee3bccc
 	github.com/dyninc/qstring v0.0.0-20160719172318-ab5840a88e81
 	github.com/gorilla/handlers v1.4.1
@@ -12,11 +11,15 @@ require (
 	github.com/joho/godotenv v1.3.0
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/kr/pretty v0.1.0 // indirect
+	github.com/lib/pq v1.10.9
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v0.9.2
+	github.com/saintfish/chardet v0.0.0-20120816061221-3af4cd4741ca
 	github.com/stretchr/testify v1.4.0
+	go.etcd.io/bbolt v1.3.6
 	go.uber.org/zap v1.14.1
 	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	golang.org/x/text v0.3.0
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/mgo.v2 v2.0.0-20160818020120-3f83fa500528
 	gopkg.in/resty.v1 v1.12.0
@@ -0,0 +1,163 @@
+// Package heuristics flags servers that appear to be inflating their player count to climb the
+// default sort order. None of the checks are proof on their own so servers are flagged rather than
+// removed, clients can hide them with the `suspicious` list filter.
+package heuristics
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Config contains the thresholds for each check
+type Config struct {
+	PlayerListTolerance int           // players the list may differ from the count by, or 10% if larger
+	FlatWindow          time.Duration // how far back to look for a flat player count
+	FlatMinSamples      int           // samples required within the window before judging a curve
+	FlatMinPlayers      int           // player count below which flat curves are normal
+	SharedIPMinServers  int           // servers on one IP with the same count before it's suspicious
+}
+
+// DefaultConfig is tuned to avoid flagging genuine servers
+var DefaultConfig = Config{
+	PlayerListTolerance: 2,
+	FlatWindow:          6 * time.Hour,
+	FlatMinSamples:      12,
+	FlatMinPlayers:      10,
+	SharedIPMinServers:  3,
+}
+
+// Engine runs the checks, the shared IP check needs the whole index so its results are computed
+// periodically via UpdateShared and applied to each server on its next query.
+type Engine struct {
+	config Config
+	lock   sync.RWMutex
+	shared map[string]bool // addresses flagged by the last shared IP check
+}
+
+// New creates an engine with the given thresholds
+func New(config Config) *Engine {
+	return &Engine{
+		config: config,
+		shared: make(map[string]bool),
+	}
+}
+
+// Window returns how much raw history Check needs for each server
+func (e *Engine) Window() time.Duration {
+	return e.config.FlatWindow
+}
+
+// Check returns every reason a freshly queried server is suspicious, `history` should contain the
+// server's raw player count samples covering at least the configured flat window.
+func (e *Engine) Check(server types.Server, history []types.Sample) (reasons []types.Suspicion) {
+	if e.PlayerListMismatch(server.Core, server.PlayerList) {
+		reasons = append(reasons, types.SuspicionPlayerList)
+	}
+	if e.FlatCurve(history) {
+		reasons = append(reasons, types.SuspicionFlatCurve)
+	}
+
+	e.lock.RLock()
+	shared := e.shared[server.Core.Address]
+	e.lock.RUnlock()
+	if shared {
+		reasons = append(reasons, types.SuspicionSharedIP)
+	}
+	return
+}
+
+// PlayerListMismatch compares the count from the info packet against the number of clients in the
+// player list. Lists that weren't collected or that SA:MP refused to send can't be compared.
+func (e *Engine) PlayerListMismatch(core types.ServerCore, list *types.PlayerList) bool {
+	if list == nil || !list.Available {
+		return false
+	}
+
+	tolerance := e.config.PlayerListTolerance
+	if percent := core.Players / 10; percent > tolerance {
+		tolerance = percent
+	}
+
+	difference := core.Players - len(list.Players)
+	if difference < 0 {
+		difference = -difference
+	}
+	return difference > tolerance
+}
+
+// FlatCurve checks if a busy server's player count has been exactly the same for the whole window,
+// genuine servers always have players coming and going.
+func (e *Engine) FlatCurve(history []types.Sample) bool {
+	if len(history) < e.config.FlatMinSamples {
+		return false
+	}
+
+	latest := history[len(history)-1].Time
+	if latest.Sub(history[0].Time) < e.config.FlatWindow*3/4 {
+		return false // not enough coverage, the server is new or was offline
+	}
+
+	first := history[0]
+	if first.Value < float64(e.config.FlatMinPlayers) {
+		return false
+	}
+	for _, sample := range history {
+		if sample.Min != first.Value || sample.Max != first.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateShared finds groups of active servers that resolve to the same IP and report the same
+// non-zero player count, every server in a large enough group is flagged.
+func (e *Engine) UpdateShared(servers []types.Server) {
+	type group struct {
+		ip      string
+		players int
+	}
+	groups := make(map[group][]string)
+	for _, server := range servers {
+		if !server.Active || server.Core.Players == 0 {
+			continue
+		}
+		ip := server.IP
+		if ip == "" {
+			if host, _, err := net.SplitHostPort(server.Core.Address); err == nil {
+				ip = host
+			}
+		}
+		key := group{ip, server.Core.Players}
+		groups[key] = append(groups[key], server.Core.Address)
+	}
+
+	shared := make(map[string]bool)
+	for _, addresses := range groups {
+		if len(addresses) < e.config.SharedIPMinServers {
+			continue
+		}
+		for _, address := range addresses {
+			shared[address] = true
+		}
+	}
+
+	e.lock.Lock()
+	e.shared = shared
+	e.lock.Unlock()
+}
+
+// Shared returns the addresses flagged by the last shared IP check
+func (e *Engine) Shared() (addresses []string) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	for address := range e.shared {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return
+}
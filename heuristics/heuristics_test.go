@@ -0,0 +1,115 @@
+package heuristics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func players(n int) *types.PlayerList {
+	list := &types.PlayerList{Available: true}
+	for i := 0; i < n; i++ {
+		list.Players = append(list.Players, types.Player{Name: "player"})
+	}
+	return list
+}
+
+func curve(values ...float64) (samples []types.Sample) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := DefaultConfig.FlatWindow / time.Duration(len(values)-1)
+	for i, value := range values {
+		samples = append(samples, types.Sample{Time: start.Add(step * time.Duration(i)), Value: value, Min: value, Max: value, Count: 1})
+	}
+	return
+}
+
+func repeat(value float64, n int) (values []float64) {
+	for i := 0; i < n; i++ {
+		values = append(values, value)
+	}
+	return
+}
+
+func TestEngine_PlayerListMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		players int
+		list    *types.PlayerList
+		want    bool
+	}{
+		{"no list", 500, nil, false},
+		{"unavailable", 500, &types.PlayerList{Available: false}, false},
+		{"exact", 20, players(20), false},
+		{"within tolerance", 20, players(18), false},
+		{"within percentage", 200, players(185), false},
+		{"inflated", 20, players(5), true},
+		{"inflated large", 200, players(150), true},
+		{"deflated", 0, players(10), true},
+	}
+	e := New(DefaultConfig)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.PlayerListMismatch(types.ServerCore{Players: tt.players}, tt.list)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEngine_FlatCurve(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []types.Sample
+		want    bool
+	}{
+		{"no history", nil, false},
+		{"too few samples", curve(repeat(100, 5)...), false},
+		{"flat", curve(repeat(100, 36)...), true},
+		{"flat but quiet", curve(repeat(3, 36)...), false},
+		{"varied", curve(append(repeat(100, 35), 101)...), false},
+		{"short coverage", curve(repeat(100, 36)...)[:12], false},
+	}
+	e := New(DefaultConfig)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.FlatCurve(tt.history)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEngine_UpdateShared(t *testing.T) {
+	server := func(address, ip string, players int, active bool) types.Server {
+		return types.Server{Core: types.ServerCore{Address: address, Players: players}, IP: ip, Active: active}
+	}
+
+	e := New(DefaultConfig)
+	e.UpdateShared([]types.Server{
+		server("1.1.1.1:7777", "1.1.1.1", 80, true),
+		server("1.1.1.1:7778", "1.1.1.1", 80, true),
+		server("farm.example.com:7779", "1.1.1.1", 80, true),
+		server("1.1.1.1:7780", "1.1.1.1", 12, true),
+		server("2.2.2.2:7777", "", 50, true),
+		server("2.2.2.2:7778", "", 50, true),
+		server("2.2.2.2:7779", "", 50, false),
+		server("3.3.3.3:7777", "3.3.3.3", 0, true),
+		server("3.3.3.3:7778", "3.3.3.3", 0, true),
+		server("3.3.3.3:7779", "3.3.3.3", 0, true),
+	})
+	assert.Equal(t, []string{"1.1.1.1:7777", "1.1.1.1:7778", "farm.example.com:7779"}, e.Shared())
+
+	got := e.Check(server("1.1.1.1:7778", "1.1.1.1", 80, true), nil)
+	assert.Equal(t, []types.Suspicion{types.SuspicionSharedIP}, got)
+
+	got = e.Check(types.Server{
+		Core:       types.ServerCore{Address: "1.1.1.1:7777", Players: 80},
+		PlayerList: players(10),
+	}, curve(repeat(80, 36)...))
+	assert.Equal(t, []types.Suspicion{types.SuspicionPlayerList, types.SuspicionFlatCurve, types.SuspicionSharedIP}, got)
+
+	e.UpdateShared(nil)
+	assert.Empty(t, e.Shared())
+	assert.Empty(t, e.Check(server("1.1.1.1:7778", "1.1.1.1", 80, true), nil))
+}
@@ -0,0 +1,169 @@
+// Package masterlist fetches lists of server addresses from other server browsers so servers that
+// haven't been added to the index directly are still found.
+package masterlist
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// List is the result of reading a masterlist
+type List struct {
+	Addresses []string // valid addresses, without duplicates
+	Invalid   []error  // one error for each entry that wasn't a valid address
+}
+
+// Entries returns how many entries the list contained
+func (list List) Entries() int {
+	return len(list.Addresses) + len(list.Invalid)
+}
+
+// MaxSize is the largest masterlist that will be read, the SA:MP list is well under a megabyte so
+// anything bigger is a misbehaving source
+const MaxSize = 16 << 20
+
+// Fetch downloads and parses a masterlist. Invalid entries are skipped unless there are more of them
+// than the source tolerates, in which case the list is probably broken and an error is returned
+// along with what was parsed so the invalid entries can still be counted.
+func Fetch(ctx context.Context, client *http.Client, source types.MasterlistSource) (list List, err error) {
+	req, err := http.NewRequest("GET", source.URL, nil)
+	if err != nil {
+		return list, errors.Wrap(err, "failed to create masterlist request")
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return list, errors.Wrap(err, "failed to get masterlist")
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return list, errors.Errorf("unexpected masterlist status %s", resp.Status)
+	}
+
+	body := &io.LimitedReader{R: resp.Body, N: MaxSize + 1}
+	list, err = Parse(source, body)
+	if body.N <= 0 {
+		return List{}, errors.Errorf("masterlist is larger than %d bytes", MaxSize)
+	}
+	if err != nil {
+		return
+	}
+	if entries := list.Entries(); entries > 0 && float64(len(list.Invalid)) > float64(entries)*source.Tolerance {
+		return list, errors.Errorf("%d of %d entries are invalid", len(list.Invalid), entries)
+	}
+	return
+}
+
+// Parse reads the addresses from a masterlist in the format of the source
+func Parse(source types.MasterlistSource, r io.Reader) (list List, err error) {
+	var entries []string
+	switch source.Format {
+	case types.MasterlistText:
+		entries, err = parseText(r)
+	case types.MasterlistHosted:
+		entries, err = parseJSON(r, "", "")
+	case types.MasterlistOpenMP:
+		entries, err = parseJSON(r, "", "ip")
+	case types.MasterlistJSON:
+		entries, err = parseJSON(r, source.Path, source.Field)
+	default:
+		err = errors.Errorf("unknown masterlist format '%s'", source.Format)
+	}
+	if err != nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		address, errs := types.AddressFromString(entry)
+		if errs != nil {
+			list.Invalid = append(list.Invalid, errors.Wrapf(errs[0], "invalid address '%s'", entry))
+			continue
+		}
+		if !validHost(address) {
+			list.Invalid = append(list.Invalid, errors.Errorf("invalid host in address '%s'", entry))
+			continue
+		}
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		list.Addresses = append(list.Addresses, address)
+	}
+	return
+}
+
+// validHost checks the host of an address is an IP or a hostname, AddressFromString accepts any
+// text as a host so without this an HTML error page served as a list would be read as addresses.
+func validHost(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseText reads one address per line, blank lines are ignored
+func parseText(r io.Reader) (entries []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read masterlist")
+	}
+	return
+}
+
+// parseJSON finds the array at path and reads the address from each element, the elements are
+// addresses themselves when field is empty, otherwise objects with the address under field. An
+// element of the wrong type is an invalid entry rather than a failure of the whole list.
+func parseJSON(r io.Reader, path, field string) (entries []string, err error) {
+	var root interface{}
+	if err = json.NewDecoder(r).Decode(&root); err != nil {
+		return nil, errors.Wrap(err, "failed to decode masterlist")
+	}
+
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			object, ok := root.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("masterlist has no object at '%s'", key)
+			}
+			root = object[key]
+		}
+	}
+	elements, ok := root.([]interface{})
+	if !ok {
+		return nil, errors.New("masterlist is not an array")
+	}
+
+	for _, element := range elements {
+		if field != "" {
+			object, _ := element.(map[string]interface{})
+			element = object[field]
+		}
+		address, _ := element.(string)
+		entries = append(entries, address) // an empty address is counted as invalid
+	}
+	return
+}
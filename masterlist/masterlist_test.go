@@ -0,0 +1,134 @@
+package masterlist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    types.MasterlistSource
+		body      string
+		want      []string
+		wantBad   int
+		wantError bool
+	}{
+		{
+			"text",
+			types.MasterlistSource{Format: types.MasterlistText},
+			"1.2.3.4:7777\n\n5.6.7.8\r\n1.2.3.4:7777\n",
+			[]string{"1.2.3.4:7777", "5.6.7.8:7777"}, 0, false,
+		},
+		{
+			"text invalid line",
+			types.MasterlistSource{Format: types.MasterlistText},
+			"1.2.3.4:7777\nhttp://nope\n1.2.3.4:80\n5.6.7.8:7778\n",
+			[]string{"1.2.3.4:7777", "5.6.7.8:7778"}, 2, false,
+		},
+		{
+			"hosted",
+			types.MasterlistSource{Format: types.MasterlistHosted},
+			`["1.2.3.4:7777", 5, "5.6.7.8:7778"]`,
+			[]string{"1.2.3.4:7777", "5.6.7.8:7778"}, 1, false,
+		},
+		{
+			"hosted not an array",
+			types.MasterlistSource{Format: types.MasterlistHosted},
+			`{"servers": []}`,
+			nil, 0, true,
+		},
+		{
+			"openmp",
+			types.MasterlistSource{Format: types.MasterlistOpenMP},
+			`[{"ip": "1.2.3.4:7777", "hn": "a", "omp": true}, {"hn": "b"}, {"ip": "5.6.7.8:7777", "hn": "c"}]`,
+			[]string{"1.2.3.4:7777", "5.6.7.8:7777"}, 1, false,
+		},
+		{
+			"json",
+			types.MasterlistSource{Format: types.MasterlistJSON, Path: "data.servers", Field: "address"},
+			`{"data": {"servers": [{"address": "1.2.3.4:7777"}, "5.6.7.8:7777"]}}`,
+			[]string{"1.2.3.4:7777"}, 1, false,
+		},
+		{
+			"json missing path",
+			types.MasterlistSource{Format: types.MasterlistJSON, Path: "data.servers", Field: "address"},
+			`{"data": []}`,
+			nil, 0, true,
+		},
+		{
+			"malformed",
+			types.MasterlistSource{Format: types.MasterlistOpenMP},
+			`<html>`,
+			nil, 0, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.source, strings.NewReader(tt.body))
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Addresses)
+			assert.Len(t, got.Invalid, tt.wantBad)
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good":
+			w.Write([]byte("1.2.3.4:7777\n5.6.7.8:7777\n9.9.9.9:7777\nnot an address:1\n")) // nolint:errcheck
+		case "/broken":
+			w.Write([]byte("1.2.3.4:7777\n<html>\n<body>\n")) // nolint:errcheck
+		case "/huge":
+			line := []byte("1.2.3.4:7777\n")
+			for written := 0; written <= MaxSize; written += len(line) {
+				w.Write(line) // nolint:errcheck
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		tolerance float64
+		wantCount int
+		wantError bool
+	}{
+		{"tolerated", "/good", 0.25, 3, false},
+		{"too many invalid", "/good", 0.1, 3, true},
+		{"broken", "/broken", 0.5, 1, true},
+		{"not found", "/missing", 1, 0, true},
+		{"too large", "/huge", 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fetch(context.Background(), ts.Client(), types.MasterlistSource{
+				Name:      tt.name,
+				URL:       ts.URL + tt.path,
+				Format:    types.MasterlistText,
+				Tolerance: tt.tolerance,
+			})
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, got.Addresses, tt.wantCount)
+		})
+	}
+}
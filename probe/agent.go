@@ -0,0 +1,234 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Config contains the settings of a probe agent
+type Config struct {
+	Endpoint     string        // base URL of the API, such as `https://api.samp-servers.net`
+	Key          string        // API key with the `probe:<region>` scope
+	Region       string        // name of the region the agent runs in, such as `eu-west`
+	Interval     time.Duration // interval between rounds of queries
+	Timeout      time.Duration // timeout for querying a single server
+	Workers      int           // number of servers queried at once
+	Querier      Querier       // sends the queries, usually an Engine
+	PingFunction PingFunction  // function for measuring latency
+	Logger       *zap.Logger   // may be nil
+}
+
+// Agent queries the servers assigned to it by the API from its own region and reports whether each
+// was reachable along with the rolling median of its latency.
+type Agent struct {
+	config  Config
+	client  *http.Client
+	lock    sync.Mutex
+	latency map[string]*Latency
+}
+
+// NewAgent validates the config and creates an agent
+func NewAgent(config Config) (agent *Agent, err error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("no API endpoint specified")
+	}
+	if config.Key == "" {
+		return nil, errors.New("no API key specified")
+	}
+	if err = types.ValidateRegion(config.Region); err != nil {
+		return nil, err
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Second * 30
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second * 10
+	}
+	if config.Workers <= 0 {
+		config.Workers = 16
+	}
+	if config.Querier == nil {
+		return nil, errors.New("no querier specified")
+	}
+	if config.PingFunction == nil {
+		return nil, errors.New("no ping function specified")
+	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+
+	return &Agent{
+		config:  config,
+		client:  &http.Client{Timeout: time.Second * 30},
+		latency: make(map[string]*Latency),
+	}, nil
+}
+
+// Run queries the assigned servers every interval until the context is cancelled, a failed round is
+// logged and retried on the next interval.
+func (agent *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(agent.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := agent.Round(ctx)
+		if err != nil {
+			agent.config.Logger.Error("probe round failed",
+				zap.Error(err))
+		} else {
+			agent.config.Logger.Debug("probe round complete",
+				zap.Int("accepted", result.Accepted),
+				zap.Int("ignored", len(result.Ignored)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Round fetches the current assignments, queries each server and reports the results
+func (agent *Agent) Round(ctx context.Context) (result types.ProbeReportResult, err error) {
+	var assignments types.ProbeAssignments
+	err = agent.request(ctx, "GET", "/v2/probe/assignments?region="+agent.config.Region, nil, &assignments)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get assignments")
+	}
+
+	report := types.ProbeReport{
+		Region:  agent.config.Region,
+		Results: agent.Measure(ctx, assignments.Addresses),
+	}
+	agent.forget(assignments.Addresses)
+
+	err = agent.request(ctx, "POST", "/v2/probe/results", report, &result)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to report results")
+	}
+	return
+}
+
+// Measure queries each address using the configured number of workers, results are in the same
+// order as the addresses.
+func (agent *Agent) Measure(ctx context.Context, addresses []string) []types.ProbeResult {
+	results := make([]types.ProbeResult, len(addresses))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < agent.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = agent.measure(ctx, addresses[index])
+			}
+		}()
+	}
+	for i := range addresses {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// measure checks a single server responds to the info query then pings it, a server that doesn't
+// respond is reported as unreachable with the median of any earlier measurements.
+func (agent *Agent) measure(ctx context.Context, address string) types.ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, agent.config.Timeout)
+	defer cancel()
+
+	latency := agent.latencyOf(address)
+	result := types.ProbeResult{Address: address}
+
+	_, err := agent.config.Querier.Query(ctx, address, sampquery.Info)
+	if err != nil {
+		result.Ping = latency.Median()
+		return result
+	}
+	result.Reachable = true
+
+	ping, err := agent.config.PingFunction(ctx, address)
+	if err != nil {
+		result.Ping = latency.Median()
+		return result
+	}
+	result.Ping = latency.Add(ping)
+	return result
+}
+
+func (agent *Agent) latencyOf(address string) *Latency {
+	agent.lock.Lock()
+	defer agent.lock.Unlock()
+
+	latency, ok := agent.latency[address]
+	if !ok {
+		latency = &Latency{}
+		agent.latency[address] = latency
+	}
+	return latency
+}
+
+// forget drops the measurements of servers that are no longer assigned to the agent
+func (agent *Agent) forget(assigned []string) {
+	agent.lock.Lock()
+	defer agent.lock.Unlock()
+
+	keep := make(map[string]bool, len(assigned))
+	for _, address := range assigned {
+		keep[address] = true
+	}
+	for address := range agent.latency {
+		if !keep[address] {
+			delete(agent.latency, address)
+		}
+	}
+}
+
+// request makes an authenticated request to the API and decodes the JSON response into `out`
+func (agent *Agent) request(ctx context.Context, method, path string, in, out interface{}) (err error) {
+	var body bytes.Buffer
+	if in != nil {
+		if err = json.NewEncoder(&body).Encode(in); err != nil {
+			return
+		}
+	}
+
+	req, err := http.NewRequest(method, agent.config.Endpoint+path, &body)
+	if err != nil {
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+agent.config.Key)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := agent.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestAgent_Round(t *testing.T) {
+	var reports []types.ProbeReport
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer probe-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/probe/assignments":
+			assert.Equal(t, "eu-west", r.URL.Query().Get("region"))
+			json.NewEncoder(w).Encode(types.ProbeAssignments{ // nolint:errcheck
+				Region:    "eu-west",
+				Addresses: []string{"up.example.com:7777", "down.example.com:7777", "slow.example.com:7777"},
+			})
+		case "/v2/probe/results":
+			var report types.ProbeReport
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+			reports = append(reports, report)
+			json.NewEncoder(w).Encode(types.ProbeReportResult{Accepted: len(report.Results)}) // nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	var lock sync.Mutex
+	pings := map[string][]time.Duration{
+		"up.example.com:7777":   {20 * time.Millisecond, 90 * time.Millisecond, 22 * time.Millisecond},
+		"slow.example.com:7777": {300 * time.Millisecond},
+	}
+	agent, err := NewAgent(Config{
+		Endpoint: api.URL + "/",
+		Key:      "probe-key",
+		Region:   "eu-west",
+		Workers:  2,
+		Querier: QuerierFunc(func(ctx context.Context, address string, opcode sampquery.QueryType) ([]byte, error) {
+			if address == "down.example.com:7777" {
+				return nil, errors.New("timeout")
+			}
+			return packet(byte(opcode)), nil
+		}),
+		PingFunction: func(ctx context.Context, address string) (time.Duration, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if len(pings[address]) == 0 {
+				return 0, errors.New("timeout")
+			}
+			ping := pings[address][0]
+			pings[address] = pings[address][1:]
+			return ping, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		result, err := agent.Round(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result.Accepted)
+	}
+
+	if assert.Len(t, reports, 3) {
+		assert.Equal(t, "eu-west", reports[2].Region)
+		assert.Equal(t, []types.ProbeResult{
+			{Address: "up.example.com:7777", Reachable: true, Ping: 22},
+			{Address: "down.example.com:7777", Reachable: false, Ping: 0},
+			{Address: "slow.example.com:7777", Reachable: true, Ping: 300}, // keeps the median after a lost ping
+		}, reports[2].Results)
+	}
+
+	agent.config.Key = "wrong"
+	_, err = agent.Round(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNewAgent(t *testing.T) {
+	query := QuerierFunc(func(context.Context, string, sampquery.QueryType) ([]byte, error) { return nil, nil })
+	ping := func(context.Context, string) (time.Duration, error) { return 0, nil }
+
+	_, err := NewAgent(Config{Endpoint: "http://localhost", Key: "key", Region: "EU West", Querier: query, PingFunction: ping})
+	assert.Error(t, err)
+	_, err = NewAgent(Config{Endpoint: "http://localhost", Region: "eu-west", Querier: query, PingFunction: ping})
+	assert.Error(t, err)
+	_, err = NewAgent(Config{Endpoint: "http://localhost", Key: "key", Region: "eu-west", Querier: query, PingFunction: ping})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,349 @@
+package probe
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// MaxResponse is the largest response the engine reads, the player lists of full servers are well
+// over the 2048 bytes that sampquery reads.
+const MaxResponse = 65535
+
+// pruneInterval is how often the buckets of servers that haven't been queried recently are dropped
+const pruneInterval = time.Minute
+
+// EngineConfig contains the settings of a query engine
+type EngineConfig struct {
+	Sockets   int           // number of UDP sockets shared by all queries, defaults to 4
+	Timeout   time.Duration // longest wait for a single response, defaults to 10 seconds
+	Rate      float64       // packets per second sent to all servers, zero for no limit
+	Burst     int           // packets that can be sent at once before Rate applies, defaults to Rate
+	HostRate  float64       // packets per second sent to a single IP, zero for no limit
+	HostBurst int           // packets that can be sent to a single IP at once, defaults to HostRate
+}
+
+// Engine sends queries for every server through a small pool of UDP sockets instead of one socket
+// per query. Responses are matched to requests by the address they came from and their opcode and
+// outgoing packets are rate limited globally and per destination IP so hosts running many servers
+// aren't flooded when they're all due at once.
+type Engine struct {
+	config  EngineConfig
+	sockets []*net.UDPConn
+	next    uint32 // socket to send the next packet from
+	closed  chan struct{}
+
+	lock    sync.Mutex
+	waiting map[string][]chan []byte // response key -> requests waiting for it, oldest first
+	global  *bucket
+	hosts   map[string]*bucket
+	pruned  time.Time
+}
+
+// NewEngine opens the sockets and starts reading responses
+func NewEngine(config EngineConfig) (engine *Engine, err error) {
+	if config.Sockets <= 0 {
+		config.Sockets = 4
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second * 10
+	}
+
+	engine = &Engine{
+		config:  config,
+		closed:  make(chan struct{}),
+		waiting: make(map[string][]chan []byte),
+		hosts:   make(map[string]*bucket),
+		pruned:  time.Now(),
+	}
+	if config.Rate > 0 {
+		engine.global = newBucket(config.Rate, config.Burst)
+	}
+
+	for i := 0; i < config.Sockets; i++ {
+		conn, errListen := net.ListenUDP("udp4", nil)
+		if errListen != nil {
+			engine.Close() // nolint:errcheck
+			return nil, errors.Wrap(errListen, "failed to open query socket")
+		}
+		engine.sockets = append(engine.sockets, conn)
+		go engine.read(conn)
+	}
+	return
+}
+
+// Close stops reading responses and closes the sockets, waiting queries time out
+func (engine *Engine) Close() (err error) {
+	close(engine.closed)
+	for _, conn := range engine.sockets {
+		if errClose := conn.Close(); errClose != nil {
+			err = errClose
+		}
+	}
+	return
+}
+
+// Query sends a query with the specified opcode and returns the raw response
+func (engine *Engine) Query(ctx context.Context, address string, opcode sampquery.QueryType) (response []byte, err error) {
+	response, _, err = engine.query(ctx, address, opcode)
+	return
+}
+
+// query also returns when the request was sent, after any wait for the rate limit
+func (engine *Engine) query(ctx context.Context, address string, opcode sampquery.QueryType) (response []byte, sent time.Time, err error) {
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, sent, errors.Wrap(err, "failed to resolve host")
+	}
+	if addr.IP.To4() == nil {
+		return nil, sent, errors.Errorf("'%s' is not an IPv4 address", address)
+	}
+
+	var nonce []byte
+	if opcode == sampquery.Ping {
+		nonce = make([]byte, 4)
+		if _, err = rand.Read(nonce); err != nil {
+			return nil, sent, errors.Wrap(err, "failed to generate ping payload")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, engine.config.Timeout)
+	defer cancel()
+
+	key := responseKey(addr, byte(opcode), nonce)
+	result := make(chan []byte, 1)
+	engine.wait(key, result)
+	defer engine.forget(key, result)
+
+	if err = engine.limit(ctx, addr.IP.String()); err != nil {
+		return
+	}
+
+	conn := engine.sockets[atomic.AddUint32(&engine.next, 1)%uint32(len(engine.sockets))]
+	sent = time.Now()
+	if _, err = conn.WriteToUDP(encodeRequest(addr, opcode, nonce), addr); err != nil {
+		return nil, sent, errors.Wrap(err, "failed to write")
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, sent, errors.New("socket read timed out")
+	case response = <-result:
+		return response, sent, nil
+	}
+}
+
+// GetPlayers requests the detailed player list from a server, falling back to the basic client
+// list which lacks IDs and pings if the server doesn't respond to the detailed query.
+func (engine *Engine) GetPlayers(ctx context.Context, address string) (players []types.Player, err error) {
+	response, err := engine.Query(ctx, address, opcodeDetailed)
+	if err == nil {
+		return parseDetailedPlayers(response)
+	}
+
+	response, err = engine.Query(ctx, address, sampquery.Players)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query player list")
+	}
+	return parseClientList(response)
+}
+
+// GetPing measures the round trip time of a SA:MP 'p' (ping) query, the time spent waiting for the
+// rate limit isn't included.
+func (engine *Engine) GetPing(ctx context.Context, address string) (ping time.Duration, err error) {
+	_, sent, err := engine.query(ctx, address, sampquery.Ping)
+	if err != nil {
+		return
+	}
+	return time.Since(sent), nil
+}
+
+// read delivers the responses arriving on a socket until the engine is closed
+func (engine *Engine) read(conn *net.UDPConn) {
+	buf := make([]byte, MaxResponse)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-engine.closed:
+				return
+			default:
+				continue
+			}
+		}
+		if n < headerLength || string(buf[:4]) != "SAMP" {
+			continue
+		}
+
+		var nonce []byte
+		if buf[10] == byte(sampquery.Ping) {
+			if n < headerLength+4 {
+				continue
+			}
+			nonce = buf[headerLength : headerLength+4]
+		}
+		engine.deliver(responseKey(from, buf[10], nonce), append([]byte(nil), buf[:n]...))
+	}
+}
+
+// responseKey identifies the response to a request, pings also carry a random payload so each ping
+// is only matched with its own response and the round trip time is accurate.
+func responseKey(addr *net.UDPAddr, opcode byte, nonce []byte) string {
+	return net.JoinHostPort(addr.IP.String(), strconv.Itoa(addr.Port)) + "/" + string(opcode) + string(nonce)
+}
+
+func (engine *Engine) wait(key string, result chan []byte) {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+	engine.waiting[key] = append(engine.waiting[key], result)
+}
+
+func (engine *Engine) forget(key string, result chan []byte) {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+
+	waiting := engine.waiting[key]
+	for i := range waiting {
+		if waiting[i] == result {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(engine.waiting, key)
+	} else {
+		engine.waiting[key] = waiting
+	}
+}
+
+// deliver hands a response to the oldest request waiting for it, responses nobody is waiting for
+// such as late replies to timed out requests are dropped.
+func (engine *Engine) deliver(key string, response []byte) {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+
+	waiting := engine.waiting[key]
+	if len(waiting) == 0 {
+		return
+	}
+	waiting[0] <- response
+	if len(waiting) == 1 {
+		delete(engine.waiting, key)
+	} else {
+		engine.waiting[key] = waiting[1:]
+	}
+}
+
+// limit waits until a packet can be sent to an IP without going over either rate limit, the tokens
+// are given back if the context ends first so requests that time out don't slow down later ones
+func (engine *Engine) limit(ctx context.Context, ip string) error {
+	engine.lock.Lock()
+	now := time.Now()
+	var delay time.Duration
+	if engine.global != nil {
+		delay = engine.global.reserve(now)
+	}
+	var host *bucket
+	if engine.config.HostRate > 0 {
+		var ok bool
+		host, ok = engine.hosts[ip]
+		if !ok {
+			host = newBucket(engine.config.HostRate, engine.config.HostBurst)
+			engine.hosts[ip] = host
+		}
+		if wait := host.reserve(now); wait > delay {
+			delay = wait
+		}
+	}
+	engine.prune(now)
+	engine.lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		engine.lock.Lock()
+		now = time.Now()
+		if engine.global != nil {
+			engine.global.refund(now)
+		}
+		if host != nil {
+			host.refund(now)
+		}
+		engine.lock.Unlock()
+		return errors.New("timed out waiting for the rate limit")
+	case <-timer.C:
+		return nil
+	}
+}
+
+// prune drops the buckets that have refilled since they aren't limiting anything, must hold the lock
+func (engine *Engine) prune(now time.Time) {
+	if now.Sub(engine.pruned) < pruneInterval {
+		return
+	}
+	engine.pruned = now
+	for ip, host := range engine.hosts {
+		if host.full(now) {
+			delete(engine.hosts, ip)
+		}
+	}
+}
+
+// bucket is a token bucket, tokens can be reserved ahead of time so callers wait their turn
+type bucket struct {
+	rate   float64 // tokens added per second
+	burst  float64 // most tokens the bucket holds
+	tokens float64 // negative when tokens have been reserved ahead of time
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	b := &bucket{rate: rate, burst: float64(burst)}
+	if b.burst <= 0 {
+		b.burst = math.Max(1, math.Ceil(rate))
+	}
+	b.tokens = b.burst
+	return b
+}
+
+// reserve takes a token and returns how long to wait until it's available
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund gives back a token that was reserved but not used
+func (b *bucket) refund(now time.Time) {
+	b.refill(now)
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
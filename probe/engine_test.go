@@ -0,0 +1,192 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// fakeServer answers queries like a SA:MP server, opcodes without a response are ignored
+type fakeServer struct {
+	conn      *net.UDPConn
+	responses map[byte][]byte
+
+	lock     sync.Mutex
+	received []time.Time
+}
+
+func newFakeServer(t *testing.T, responses map[byte][]byte) *fakeServer {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip("can't listen on UDP:", err)
+	}
+	server := &fakeServer{conn: conn, responses: responses}
+	go server.serve()
+	t.Cleanup(func() { conn.Close() })
+	return server
+}
+
+func (s *fakeServer) Address() string {
+	return s.conn.LocalAddr().String()
+}
+
+func (s *fakeServer) Received() []time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]time.Time(nil), s.received...)
+}
+
+func (s *fakeServer) serve() {
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.received = append(s.received, time.Now())
+		s.lock.Unlock()
+
+		if n < headerLength {
+			continue
+		}
+		opcode := buf[10]
+		if opcode == byte(sampquery.Ping) {
+			s.conn.WriteToUDP(buf[:n], from) // nolint:errcheck
+			continue
+		}
+		if body, ok := s.responses[opcode]; ok {
+			s.conn.WriteToUDP(append(append([]byte{}, buf[:headerLength]...), body...), from) // nolint:errcheck
+		}
+	}
+}
+
+func newTestEngine(t *testing.T, config EngineConfig) *Engine {
+	engine, err := NewEngine(config)
+	if err != nil {
+		t.Skip("can't open UDP sockets:", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func TestEngine(t *testing.T) {
+	server := newFakeServer(t, map[byte][]byte{
+		'i': {0, 3, 0, 50, 0, 4, 0, 0, 0, 'T', 'e', 's', 't', 3, 0, 0, 0, 'T', 'D', 'M', 0, 0, 0, 0},
+		'r': {1, 0, 7, 'v', 'e', 'r', 's', 'i', 'o', 'n', 8, '0', '.', '3', '.', '7', '-', 'R', '2'},
+		'c': {1, 0, 3, 'B', 'o', 'b', 10, 0, 0, 0},
+	})
+	engine := newTestEngine(t, EngineConfig{Sockets: 2, Timeout: time.Millisecond * 500})
+	ctx := context.Background()
+
+	info, err := Query(ctx, engine, server.Address(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Server{
+		IP: "127.0.0.1",
+		Core: types.ServerCore{
+			Address:    server.Address(),
+			Hostname:   "Test",
+			Players:    3,
+			MaxPlayers: 50,
+			Gamemode:   "TDM",
+			Language:   "-",
+			Version:    "0.3.7-R2",
+		},
+		Rules:    map[string]string{"version": "0.3.7-R2"},
+		Platform: types.PlatformSAMP,
+	}, info)
+
+	// the server doesn't answer detailed player queries so the client list is used
+	players, err := engine.GetPlayers(ctx, server.Address())
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Player{{ID: -1, Name: "Bob", Score: 10, Ping: -1}}, players)
+
+	ping, err := engine.GetPing(ctx, server.Address())
+	assert.NoError(t, err)
+	assert.True(t, ping > 0)
+
+	// concurrent queries share the sockets and each gets its own response
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := engine.GetPing(ctx, server.Address())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, engine.waiting, 0)
+}
+
+func TestEngine_Timeout(t *testing.T) {
+	server := newFakeServer(t, map[byte][]byte{})
+	engine := newTestEngine(t, EngineConfig{Timeout: time.Millisecond * 100})
+
+	start := time.Now()
+	_, err := engine.Query(context.Background(), server.Address(), sampquery.Info)
+	assert.Error(t, err)
+	assert.WithinDuration(t, start.Add(time.Millisecond*100), time.Now(), time.Millisecond*100)
+}
+
+func TestEngine_HostRate(t *testing.T) {
+	server := newFakeServer(t, map[byte][]byte{})
+	engine := newTestEngine(t, EngineConfig{Timeout: time.Second, HostRate: 20, HostBurst: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			engine.Query(context.Background(), server.Address(), sampquery.Rules) // nolint:errcheck
+		}()
+	}
+	wg.Wait()
+
+	// two packets go out at once then one every 50ms
+	received := server.Received()
+	if assert.Len(t, received, 6) {
+		assert.True(t, received[5].Sub(received[0]) >= time.Millisecond*180, received[5].Sub(received[0]))
+	}
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Now()
+	b := newBucket(10, 2)
+	assert.Equal(t, time.Duration(0), b.reserve(now))
+	assert.Equal(t, time.Duration(0), b.reserve(now))
+	assert.Equal(t, time.Millisecond*100, b.reserve(now))
+	assert.Equal(t, time.Millisecond*200, b.reserve(now))
+	assert.False(t, b.full(now))
+	b.refund(now)
+	assert.Equal(t, time.Millisecond*200, b.reserve(now))
+	assert.True(t, b.full(now.Add(time.Second)))
+	b.refund(now.Add(time.Second))
+	assert.Equal(t, 2.0, b.tokens, "refunds don't go over the burst")
+
+	assert.Equal(t, 5.0, newBucket(4.5, 0).burst)
+	assert.Equal(t, 1.0, newBucket(0.5, 0).burst)
+}
+
+func TestEngine_LimitRefund(t *testing.T) {
+	engine := newTestEngine(t, EngineConfig{Timeout: time.Second, HostRate: 1, HostBurst: 1})
+
+	assert.NoError(t, engine.limit(context.Background(), "127.0.0.1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Error(t, engine.limit(ctx, "127.0.0.1"))
+
+	// the cancelled request's token was given back so the next one only waits for the first
+	engine.lock.Lock()
+	assert.True(t, engine.hosts["127.0.0.1"].tokens >= 0, engine.hosts["127.0.0.1"].tokens)
+	engine.lock.Unlock()
+}
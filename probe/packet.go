@@ -0,0 +1,97 @@
+package probe
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+	"github.com/saintfish/chardet"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// encodeRequest builds a query packet: "SAMP", the IP and port of the server, the opcode and for
+// pings four bytes that the server echoes back.
+func encodeRequest(addr *net.UDPAddr, opcode sampquery.QueryType, nonce []byte) []byte {
+	request := make([]byte, 0, headerLength+len(nonce))
+	request = append(request, "SAMP"...)
+	request = append(request, addr.IP.To4()...)
+	request = append(request, byte(addr.Port&0xFF), byte((addr.Port>>8)&0xFF))
+	request = append(request, byte(opcode))
+	return append(request, nonce...)
+}
+
+// parseInfo decodes an 'i' response: the password flag, player counts then the length-prefixed
+// hostname, gamemode and language.
+func parseInfo(response []byte, attemptDecode bool) (server sampquery.Server, err error) {
+	r := reader{buf: response, ptr: headerLength}
+
+	server.Password = r.uint8() == 1
+	server.Players = int(r.uint16())
+	server.MaxPlayers = int(r.uint16())
+	hostname := r.bytes32()
+	gamemode := r.bytes32()
+	language := r.bytes32()
+	if r.err != nil {
+		return server, errors.Wrap(r.err, "malformed server info")
+	}
+
+	if attemptDecode {
+		guess := bytes.Join([][]byte{hostname, gamemode, language}, []byte(" "))
+		server.Hostname = decodeANSI(hostname, guess)
+		server.Gamemode = decodeANSI(gamemode, guess)
+		server.Language = decodeANSI(language, guess)
+	} else {
+		server.Hostname = string(hostname)
+		server.Gamemode = string(gamemode)
+		server.Language = string(language)
+	}
+	if server.Language == "" {
+		server.Language = "-"
+	}
+	return
+}
+
+// parseRules decodes an 'r' response: a count followed by a length-prefixed name and value for
+// each rule.
+func parseRules(response []byte) (rules map[string]string, err error) {
+	r := reader{buf: response, ptr: headerLength}
+
+	count := r.uint16()
+	rules = make(map[string]string, count)
+	for i := 0; i < int(count) && r.err == nil; i++ {
+		key := r.string8()
+		rules[key] = r.string8()
+	}
+	if r.err != nil {
+		return nil, errors.Wrap(r.err, "malformed rules")
+	}
+	return
+}
+
+// decodeANSI converts text from servers using other codepages, such as Cyrillic, into UTF-8. The
+// other fields of the response are used to help guess the codepage.
+func decodeANSI(input []byte, guess []byte) string {
+	detected, err := chardet.NewTextDetector().DetectBest(guess)
+	if err != nil {
+		return string(input)
+	}
+	encoding, err := htmlindex.Get(detected.Charset)
+	if err != nil {
+		return string(input)
+	}
+	decoded, err := encoding.NewDecoder().Bytes(input)
+	if err != nil {
+		return string(input)
+	}
+	return string(decoded)
+}
+
+func (r *reader) bytes32() []byte {
+	length := r.uint32()
+	if length > uint32(len(r.buf)) {
+		r.err = errors.Errorf("length %d at byte %d is longer than the response", length, r.ptr)
+		return nil
+	}
+	return r.take(int(length))
+}
@@ -0,0 +1,67 @@
+package probe
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeRequest(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7777}
+	assert.Equal(t, packet('i'), encodeRequest(addr, sampquery.Info, nil))
+	assert.Equal(t, packet('p', 1, 2, 3, 4), encodeRequest(addr, sampquery.Ping, []byte{1, 2, 3, 4}))
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   []byte
+		wantServer sampquery.Server
+		wantErr    bool
+	}{
+		{"valid", packet('i',
+			1,
+			12, 0,
+			50, 0,
+			4, 0, 0, 0, 'T', 'e', 's', 't',
+			3, 0, 0, 0, 'T', 'D', 'M',
+			7, 0, 0, 0, 'E', 'n', 'g', 'l', 'i', 's', 'h',
+		), sampquery.Server{Hostname: "Test", Players: 12, MaxPlayers: 50, Gamemode: "TDM", Language: "English", Password: true}, false},
+		{"no language", packet('i',
+			0,
+			0, 0,
+			50, 0,
+			4, 0, 0, 0, 'T', 'e', 's', 't',
+			3, 0, 0, 0, 'T', 'D', 'M',
+			0, 0, 0, 0,
+		), sampquery.Server{Hostname: "Test", MaxPlayers: 50, Gamemode: "TDM", Language: "-"}, false},
+		{"hostname overflow", packet('i', 0, 0, 0, 50, 0, 0xff, 0xff, 0xff, 0xff, 'T'), sampquery.Server{}, true},
+		{"truncated", packet('i', 0, 0, 0), sampquery.Server{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotServer, err := parseInfo(tt.response, false)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantServer, gotServer)
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(packet('r',
+		2, 0,
+		7, 'v', 'e', 'r', 's', 'i', 'o', 'n', 8, '0', '.', '3', '.', '7', '-', 'R', '2',
+		7, 'w', 'e', 'a', 't', 'h', 'e', 'r', 2, '1', '0',
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"version": "0.3.7-R2", "weather": "10"}, rules)
+
+	_, err = parseRules(packet('r', 2, 0, 7, 'v', 'e', 'r'))
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package probe
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyWindow is the number of recent pings that a server's reported latency is the median of, a
+// median is used so a single packet delayed by a busy server or network doesn't move the value.
+const LatencyWindow = 5
+
+// PingFunction represents a function capable of measuring the round trip time to a server
+type PingFunction func(context.Context, string) (time.Duration, error)
+
+// Latency holds the most recent ping measurements of a server in milliseconds
+type Latency struct {
+	lock    sync.Mutex
+	samples []int
+}
+
+// Add records a measurement and returns the new median
+func (l *Latency) Add(ping time.Duration) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// round up so any measured latency is distinguishable from 0, which means not yet measured
+	ms := int((ping + time.Millisecond - 1) / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+
+	l.samples = append(l.samples, ms)
+	if len(l.samples) > LatencyWindow {
+		l.samples = l.samples[len(l.samples)-LatencyWindow:]
+	}
+	return l.median()
+}
+
+// Median returns the current median without recording a measurement
+func (l *Latency) Median() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.median()
+}
+
+func (l *Latency) median() int {
+	if len(l.samples) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), l.samples...)
+	sort.Ints(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
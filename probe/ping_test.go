@@ -0,0 +1,52 @@
+package probe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatency(t *testing.T) {
+	tests := []struct {
+		name  string
+		pings []time.Duration
+		want  int
+	}{
+		{"none", nil, 0},
+		{"one", []time.Duration{40 * time.Millisecond}, 40},
+		{"rounds up", []time.Duration{40*time.Millisecond + time.Microsecond}, 41},
+		{"never zero", []time.Duration{time.Microsecond}, 1},
+		{"even", []time.Duration{40 * time.Millisecond, 60 * time.Millisecond}, 50},
+		{"spike", []time.Duration{40 * time.Millisecond, 900 * time.Millisecond, 42 * time.Millisecond}, 42},
+		{"rolls", []time.Duration{
+			900 * time.Millisecond,
+			900 * time.Millisecond,
+			900 * time.Millisecond,
+			30 * time.Millisecond,
+			30 * time.Millisecond,
+			900 * time.Millisecond,
+			900 * time.Millisecond,
+			900 * time.Millisecond,
+		}, 900},
+		{"moved", []time.Duration{
+			900 * time.Millisecond,
+			900 * time.Millisecond,
+			30 * time.Millisecond,
+			30 * time.Millisecond,
+			30 * time.Millisecond,
+			30 * time.Millisecond,
+			30 * time.Millisecond,
+		}, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Latency{}
+			for _, ping := range tt.pings {
+				l.Add(ping)
+			}
+			assert.Equal(t, tt.want, l.Median())
+			assert.True(t, len(l.samples) <= LatencyWindow)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// MaxPlayerList is the player count above which SA:MP servers refuse to send their player list
+const MaxPlayerList = 100
+
+// PlayersFunction represents a function capable of retreiving the player list of a server
+type PlayersFunction func(context.Context, string) ([]types.Player, error)
+
+const (
+	opcodeDetailed sampquery.QueryType = 'd'
+	headerLength                       = 11 // "SAMP" + 4 byte IP + 2 byte port + opcode
+)
+
+// parseDetailedPlayers decodes a 'd' response: a count followed by an ID, a length-prefixed name,
+// a score and a ping for each player.
+func parseDetailedPlayers(response []byte) (players []types.Player, err error) {
+	r := reader{buf: response}
+	r.header(opcodeDetailed)
+
+	count := r.uint16()
+	players = make([]types.Player, 0, count)
+	for i := 0; i < int(count) && r.err == nil; i++ {
+		player := types.Player{
+			ID:   int(r.uint8()),
+			Name: r.string8(),
+		}
+		player.Score = int(int32(r.uint32()))
+		player.Ping = int(int32(r.uint32()))
+		players = append(players, player)
+	}
+	if r.err != nil {
+		return nil, errors.Wrap(r.err, "malformed detailed player list")
+	}
+	return
+}
+
+// parseClientList decodes a 'c' response: a count followed by a length-prefixed name and a score
+// for each player.
+func parseClientList(response []byte) (players []types.Player, err error) {
+	r := reader{buf: response}
+	r.header(sampquery.Players)
+
+	count := r.uint16()
+	players = make([]types.Player, 0, count)
+	for i := 0; i < int(count) && r.err == nil; i++ {
+		player := types.Player{
+			ID:   -1,
+			Name: r.string8(),
+		}
+		player.Score = int(int32(r.uint32()))
+		player.Ping = -1
+		players = append(players, player)
+	}
+	if r.err != nil {
+		return nil, errors.Wrap(r.err, "malformed client list")
+	}
+	return
+}
+
+// reader is a bounds-checked little-endian reader for query responses, a server can reply with
+// anything so every read is checked and the first failure is kept in `err`.
+type reader struct {
+	buf []byte
+	ptr int
+	err error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.ptr+n > len(r.buf) {
+		r.err = errors.Errorf("unexpected end of response at byte %d", r.ptr)
+		return nil
+	}
+	b := r.buf[r.ptr : r.ptr+n]
+	r.ptr += n
+	return b
+}
+
+// header skips the response header, failing if the response isn't a reply to the opcode
+func (r *reader) header(opcode sampquery.QueryType) {
+	h := r.take(headerLength)
+	if h != nil && (string(h[:4]) != "SAMP" || h[headerLength-1] != byte(opcode)) {
+		r.err = errors.Errorf("response is not a reply to the '%c' query", opcode)
+	}
+}
+
+func (r *reader) uint8() uint8 {
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.take(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) string8() string {
+	return string(r.take(int(r.uint8())))
+}
@@ -0,0 +1,58 @@
+package probe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+var header = []byte{'S', 'A', 'M', 'P', 127, 0, 0, 1, 0x61, 0x1e}
+
+func packet(opcode byte, body ...byte) []byte {
+	return append(append(append([]byte{}, header...), opcode), body...)
+}
+
+func TestParseDetailedPlayers(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    []byte
+		wantPlayers []types.Player
+		wantErr     bool
+	}{
+		{"empty", packet('d', 0, 0), []types.Player{}, false},
+		{"two", packet('d',
+			2, 0,
+			0, 3, 'B', 'o', 'b', 10, 0, 0, 0, 50, 0, 0, 0,
+			7, 5, 'A', 'l', 'i', 'c', 'e', 0xff, 0xff, 0xff, 0xff, 120, 0, 0, 0,
+		), []types.Player{
+			{ID: 0, Name: "Bob", Score: 10, Ping: 50},
+			{ID: 7, Name: "Alice", Score: -1, Ping: 120},
+		}, false},
+		{"truncated", packet('d', 2, 0, 0, 3, 'B', 'o', 'b', 10, 0), nil, true},
+		{"name overflow", packet('d', 1, 0, 0, 200, 'B'), nil, true},
+		{"no header", []byte{'S', 'A'}, nil, true},
+		{"wrong opcode", packet('c', 0, 0), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPlayers, err := parseDetailedPlayers(tt.response)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPlayers, gotPlayers)
+		})
+	}
+}
+
+func TestParseClientList(t *testing.T) {
+	gotPlayers, err := parseClientList(packet('c',
+		1, 0,
+		3, 'B', 'o', 'b', 10, 0, 0, 0,
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Player{{ID: -1, Name: "Bob", Score: 10, Ping: -1}}, gotPlayers)
+}
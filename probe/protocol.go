@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Querier sends a raw query to a server and returns the response, Engine is the implementation
+// used outside of tests.
+type Querier interface {
+	Query(ctx context.Context, address string, opcode sampquery.QueryType) ([]byte, error)
+}
+
+// QuerierFunc allows a plain function to be used as a Querier
+type QuerierFunc func(ctx context.Context, address string, opcode sampquery.QueryType) ([]byte, error)
+
+// Query implements Querier
+func (f QuerierFunc) Query(ctx context.Context, address string, opcode sampquery.QueryType) ([]byte, error) {
+	return f(ctx, address, opcode)
+}
+
+// Protocol is the query protocol of SA:MP or one of its forks. Every fork answers the SA:MP info and
+// rules queries so a protocol is detected from those responses, it can then make any queries of its
+// own to fill in the information only that platform provides.
+type Protocol interface {
+	Platform() types.Platform
+	Detect(server types.Server) bool
+	Extend(ctx context.Context, querier Querier, server *types.Server) error
+}
+
+// Protocols are the protocols that are detected by default, in the order they're checked
+var Protocols = []Protocol{
+	OpenMP{},
+	Fork{Name: types.PlatformSAMPDL, VersionPrefix: "0.3.DL"},
+	SAMP{},
+}
+
+// Query makes the info and rules queries that every platform answers and returns the server
+func Query(ctx context.Context, querier Querier, address string, attemptDecode bool) (server types.Server, err error) {
+	response, err := querier.Query(ctx, address, sampquery.Info)
+	if err != nil {
+		return
+	}
+	info, err := parseInfo(response, attemptDecode)
+	if err != nil {
+		return
+	}
+	info.Address = address
+
+	response, err = querier.Query(ctx, address, sampquery.Rules)
+	if err != nil {
+		return
+	}
+	info.Rules, err = parseRules(response)
+	if err != nil {
+		return
+	}
+
+	server = NewServer(info)
+	server.Platform = types.PlatformSAMP
+	return
+}
+
+// Detect sets the platform of a server from the first protocol that matches then collects the
+// information only that platform provides. The platform is set even if that fails since the server
+// has already answered the standard queries, the error is only for the extra queries.
+func Detect(ctx context.Context, querier Querier, protocols []Protocol, server *types.Server) error {
+	for _, protocol := range protocols {
+		if protocol.Detect(*server) {
+			server.Platform = protocol.Platform()
+			return protocol.Extend(ctx, querier, server)
+		}
+	}
+	return nil
+}
+
+// SAMP is the original SA:MP query protocol, it matches any server
+type SAMP struct{}
+
+// Platform implements Protocol
+func (SAMP) Platform() types.Platform { return types.PlatformSAMP }
+
+// Detect implements Protocol
+func (SAMP) Detect(types.Server) bool { return true }
+
+// Extend implements Protocol, SA:MP has nothing beyond the info and rules
+func (SAMP) Extend(context.Context, Querier, *types.Server) error { return nil }
+
+// opcodeOpenMP is the open.mp extended info query
+const opcodeOpenMP sampquery.QueryType = 'o'
+
+// OpenMP is the open.mp query protocol, open.mp reports its version in the `version` rule as
+// "omp <version>" and answers the 'o' query with a Discord invite and banner and logo URLs.
+type OpenMP struct{}
+
+// Platform implements Protocol
+func (OpenMP) Platform() types.Platform { return types.PlatformOpenMP }
+
+// Detect implements Protocol
+func (OpenMP) Detect(server types.Server) bool {
+	return strings.HasPrefix(server.Rules["version"], "omp ")
+}
+
+// Extend implements Protocol, the extra information is only set once the 'o' query succeeds, even if
+// it's empty, so a failed query can be told apart from a server that has nothing to show.
+func (OpenMP) Extend(ctx context.Context, querier Querier, server *types.Server) error {
+	response, err := querier.Query(ctx, server.Core.Address, opcodeOpenMP)
+	if err != nil {
+		return errors.Wrap(err, "failed to query open.mp extended info")
+	}
+	extra, err := parseOpenMP(response)
+	if err != nil {
+		return err
+	}
+	server.Extra = extra
+	return nil
+}
+
+// parseOpenMP decodes an 'o' response: the length-prefixed Discord invite, light and dark banner
+// URLs and logo URL. Empty values are left out.
+func parseOpenMP(response []byte) (extra map[string]string, err error) {
+	r := reader{buf: response, ptr: headerLength}
+
+	extra = make(map[string]string)
+	for _, key := range []string{types.ExtraDiscord, types.ExtraBannerLight, types.ExtraBannerDark, types.ExtraLogo} {
+		if value := string(r.bytes32()); value != "" {
+			extra[key] = value
+		}
+	}
+	if r.err != nil {
+		return nil, errors.Wrap(r.err, "malformed open.mp extended info")
+	}
+	return
+}
+
+// Fork is a SA:MP fork that speaks the SA:MP protocol unchanged and is told apart by the prefix of
+// its `version` rule.
+type Fork struct {
+	Name          types.Platform
+	VersionPrefix string
+}
+
+// Platform implements Protocol
+func (fork Fork) Platform() types.Platform { return fork.Name }
+
+// Detect implements Protocol
+func (fork Fork) Detect(server types.Server) bool {
+	return strings.HasPrefix(server.Rules["version"], fork.VersionPrefix)
+}
+
+// Extend implements Protocol
+func (Fork) Extend(context.Context, Querier, *types.Server) error { return nil }
@@ -0,0 +1,74 @@
+package probe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func fakeQuerier(responses map[sampquery.QueryType][]byte) Querier {
+	return QuerierFunc(func(ctx context.Context, address string, opcode sampquery.QueryType) ([]byte, error) {
+		body, ok := responses[opcode]
+		if !ok {
+			return nil, errors.New("socket read timed out")
+		}
+		return packet(byte(opcode), body...), nil
+	})
+}
+
+func rules(version string) []byte {
+	return append([]byte{1, 0, 7, 'v', 'e', 'r', 's', 'i', 'o', 'n', byte(len(version))}, version...)
+}
+
+func TestDetect(t *testing.T) {
+	info := []byte{0, 3, 0, 50, 0, 4, 0, 0, 0, 'T', 'e', 's', 't', 3, 0, 0, 0, 'T', 'D', 'M', 0, 0, 0, 0}
+	openmp := []byte{
+		19, 0, 0, 0, 'd', 'i', 's', 'c', 'o', 'r', 'd', '.', 'g', 'g', '/', 's', 'a', 'm', 'p', 'l', 'i', 's', 't',
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		12, 0, 0, 0, 'h', 't', 't', 'p', 's', ':', '/', '/', 'l', 'o', 'g', 'o',
+	}
+
+	tests := []struct {
+		name         string
+		responses    map[sampquery.QueryType][]byte
+		wantPlatform types.Platform
+		wantExtra    map[string]string
+		wantErr      bool
+	}{
+		{"samp", map[sampquery.QueryType][]byte{'i': info, 'r': rules("0.3.7-R2")}, types.PlatformSAMP, nil, false},
+		{"openmp", map[sampquery.QueryType][]byte{'i': info, 'r': rules("omp 1.3.1.2748"), 'o': openmp}, types.PlatformOpenMP, map[string]string{
+			types.ExtraDiscord: "discord.gg/samplist",
+			types.ExtraLogo:    "https://logo",
+		}, false},
+		{"openmp.empty", map[sampquery.QueryType][]byte{'i': info, 'r': rules("omp 1.3.1.2748"), 'o': make([]byte, 16)}, types.PlatformOpenMP, map[string]string{}, false},
+		{"openmp.no.extra", map[sampquery.QueryType][]byte{'i': info, 'r': rules("omp 1.3.1.2748")}, types.PlatformOpenMP, nil, true},
+		{"openmp.malformed", map[sampquery.QueryType][]byte{'i': info, 'r': rules("omp 1.3.1.2748"), 'o': {19, 0, 0, 0, 'd'}}, types.PlatformOpenMP, nil, true},
+		{"samp-dl", map[sampquery.QueryType][]byte{'i': info, 'r': rules("0.3.DL-R1")}, types.PlatformSAMPDL, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			querier := fakeQuerier(tt.responses)
+			server, err := Query(context.Background(), querier, "127.0.0.1:7777", false)
+			assert.NoError(t, err)
+			assert.Equal(t, "Test", server.Core.Hostname)
+
+			err = Detect(context.Background(), querier, Protocols, &server)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPlatform, server.Platform)
+			assert.Equal(t, tt.wantExtra, server.Extra)
+		})
+	}
+
+	_, err := Query(context.Background(), fakeQuerier(map[sampquery.QueryType][]byte{'i': info}), "127.0.0.1:7777", false)
+	assert.Error(t, err, "servers must answer the rules query")
+}
@@ -0,0 +1,62 @@
+// Package probe contains the code for querying SA:MP servers, it's shared by the scraper in the API
+// and the standalone probe agents that measure reachability and latency from other regions.
+package probe
+
+import (
+	"context"
+	"net"
+
+	"github.com/Southclaws/go-samp-query"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// NewServer converts a query response into a Server, resolving the IP address of the host. The IP
+// falls back to the host itself if it can't be resolved.
+func NewServer(info sampquery.Server) types.Server {
+	host, _, err := net.SplitHostPort(info.Address)
+	if err != nil {
+		host = info.Address
+	}
+
+	ip := host
+	if addrs, err := net.LookupHost(host); err == nil && len(addrs) > 0 {
+		ip = addrs[0]
+	}
+
+	server := types.Server{
+		IP: ip,
+		Core: types.ServerCore{
+			Address:    info.Address,
+			Hostname:   info.Hostname,
+			Players:    info.Players,
+			MaxPlayers: info.MaxPlayers,
+			Gamemode:   info.Gamemode,
+			Language:   info.Language,
+			Password:   info.Password,
+		},
+		Rules: info.Rules,
+	}
+	if version, ok := info.Rules["version"]; ok {
+		server.Core.Version = version
+	}
+	return server
+}
+
+// GetPlayerList collects the player list of a server. SA:MP refuses to send the list for servers
+// with more than MaxPlayerList players so the list is marked unavailable without a query, the error
+// is only for failed queries.
+func GetPlayerList(ctx context.Context, players PlayersFunction, core types.ServerCore) (*types.PlayerList, error) {
+	if core.Players > MaxPlayerList {
+		return &types.PlayerList{Available: false, Players: []types.Player{}}, nil
+	}
+	if core.Players == 0 {
+		return &types.PlayerList{Available: true, Players: []types.Player{}}, nil
+	}
+
+	list, err := players(ctx, core.Address)
+	if err != nil {
+		return &types.PlayerList{Available: false, Players: []types.Player{}}, err
+	}
+	return &types.PlayerList{Available: true, Players: list}, nil
+}
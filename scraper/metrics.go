@@ -10,12 +10,53 @@ type metrics struct {
 	Failures  prometheus.Counter
 	Archives  prometheus.Counter
 	Removals  prometheus.Counter
+	Announces prometheus.Counter
 	QueryTime prometheus.Summary
+
+	PlayerListFailures prometheus.Counter
+	Bans               prometheus.Counter
+	PingFailures       prometheus.Counter
+	Skips              prometheus.Counter
+	ExtraFailures      prometheus.Counter
+	Ping               prometheus.Summary
+
+	QueueDepth   prometheus.Gauge
+	QueueOverdue prometheus.Gauge
+	QueueRunning prometheus.Gauge
+	QueueLag     prometheus.Summary
+	Interval     prometheus.Summary
 }
 
-// newMetricsRecorder initialises a new metrics recorder
+// newMetricsRecorder initialises a new metrics recorder and registers it
 func newMetricsRecorder() (m *metrics) {
-	m = &metrics{
+	m = newMetrics()
+	prometheus.MustRegister(
+		m.Errors,
+		m.Queries,
+		m.Successes,
+		m.Failures,
+		m.Archives,
+		m.Removals,
+		m.Announces,
+		m.QueryTime,
+		m.PlayerListFailures,
+		m.Bans,
+		m.PingFailures,
+		m.Skips,
+		m.ExtraFailures,
+		m.Ping,
+		m.QueueDepth,
+		m.QueueOverdue,
+		m.QueueRunning,
+		m.QueueLag,
+		m.Interval,
+	)
+	return m
+}
+
+// newMetrics creates the metrics without registering them
+func newMetrics() *metrics {
+	return &metrics{
 		Errors: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: "samplist",
 			Subsystem: "scraper",
@@ -52,21 +93,83 @@ func newMetricsRecorder() (m *metrics) {
 			Name:      "removes",
 			Help:      "Removed servers",
 		}),
+		Announces: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "announces",
+			Help:      "Announced servers",
+		}),
 		QueryTime: prometheus.NewSummary(prometheus.SummaryOpts{
 			Namespace: "samplist",
 			Subsystem: "scraper",
 			Name:      "query_time",
 			Help:      "The length of queries in seconds",
 		}),
+		PlayerListFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "player_list_failures",
+			Help:      "Failed player list queries",
+		}),
+		Bans: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "bans",
+			Help:      "Addresses rejected or removed because of a ban",
+		}),
+		PingFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "ping_failures",
+			Help:      "Failed ping queries",
+		}),
+		Skips: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "skips",
+			Help:      "Queries skipped because another replica owns the address",
+		}),
+		ExtraFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "extra_failures",
+			Help:      "Failed queries for information specific to the platform of a server",
+		}),
+		Ping: prometheus.NewSummary(prometheus.SummaryOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "ping",
+			Help:      "The round trip time of ping queries in seconds",
+		}),
+		QueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "queue_depth",
+			Help:      "Addresses in the query schedule",
+		}),
+		QueueOverdue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "queue_overdue",
+			Help:      "Addresses that are due a query but waiting for the query budget",
+		}),
+		QueueRunning: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "queue_running",
+			Help:      "Queries in progress",
+		}),
+		QueueLag: prometheus.NewSummary(prometheus.SummaryOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "queue_lag",
+			Help:      "The time between a query being due and being made in seconds",
+		}),
+		Interval: prometheus.NewSummary(prometheus.SummaryOpts{
+			Namespace: "samplist",
+			Subsystem: "scraper",
+			Name:      "interval",
+			Help:      "The scheduled interval between queries of an address in seconds",
+		}),
 	}
-	prometheus.MustRegister(
-		m.Errors,
-		m.Queries,
-		m.Successes,
-		m.Failures,
-		m.Archives,
-		m.Removals,
-		m.QueryTime,
-	)
-	return m
 }
@@ -0,0 +1,26 @@
+package scraper
+
+import (
+	"context"
+
+	"github.com/Southclaws/samp-servers-api/probe"
+)
+
+// ping measures the latency of a server and returns the rolling median, a failed measurement leaves
+// the median unchanged since the server has just responded to the info query anyway.
+func (daemon *Scraper) ping(ctx context.Context, address string) int {
+	value, _ := daemon.pings.LoadOrStore(address, &probe.Latency{})
+	latency := value.(*probe.Latency)
+
+	if daemon.config.PingFunction == nil {
+		return latency.Median()
+	}
+
+	ping, err := daemon.config.PingFunction(ctx, address)
+	if err != nil {
+		daemon.metrics.PingFailures.Inc()
+		return latency.Median()
+	}
+	daemon.metrics.Ping.Observe(ping.Seconds())
+	return latency.Add(ping)
+}
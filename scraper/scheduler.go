@@ -0,0 +1,400 @@
+package scraper
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// DemandWindow is how long a server counts as recently viewed after a request for it
+	DemandWindow = time.Minute * 5
+	// VolatilityScale is the average change in player count between queries that halves the
+	// interval of a server
+	VolatilityScale = 10.0
+
+	volatilityDecay = 0.3             // weight of the latest change in the moving average
+	idleWait        = time.Minute     // how long to sleep when nothing is scheduled
+	recordInterval  = time.Second * 5 // how often the queue gauges are updated
+)
+
+// scheduler decides when each address is queried. Instead of a fixed interval each address gets
+// one based on how much its player count moves, how often it fails to respond and how recently it
+// was requested from the API, all queries share a budget of queries per second.
+type scheduler struct {
+	config  Config
+	run     func(string)
+	metrics *metrics
+	wake    chan struct{}
+
+	lock    sync.Mutex
+	entries map[string]*entry
+	queue   queue
+	last    time.Time // when the most recent query was made, for pacing the budget
+}
+
+// entry is the scheduling state of a single address
+type entry struct {
+	address    string
+	next       time.Time // when the address is due
+	last       time.Time // when the last query finished
+	viewed     time.Time // when the address was last requested from the API
+	index      int       // position in the queue, -1 while a query is running
+	failing    bool      // archived addresses are queried at the maximum interval
+	failures   int       // failed queries in a row
+	observed   bool      // whether players has been set by a query yet
+	players    int       // player count from the last successful query
+	volatility float64   // moving average of the change in player count between queries
+}
+
+func newScheduler(config Config, run func(string), metrics *metrics) *scheduler {
+	return &scheduler{
+		config:  config,
+		run:     run,
+		metrics: metrics,
+		wake:    make(chan struct{}, 1),
+		entries: make(map[string]*entry),
+	}
+}
+
+// Run dispatches queries as they become due until the context is cancelled
+func (s *scheduler) Run(ctx context.Context) {
+	record := time.NewTicker(recordInterval)
+	defer record.Stop()
+
+	for {
+		now := time.Now()
+		select {
+		case <-record.C:
+			s.record(now)
+		default:
+		}
+
+		e, due, wait := s.pop(now)
+		if e == nil {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-s.wake:
+			case <-record.C:
+				s.record(now)
+			case <-timer.C:
+			}
+			timer.Stop()
+			continue
+		}
+
+		if delay := s.reserve(now); delay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		s.metrics.QueueLag.Observe(time.Since(due).Seconds())
+		go s.dispatch(e)
+	}
+}
+
+// pop takes the next address off the queue if it's due, otherwise it returns how long to wait
+func (s *scheduler) pop(now time.Time) (e *entry, due time.Time, wait time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, due, idleWait
+	}
+	if head := s.queue[0]; head.next.After(now) {
+		return nil, due, head.next.Sub(now)
+	}
+	e = heap.Pop(&s.queue).(*entry)
+	return e, e.next, 0
+}
+
+// reserve claims the next slot in the budget and returns how long to wait for it
+func (s *scheduler) reserve(now time.Time) time.Duration {
+	if s.config.QueryBudget <= 0 {
+		return 0
+	}
+	gap := time.Duration(float64(time.Second) / s.config.QueryBudget)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	next := s.last.Add(gap)
+	if next.Before(now) {
+		next = now
+	}
+	s.last = next
+	return next.Sub(now)
+}
+
+// dispatch runs a query then puts the address back on the queue, unless it was removed meanwhile
+func (s *scheduler) dispatch(e *entry) {
+	if s.current(e) {
+		s.run(e.address)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.entries[e.address] != e {
+		return
+	}
+	now := time.Now()
+	interval := s.interval(e, now)
+	e.last = now
+	e.next = now.Add(interval)
+	heap.Push(&s.queue, e)
+	s.signal(e)
+	s.metrics.Interval.Observe(interval.Seconds())
+}
+
+// current checks an entry hasn't been removed or replaced since it was taken off the queue
+func (s *scheduler) current(e *entry) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.entries[e.address] == e
+}
+
+// interval calculates how long to wait between queries of an address
+func (s *scheduler) interval(e *entry, now time.Time) time.Duration {
+	if e.failing {
+		return s.config.MaxInterval
+	}
+
+	interval := s.config.QueryInterval
+	if e.observed && e.players == 0 && e.volatility < 1 {
+		interval *= 4 // empty and nobody has been joining
+	} else {
+		interval = time.Duration(float64(interval) / (1 + e.volatility/VolatilityScale))
+	}
+
+	// back off from servers that are failing to respond, they're archived after MaxFailed anyway
+	interval = time.Duration(float64(interval) * math.Pow(2, float64(e.failures)))
+
+	if !e.viewed.IsZero() && now.Sub(e.viewed) < DemandWindow {
+		interval /= 4
+	}
+
+	if interval < s.config.MinInterval {
+		return s.config.MinInterval
+	}
+	if interval > s.config.MaxInterval {
+		return s.config.MaxInterval
+	}
+	return interval
+}
+
+// record updates the queue gauges
+func (s *scheduler) record(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	overdue := 0
+	for _, e := range s.queue {
+		if !e.next.After(now) {
+			overdue++
+		}
+	}
+	s.metrics.QueueDepth.Set(float64(len(s.entries)))
+	s.metrics.QueueOverdue.Set(float64(overdue))
+	s.metrics.QueueRunning.Set(float64(len(s.entries) - len(s.queue)))
+}
+
+// Add schedules an address, its first query is spread randomly over the interval so a large batch
+// of addresses doesn't all get queried at once. Returns false if the address was already scheduled.
+func (s *scheduler) Add(address string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exists := s.entries[address]; exists {
+		return false
+	}
+	now := time.Now()
+	e := &entry{
+		address: address,
+		next:    now.Add(time.Duration(rand.Int63n(int64(s.config.QueryInterval)))),
+		last:    now,
+	}
+	s.entries[address] = e
+	heap.Push(&s.queue, e)
+	s.signal(e)
+	return true
+}
+
+// Remove unschedules an address, a query that's already running is allowed to finish
+func (s *scheduler) Remove(address string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exists := s.entries[address]
+	if !exists {
+		return false
+	}
+	delete(s.entries, address)
+	if e.index >= 0 {
+		heap.Remove(&s.queue, e.index)
+	}
+	return true
+}
+
+// Exists checks if an address is scheduled
+func (s *scheduler) Exists(address string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, exists := s.entries[address]
+	return exists
+}
+
+// Addresses returns every scheduled address
+func (s *scheduler) Addresses() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make([]string, 0, len(s.entries))
+	for address := range s.entries {
+		result = append(result, address)
+	}
+	return result
+}
+
+// Failures returns the failed queries in a row for an address and whether it's failing
+func (s *scheduler) Failures(address string) (attempts int, failing bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if e, exists := s.entries[address]; exists {
+		return e.failures, e.failing
+	}
+	return 0, false
+}
+
+// SetFailing moves an address in or out of the failing state, the failure count starts again
+func (s *scheduler) SetFailing(address string, failing bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if e, exists := s.entries[address]; exists {
+		e.failing = failing
+		e.failures = 0
+	}
+}
+
+// Observe records a successful query and how much the player count changed since the last one
+func (s *scheduler) Observe(address string, players int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exists := s.entries[address]
+	if !exists {
+		return
+	}
+	if e.observed {
+		change := math.Abs(float64(players - e.players))
+		e.volatility = e.volatility*(1-volatilityDecay) + change*volatilityDecay
+	}
+	e.observed = true
+	e.players = players
+	e.failures = 0
+}
+
+// Fail records a failed query and returns the number of failed queries in a row
+func (s *scheduler) Fail(address string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exists := s.entries[address]
+	if !exists {
+		return 0
+	}
+	e.failures++
+	return e.failures
+}
+
+// Viewed marks an address as requested from the API, if it's waiting for a query it's brought
+// forward to the interval it would have had if it was viewed before the last query.
+func (s *scheduler) Viewed(address string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exists := s.entries[address]
+	if !exists {
+		return
+	}
+	now := time.Now()
+	e.viewed = now
+	if e.index < 0 {
+		return // running, the next interval will take the view into account
+	}
+
+	next := e.last.Add(s.interval(e, now))
+	if next.Before(now) {
+		next = now
+	}
+	if next.Before(e.next) {
+		e.next = next
+		heap.Fix(&s.queue, e.index)
+		s.signal(e)
+	}
+}
+
+// Expedite makes an address due now, unless a query is already running
+func (s *scheduler) Expedite(address string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, exists := s.entries[address]
+	if !exists || e.index < 0 {
+		return
+	}
+	if now := time.Now(); e.next.After(now) {
+		e.next = now
+		heap.Fix(&s.queue, e.index)
+		s.signal(e)
+	}
+}
+
+// signal wakes up Run if an entry is now at the head of the queue, must hold the lock
+func (s *scheduler) signal(e *entry) {
+	if e.index != 0 {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// queue is a heap of entries ordered by when they're due
+type queue []*entry
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*q = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,174 @@
+package scraper
+
+import (
+	"container/heap"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testScheduler(run func(string)) *scheduler {
+	return newScheduler(Config{
+		QueryInterval: time.Minute,
+		MinInterval:   time.Second * 15,
+		MaxInterval:   time.Minute * 10,
+	}, run, newMetrics())
+}
+
+func TestScheduler_Interval(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		entry entry
+		want  time.Duration
+	}{
+		{"new", entry{}, time.Minute},
+		{"steady", entry{observed: true, players: 50}, time.Minute},
+		{"idle", entry{observed: true}, time.Minute * 4},
+		{"empty.volatile", entry{observed: true, volatility: 10}, time.Second * 30},
+		{"volatile", entry{observed: true, players: 300, volatility: 10}, time.Second * 30},
+		{"very.volatile", entry{observed: true, players: 300, volatility: 100}, time.Second * 15},
+		{"failures", entry{failures: 2}, time.Minute * 4},
+		{"many.failures", entry{failures: 8}, time.Minute * 10},
+		{"failing", entry{failing: true, viewed: now}, time.Minute * 10},
+		{"viewed", entry{observed: true, players: 50, viewed: now.Add(-time.Minute)}, time.Second * 15},
+		{"viewed.idle", entry{observed: true, viewed: now.Add(-time.Minute)}, time.Minute},
+		{"viewed.expired", entry{observed: true, players: 50, viewed: now.Add(-DemandWindow)}, time.Minute},
+	}
+	s := testScheduler(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.interval(&tt.entry, now))
+		})
+	}
+}
+
+func TestScheduler_Observe(t *testing.T) {
+	s := testScheduler(nil)
+	s.Add("a")
+
+	s.Observe("a", 10)
+	assert.Equal(t, 0.0, s.entries["a"].volatility, "the first query has nothing to compare to")
+	s.Observe("a", 20)
+	assert.InDelta(t, 3.0, s.entries["a"].volatility, 0.001)
+	s.Observe("a", 20)
+	assert.InDelta(t, 2.1, s.entries["a"].volatility, 0.001)
+
+	assert.Equal(t, 1, s.Fail("a"))
+	assert.Equal(t, 2, s.Fail("a"))
+	s.Observe("a", 20)
+	attempts, failing := s.Failures("a")
+	assert.Equal(t, 0, attempts)
+	assert.False(t, failing)
+
+	s.Fail("a")
+	s.SetFailing("a", true)
+	attempts, failing = s.Failures("a")
+	assert.Equal(t, 0, attempts)
+	assert.True(t, failing)
+}
+
+func TestScheduler_Viewed(t *testing.T) {
+	s := testScheduler(nil)
+	s.Add("a")
+	s.Add("b")
+
+	now := time.Now()
+	a := s.entries["a"]
+	a.observed, a.players = true, 50
+	a.last, a.next = now, now.Add(time.Hour)
+	s.entries["b"].next = now.Add(time.Minute * 30)
+	heap.Init(&s.queue)
+
+	s.Viewed("a")
+	assert.WithinDuration(t, now.Add(time.Second*15), a.next, time.Second)
+	assert.Equal(t, 0, a.index, "brought to the front of the queue")
+
+	// views never push a query further back
+	a.next = now
+	heap.Init(&s.queue)
+	s.Viewed("a")
+	assert.Equal(t, now, a.next)
+}
+
+func TestScheduler_Expedite(t *testing.T) {
+	s := testScheduler(nil)
+	s.Add("a")
+	s.Add("b")
+
+	now := time.Now()
+	s.entries["a"].next = now.Add(time.Hour)
+	s.entries["b"].next = now.Add(time.Minute)
+	heap.Init(&s.queue)
+
+	s.Expedite("a")
+	assert.False(t, s.entries["a"].next.After(time.Now()))
+	assert.Equal(t, 0, s.entries["a"].index)
+
+	// running queries aren't touched
+	e, _, _ := s.pop(time.Now())
+	s.Expedite("a")
+	assert.Equal(t, -1, e.index)
+	s.Expedite("c")
+}
+
+func TestScheduler_Reserve(t *testing.T) {
+	s := testScheduler(nil)
+	now := time.Now()
+	assert.Equal(t, time.Duration(0), s.reserve(now), "no budget")
+
+	s.config.QueryBudget = 10
+	assert.Equal(t, time.Duration(0), s.reserve(now))
+	assert.Equal(t, time.Millisecond*100, s.reserve(now))
+	assert.Equal(t, time.Millisecond*200, s.reserve(now))
+	assert.Equal(t, time.Duration(0), s.reserve(now.Add(time.Second)), "unused budget doesn't accumulate")
+	assert.Equal(t, time.Millisecond*100, s.reserve(now.Add(time.Second)))
+}
+
+func TestScheduler_Run(t *testing.T) {
+	queried := make(chan string, 100)
+	s := newScheduler(Config{
+		QueryInterval: time.Millisecond * 10,
+		MinInterval:   time.Millisecond * 10,
+		MaxInterval:   time.Millisecond * 10,
+	}, func(address string) {
+		select {
+		case queried <- address:
+		default:
+		}
+	}, newMetrics())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	s.Add("a")
+	s.Add("b")
+	seen := map[string]int{}
+	timeout := time.After(time.Second * 5)
+	for seen["a"] < 2 || seen["b"] < 2 {
+		select {
+		case address := <-queried:
+			seen[address]++
+		case <-timeout:
+			t.Fatalf("addresses weren't queried repeatedly: %v", seen)
+		}
+	}
+
+	s.Remove("a")
+	time.Sleep(time.Millisecond * 50) // let a running query finish
+	for len(queried) > 0 {
+		<-queried
+	}
+	collect := time.After(time.Millisecond * 100)
+	for {
+		select {
+		case address := <-queried:
+			assert.Equal(t, "b", address)
+		case <-collect:
+			return
+		}
+	}
+}
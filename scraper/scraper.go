@@ -2,173 +2,302 @@ package scraper
 
 import (
 	"context"
-	"net"
+	"math"
 	"time"
 
-	"github.com/Southclaws/go-samp-query"
-	"github.com/Southclaws/tickerpool"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/syncmap"
 
+	"github.com/Southclaws/samp-servers-api/probe"
 	"github.com/Southclaws/samp-servers-api/types"
 )
 
 // Config contains parameters to tweak the scraper performance
 type Config struct {
-	QueryInterval    time.Duration      // interval between query attempts
-	MaxFailed        int                // maximum number of failed query attempts before removing address
-	QueryFunction    QueryFunction      // function for querying servers
-	OnRequestArchive func(string)       // called to archive an address
-	OnRequestRemove  func(string)       // called to remove an address
-	OnRequestUpdate  func(types.Server) // called to update an address
+	QueryInterval    time.Duration                 // interval between query attempts for an average server
+	MinInterval      time.Duration                 // shortest interval for busy or popular servers, defaults to a quarter of QueryInterval
+	MaxInterval      time.Duration                 // longest interval for idle or failing servers, defaults to ten times QueryInterval
+	QueryBudget      float64                       // maximum queries per second across all servers, zero for no limit
+	QueryTimeout     time.Duration                 // timeout for querying a single server, defaults to 10 seconds
+	MaxFailed        int                           // maximum number of failed query attempts before removing address
+	Querier          probe.Querier                 // sends the queries, usually a shared probe.Engine
+	Protocols        []probe.Protocol              // protocols to detect the platform of servers, defaults to probe.Protocols
+	QueryPlayers     bool                          // whether to also collect player lists
+	PlayersFunction  probe.PlayersFunction         // function for querying player lists
+	PingFunction     probe.PingFunction            // function for measuring latency, may be nil
+	Banned           BanFunction                   // checks servers against the ban list, may be nil
+	Owns             func(string) bool             // whether this replica queries an address, may be nil
+	OnRequestArchive func(string)                  // called to archive an address
+	OnRequestRemove  func(string)                  // called to remove an address
+	OnRequestUpdate  func(types.Server, int, bool) // called to update an address with the queries it missed since the last update and whether it may be created
 }
 
 // Scraper crawls through a list of server addresses and gathers information about them via the
 // legacy query API, it then stores the results as standard Server objects, accessible via the API.
 type Scraper struct {
-	config         Config
-	ctx            context.Context
-	failedAttempts *syncmap.Map
-	active         *tickerpool.TickerPool
-	failed         *tickerpool.TickerPool
-	metrics        *metrics
+	config   Config
+	ctx      context.Context
+	pings    *syncmap.Map // address -> *probe.Latency
+	pending  *syncmap.Map // addresses added by a request that haven't been stored by a query yet
+	schedule *scheduler
+	metrics  *metrics
 }
 
-// QueryFunction represents a function capable of retreiving server information via the server API
-type QueryFunction func(context.Context, string, bool) (sampquery.Server, error)
+// BanFunction returns an error if a server is banned, the IP and hostname are empty when an address
+// is added since they aren't known until it's queried.
+type BanFunction func(address, ip, hostname string) error
 
 // New sets up the query daemon and starts the background processes
 func New(ctx context.Context, initial []string, config Config) (daemon *Scraper, err error) {
+	if config.QueryInterval <= 0 {
+		return nil, errors.New("query interval must be positive")
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = config.QueryInterval / 4
+	}
+	if config.MaxInterval <= 0 {
+		config.MaxInterval = config.QueryInterval * 10 // query failed servers less often
+	}
+	if config.Protocols == nil {
+		config.Protocols = probe.Protocols
+	}
+	if config.QueryTimeout <= 0 {
+		config.QueryTimeout = time.Second * 10
+	}
+	if config.MinInterval > config.MaxInterval {
+		return nil, errors.New("minimum query interval is longer than the maximum")
+	}
+
 	daemon = &Scraper{
-		config:         config,
-		ctx:            ctx,
-		failedAttempts: &syncmap.Map{},
-		metrics:        newMetricsRecorder(),
+		config:  config,
+		ctx:     ctx,
+		pings:   &syncmap.Map{},
+		pending: &syncmap.Map{},
+		metrics: newMetricsRecorder(),
 	}
+	daemon.schedule = newScheduler(config, daemon.run, daemon.metrics)
 
-	daemon.active, err = tickerpool.NewTickerPool(config.QueryInterval)
-	if err != nil {
-		err = errors.Wrap(err, "failed to create active ticker pool")
+	for _, address := range initial {
+		if errInner := daemon.add(address); errInner != nil {
+			// addresses banned since they were stored are dropped from the index
+			daemon.config.OnRequestRemove(address)
+		}
+	}
+
+	go daemon.schedule.Run(ctx)
+
+	return
+}
+
+// Add will add a new address to the schedule and query it periodically, banned addresses are
+// rejected with the error from the ban check. The first query is always made by this replica even
+// if another one owns the address since the others won't know about it until it's stored.
+func (daemon *Scraper) Add(address string) (err error) {
+	if daemon.Exists(address) {
+		return daemon.Check(address, "", "")
+	}
+	if err = daemon.add(address); err != nil {
 		return
 	}
+	daemon.pending.Store(address, true)
+	return
+}
 
-	daemon.failed, err = tickerpool.NewTickerPool(config.QueryInterval * 10) // query failed servers less often
+// Announce adds an address that a server registered itself under. The server is queried first to
+// confirm it's really running there, then it's queried again through the schedule straight away so
+// it's listed without waiting for its first interval.
+func (daemon *Scraper) Announce(ctx context.Context, address string) (err error) {
+	if err = daemon.Check(address, "", ""); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, daemon.config.QueryTimeout)
+	defer cancel()
+
+	server, err := probe.Query(ctx, daemon.config.Querier, address, false)
 	if err != nil {
-		err = errors.Wrap(err, "failed to create failed ticker pool")
+		return errors.Wrap(err, "server did not respond to a query")
+	}
+	if err = daemon.Check(address, server.IP, server.Core.Hostname); err != nil {
 		return
 	}
 
-	for _, address := range initial {
-		daemon.Add(address)
+	if err = daemon.Add(address); err != nil {
+		return
+	}
+	daemon.pending.Store(address, true) // the replica that was announced to makes the first query
+	daemon.schedule.Expedite(address)
+	daemon.metrics.Announces.Inc()
+	return
+}
+
+// Sync brings the rotation in line with the stored addresses when the index is shared with other
+// replicas, addresses stored by them are added and those removed by them are dropped. Addresses
+// that haven't answered their first query yet aren't stored so they're kept.
+func (daemon *Scraper) Sync(stored []string) {
+	keep := make(map[string]bool, len(stored))
+	for _, address := range stored {
+		keep[address] = true
+		if !daemon.Exists(address) {
+			daemon.add(address) // nolint:errcheck
+		}
+	}
+
+	for _, address := range daemon.schedule.Addresses() {
+		if _, pending := daemon.pending.Load(address); !keep[address] && !pending {
+			daemon.drop(address)
+		}
+	}
+}
+
+// Viewed lets the scheduler know a server was requested from the API so it's refreshed sooner
+func (daemon *Scraper) Viewed(address string) {
+	daemon.schedule.Viewed(address)
+}
+
+// owns checks whether this replica should query an address on this tick, addresses added to this
+// replica are queried by it until they're stored
+func (daemon *Scraper) owns(address string) bool {
+	if _, pending := daemon.pending.Load(address); pending {
+		return true
+	}
+	return daemon.config.Owns == nil || daemon.config.Owns(address)
+}
+
+func (daemon *Scraper) add(address string) (err error) {
+	if err = daemon.Check(address, "", ""); err != nil {
+		return
 	}
 
+	daemon.schedule.Add(address)
 	return
 }
 
-// Add will add a new address to the TickerPool and query it periodically
-func (daemon *Scraper) Add(address string) {
-	daemon.active.Add(address, func() {
-		queryStart := time.Now()
+// run is called by the scheduler each time an address is due
+func (daemon *Scraper) run(address string) {
+	if !daemon.owns(address) {
+		daemon.metrics.Skips.Inc()
+		return
+	}
+
+	if _, failing := daemon.schedule.Failures(address); failing {
 		remove, err := daemon.query(address)
 		if err != nil {
-			daemon.metrics.Failures.Inc()
 			if remove {
-				daemon.metrics.Archives.Inc()
-				daemon.config.OnRequestArchive(address)
-				daemon.addFailed(address)
+				daemon.Remove(address)
 			}
-		} else {
-			daemon.metrics.Successes.Inc()
 		}
-		daemon.metrics.QueryTime.Observe(time.Since(queryStart).Seconds())
-		daemon.metrics.Queries.Inc()
-	})
+		return
+	}
+
+	queryStart := time.Now()
+	remove, err := daemon.query(address)
+	if err != nil {
+		daemon.metrics.Failures.Inc()
+		if remove {
+			daemon.metrics.Archives.Inc()
+			daemon.config.OnRequestArchive(address)
+			daemon.addFailed(address)
+		}
+	} else {
+		daemon.metrics.Successes.Inc()
+	}
+	daemon.metrics.QueryTime.Observe(time.Since(queryStart).Seconds())
+	daemon.metrics.Queries.Inc()
 }
 
-// Remove will remove an address from the query rotation
+// Check returns an error if a server is banned
+func (daemon *Scraper) Check(address, ip, hostname string) (err error) {
+	if daemon.config.Banned == nil {
+		return nil
+	}
+	if err = daemon.config.Banned(address, ip, hostname); err != nil {
+		daemon.metrics.Bans.Inc()
+	}
+	return
+}
+
+// Remove will remove an address from the query rotation, whether it's active or failing
 func (daemon *Scraper) Remove(address string) {
-	if daemon.active.Exists(address) {
-		daemon.failedAttempts.Delete(address)
-		daemon.active.Remove(address)
-		daemon.metrics.Removals.Inc()
+	if !daemon.Exists(address) {
+		return
+	}
+
+	daemon.Drop(address)
+	daemon.config.OnRequestRemove(address)
+}
+
+// Drop removes an address from the query rotation without calling OnRequestRemove, for callers that
+// have already removed it from storage themselves
+func (daemon *Scraper) Drop(address string) {
+	if !daemon.Exists(address) {
+		return
+	}
 
-		daemon.config.OnRequestRemove(address)
+	daemon.drop(address)
+	daemon.metrics.Removals.Inc()
+}
+
+// drop takes an address out of the rotation without removing it from storage
+func (daemon *Scraper) drop(address string) {
+	daemon.pings.Delete(address)
+	daemon.pending.Delete(address)
+	daemon.schedule.Remove(address)
+}
+
+// Archive immediately moves an address to the failing rotation and archives it, the address is
+// restored if it later responds to a query.
+func (daemon *Scraper) Archive(address string) {
+	if _, failing := daemon.schedule.Failures(address); failing || !daemon.Exists(address) {
+		return
 	}
+
+	daemon.metrics.Archives.Inc()
+	daemon.config.OnRequestArchive(address)
+	daemon.addFailed(address)
+}
+
+// Exists checks if an address is in the query schedule
+func (daemon *Scraper) Exists(address string) bool {
+	return daemon.schedule.Exists(address)
+}
+
+// Failures returns the number of failed queries in a row for an address and whether it has been
+// marked as failing and is queried less often.
+func (daemon *Scraper) Failures(address string) (attempts int, failing bool) {
+	return daemon.schedule.Failures(address)
 }
 
 // addFailed marks a server as "inactive" and queries it less often
 func (daemon *Scraper) addFailed(address string) {
-	daemon.failedAttempts.Delete(address)
-	daemon.active.Remove(address)
-
-	daemon.failed.Add(address, func() {
-		remove, err := daemon.query(address)
-		if err != nil {
-			if remove {
-				daemon.Remove(address)
-			}
-		}
-	})
+	daemon.pings.Delete(address) // a revived server may well be hosted somewhere else
+	daemon.schedule.SetFailing(address, true)
 }
 
 // removeFailed is called when a server is "revived" so it can be added back to the regular rotation
 func (daemon *Scraper) removeFailed(address string) {
-	if daemon.active.Exists(address) {
-		daemon.failedAttempts.Delete(address)
-		daemon.failed.Remove(address)
-		daemon.Add(address)
+	if _, failing := daemon.schedule.Failures(address); failing {
+		daemon.schedule.SetFailing(address, false)
 	}
 }
 
 func (daemon *Scraper) query(address string) (remove bool, err error) {
-	tmp, hasFailed := daemon.failedAttempts.Load(address)
-	attempts, _ := tmp.(int)
+	missed, _ := daemon.schedule.Failures(address)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(daemon.ctx, daemon.config.QueryTimeout)
 	defer cancel()
 
-	serverData, err := daemon.config.QueryFunction(ctx, address, true)
+	server, err := probe.Query(ctx, daemon.config.Querier, address, true)
 	if err != nil {
-		if hasFailed {
-			if attempts > daemon.config.MaxFailed {
-				return true, err
-			}
-			daemon.failedAttempts.Store(address, attempts+1)
-			return false, err
-		}
-		daemon.failedAttempts.Store(address, 1)
-		return false, err
+		return daemon.schedule.Fail(address) > daemon.config.MaxFailed, err
 	}
 
-	if hasFailed {
-		daemon.failedAttempts.Delete(address)
-	}
 	daemon.removeFailed(address)
 
-	var ip string
-	addrs, err := net.LookupHost(serverData.Address)
-	if err != nil {
-		err = nil
-	}
-	if len(addrs) > 0 {
-		ip = addrs[0]
-	}
-	if ip == "" {
-		ip = serverData.Address
-	}
-
-	server := types.Server{
-		IP: ip,
-		Core: types.ServerCore{
-			Address:    serverData.Address,
-			Hostname:   serverData.Hostname,
-			Players:    serverData.Players,
-			MaxPlayers: serverData.MaxPlayers,
-			Gamemode:   serverData.Gamemode,
-			Language:   serverData.Language,
-			Password:   serverData.Password,
-		},
-		Rules: serverData.Rules,
+	// bans on ranges and hostnames can only be checked once the server has responded
+	if daemon.Check(address, server.IP, server.Core.Hostname) != nil {
+		daemon.Remove(address)
+		return false, nil
 	}
 
 	if server.Core.Players > server.Core.MaxPlayers {
@@ -178,11 +307,53 @@ func (daemon *Scraper) query(address string) (remove bool, err error) {
 		return true, nil
 	}
 
-	version, ok := serverData.Rules["version"]
-	if ok {
-		server.Core.Version = version
+	daemon.schedule.Observe(address, server.Core.Players)
+	if err = probe.Detect(ctx, daemon.config.Querier, daemon.config.Protocols, &server); err != nil {
+		daemon.metrics.ExtraFailures.Inc() // the platform is still known, only its extra information is missing
+	}
+	server.Core.Ping = daemon.ping(ctx, address)
+
+	if daemon.config.QueryPlayers {
+		// the player list queries have their own timeouts rather than what's left of ctx
+		server.PlayerList = daemon.queryPlayers(daemon.ctx, server.Core)
 	}
-	daemon.config.OnRequestUpdate(server)
+
+	// only the first query of an added address creates its record, later ones must not recreate a
+	// server that was removed while it was being queried
+	_, create := daemon.pending.Load(address)
+	daemon.config.OnRequestUpdate(server, missed, create)
+	daemon.pending.Delete(address)
 
 	return false, nil
 }
+
+// uptimeDecay is the weight of each query in the moving average of a server's uptime
+const uptimeDecay = 0.05
+
+// Uptime returns the new percentage of queries a server answered from the previous one, the server
+// has just answered a query after missing some number of queries in a row
+func Uptime(previous float64, missed int) float64 {
+	if missed > 0 {
+		previous *= math.Pow(1-uptimeDecay, float64(missed))
+	}
+	return previous*(1-uptimeDecay) + 100*uptimeDecay
+}
+
+// Missed returns the number of queries a server missed when it last answered elapsed ago and is
+// queried every interval, used for archived servers which are retried less often than that
+func Missed(elapsed, interval time.Duration) int {
+	if interval <= 0 || elapsed <= interval {
+		return 0
+	}
+	return int(elapsed/interval) - 1
+}
+
+// queryPlayers collects the player list of a server, failures are not fatal since the list is
+// optional so they're only counted.
+func (daemon *Scraper) queryPlayers(ctx context.Context, core types.ServerCore) *types.PlayerList {
+	list, err := probe.GetPlayerList(ctx, daemon.config.PlayersFunction, core)
+	if err != nil {
+		daemon.metrics.PlayerListFailures.Inc()
+	}
+	return list
+}
@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Southclaws/go-samp-query"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/probe"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestScraper(t *testing.T) {
+	var archived, removed []string
+	var creates []bool
+	daemon, err := New(context.Background(), []string{"s1.example.com:7777", "s2.example.com:7777", "banned.example.com:7777"}, Config{
+		QueryInterval: time.Hour, // no queries are made during the test
+		Querier: probe.QuerierFunc(func(ctx context.Context, address string, opcode sampquery.QueryType) ([]byte, error) {
+			if address == "10.0.0.6:7777" || address == "s5.example.com:7777" {
+				return response(opcode), nil
+			}
+			return nil, errors.New("timeout") // never revives an archived server
+		}),
+		OnRequestArchive: func(address string) { archived = append(archived, address) },
+		OnRequestRemove:  func(address string) { removed = append(removed, address) },
+		OnRequestUpdate: func(server types.Server, missed int, create bool) {
+			if server.Core.Address == "s5.example.com:7777" {
+				creates = append(creates, create)
+			}
+		},
+		Owns: func(address string) bool { return address != "s4.example.com:7777" },
+		Banned: func(address, ip, hostname string) error {
+			if address == "banned.example.com:7777" {
+				return errors.New("banned")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	// banned addresses from storage are dropped and can't be added again
+	assert.Equal(t, []string{"banned.example.com:7777"}, removed)
+	assert.False(t, daemon.Exists("banned.example.com:7777"))
+	assert.Error(t, daemon.Add("banned.example.com:7777"))
+	removed = nil
+
+	daemon.Archive("s1.example.com:7777")
+	assert.Equal(t, []string{"s1.example.com:7777"}, archived)
+	assert.True(t, daemon.Exists("s1.example.com:7777"))
+	_, failing := daemon.Failures("s1.example.com:7777")
+	assert.True(t, failing)
+
+	// archived servers can still be removed
+	daemon.Remove("s1.example.com:7777")
+	daemon.Remove("s2.example.com:7777")
+	daemon.Remove("s3.example.com:7777")
+	assert.Equal(t, []string{"s1.example.com:7777", "s2.example.com:7777"}, removed)
+	assert.False(t, daemon.Exists("s1.example.com:7777"))
+	assert.False(t, daemon.Exists("s2.example.com:7777"))
+
+	removed = nil
+
+	// addresses stored by other replicas are picked up and those they removed are dropped, new
+	// addresses are kept until their first query since they aren't stored until then
+	assert.NoError(t, daemon.Add("s5.example.com:7777"))
+	daemon.Sync([]string{"s4.example.com:7777"})
+	assert.True(t, daemon.Exists("s4.example.com:7777"))
+	assert.True(t, daemon.Exists("s5.example.com:7777"))
+	assert.True(t, daemon.owns("s5.example.com:7777"))
+	assert.False(t, daemon.owns("s4.example.com:7777"))
+
+	daemon.Sync([]string{"s4.example.com:7777"})
+	assert.True(t, daemon.Exists("s5.example.com:7777"))
+
+	// only the first query may create the record so a removed server isn't recreated by later ones
+	_, err = daemon.query("s5.example.com:7777")
+	assert.NoError(t, err)
+	_, err = daemon.query("s5.example.com:7777")
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, creates)
+
+	daemon.Sync([]string{"s4.example.com:7777"})
+	assert.False(t, daemon.Exists("s5.example.com:7777"))
+	assert.Empty(t, removed, "dropping an address doesn't remove it from storage")
+
+	// announced servers are only added once they respond
+	assert.Error(t, daemon.Announce(context.Background(), "10.0.0.7:7777"))
+	assert.False(t, daemon.Exists("10.0.0.7:7777"))
+	assert.Error(t, daemon.Announce(context.Background(), "banned.example.com:7777"))
+	assert.NoError(t, daemon.Announce(context.Background(), "10.0.0.6:7777"))
+	assert.True(t, daemon.Exists("10.0.0.6:7777"))
+}
+
+// response builds a minimal response to an info or rules query
+func response(opcode sampquery.QueryType) []byte {
+	packet := append([]byte("SAMP"), 10, 0, 0, 6, 0x61, 0x1e, byte(opcode))
+	switch opcode {
+	case sampquery.Info:
+		packet = append(packet, 0, 1, 0, 32, 0)
+		for _, field := range []string{"announced", "gamemode", "English"} {
+			packet = append(packet, byte(len(field)), 0, 0, 0)
+			packet = append(packet, field...)
+		}
+	case sampquery.Rules:
+		packet = append(packet, 1, 0, 7)
+		packet = append(packet, "version"...)
+		packet = append(packet, 8)
+		packet = append(packet, "0.3.7-R2"...)
+	}
+	return packet
+}
+
+func TestUptime(t *testing.T) {
+	assert.Equal(t, 100.0, Uptime(100, 0))
+	assert.InDelta(t, 90.25+5, Uptime(100, 1), 0.0001)
+	assert.InDelta(t, 52.5, Uptime(50, 0), 0.0001)
+
+	// a server that answers every other query settles at around half
+	uptime := 100.0
+	for i := 0; i < 200; i++ {
+		uptime = Uptime(uptime, 1)
+	}
+	assert.InDelta(t, 51.3, uptime, 0.1)
+
+	// a server that comes back after a long time away starts again from almost nothing
+	assert.InDelta(t, 5, Uptime(100, 100000), 0.0001)
+}
+
+func TestMissed(t *testing.T) {
+	assert.Equal(t, 0, Missed(0, time.Minute))
+	assert.Equal(t, 0, Missed(time.Minute, time.Minute))
+	assert.Equal(t, 1, Missed(2*time.Minute, time.Minute))
+	assert.Equal(t, 59, Missed(time.Hour+time.Second, time.Minute))
+	assert.Equal(t, 0, Missed(time.Hour, 0))
+}
@@ -0,0 +1,355 @@
+// Package search is an in-process full text index of the hostnames, gamemodes and descriptions of
+// the active servers. It's kept current as servers are queried so it works the same on every
+// storage backend and doesn't need text indexes in the database.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+const (
+	// DefaultLimit is the number of results returned when a limit isn't specified
+	DefaultLimit = 20
+	// MaxLimit is the most results a single search can return
+	MaxLimit = 100
+	// MaxQueryLength is the most characters a query can have
+	MaxQueryLength = 200
+	// MaxWords is the most distinct words a query can have
+	MaxWords = 8
+)
+
+// fields are the indexed parts of a server, matches in the hostname count for more than matches in
+// the gamemode or description
+var fields = []struct {
+	name   string
+	weight float64
+	value  func(types.Server) string
+}{
+	{"hostname", 3, func(s types.Server) string { return s.Core.Hostname }},
+	{"gamemode", 2, func(s types.Server) string { return s.Core.Gamemode }},
+	{"description", 1, func(s types.Server) string { return s.Description }},
+}
+
+// how much a term counts for depending on how it matched a word of the query
+const (
+	similarityExact  = 1.0
+	similarityPrefix = 0.7
+	similarityFuzzy  = 0.6 // divided by the edit distance
+)
+
+// prefixLength is the shortest word that matches the start of terms, terms are grouped by their
+// first prefixLength characters so prefix matching only looks at terms that could match
+const prefixLength = 3
+
+// Index maps the terms of each server's fields to the servers they appear in
+type Index struct {
+	lock     sync.RWMutex
+	servers  map[string]document
+	postings map[string]map[string]struct{}
+	lengths  map[int]map[string]struct{}    // terms by length, for typos
+	prefixes map[string]map[string]struct{} // terms by their first characters, for prefixes
+}
+
+// document is an indexed server
+type document struct {
+	core   types.ServerCore
+	fields []field
+}
+
+// field is the text of an indexed field with its colour codes removed and the words it contains
+type field struct {
+	text   string
+	tokens []token
+}
+
+// candidate is a term from the index that matches a word of the query
+type candidate struct {
+	term       string
+	similarity float64
+}
+
+// New creates an empty index
+func New() *Index {
+	return &Index{
+		servers:  make(map[string]document),
+		postings: make(map[string]map[string]struct{}),
+		lengths:  make(map[int]map[string]struct{}),
+		prefixes: make(map[string]map[string]struct{}),
+	}
+}
+
+// Rebuild replaces the contents of the index with the active servers from the list
+func (idx *Index) Rebuild(servers []types.Server) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.servers = make(map[string]document)
+	idx.postings = make(map[string]map[string]struct{})
+	idx.lengths = make(map[int]map[string]struct{})
+	idx.prefixes = make(map[string]map[string]struct{})
+	for _, server := range servers {
+		if server.Active {
+			idx.add(server)
+		}
+	}
+}
+
+// Update indexes a server that has just been stored, replacing what was previously indexed for its
+// address. Storing a server marks it active so Active isn't checked.
+func (idx *Index) Update(server types.Server) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.remove(server.Core.Address)
+	idx.add(server)
+}
+
+// Remove removes a server from the index
+func (idx *Index) Remove(address string) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.remove(address)
+}
+
+// Len returns the number of indexed servers
+func (idx *Index) Len() int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return len(idx.servers)
+}
+
+func (idx *Index) add(server types.Server) {
+	doc := document{core: server.Core, fields: make([]field, len(fields))}
+	for i, f := range fields {
+		text := StripColours(f.value(server))
+		// tags are the only thing some hostnames have in common so they're left out of hostnames
+		doc.fields[i] = field{text: text, tokens: tokenise(text, f.name != "hostname")}
+		for _, t := range doc.fields[i].tokens {
+			addresses, ok := idx.postings[t.term]
+			if !ok {
+				addresses = make(map[string]struct{})
+				idx.postings[t.term] = addresses
+				idx.group(t.term)
+			}
+			addresses[server.Core.Address] = struct{}{}
+		}
+	}
+	idx.servers[server.Core.Address] = doc
+}
+
+func (idx *Index) remove(address string) {
+	doc, ok := idx.servers[address]
+	if !ok {
+		return
+	}
+	for _, f := range doc.fields {
+		for _, t := range f.tokens {
+			delete(idx.postings[t.term], address)
+			if len(idx.postings[t.term]) == 0 {
+				delete(idx.postings, t.term)
+				idx.ungroup(t.term)
+			}
+		}
+	}
+	delete(idx.servers, address)
+}
+
+// group adds a new term to the length and prefix groups used to find candidates
+func (idx *Index) group(term string) {
+	length := utf8.RuneCountInString(term)
+	if idx.lengths[length] == nil {
+		idx.lengths[length] = make(map[string]struct{})
+	}
+	idx.lengths[length][term] = struct{}{}
+
+	if prefix, ok := prefixOf(term); ok {
+		if idx.prefixes[prefix] == nil {
+			idx.prefixes[prefix] = make(map[string]struct{})
+		}
+		idx.prefixes[prefix][term] = struct{}{}
+	}
+}
+
+// ungroup removes a term that no longer appears in any server from the length and prefix groups
+func (idx *Index) ungroup(term string) {
+	length := utf8.RuneCountInString(term)
+	delete(idx.lengths[length], term)
+	if len(idx.lengths[length]) == 0 {
+		delete(idx.lengths, length)
+	}
+
+	if prefix, ok := prefixOf(term); ok {
+		delete(idx.prefixes[prefix], term)
+		if len(idx.prefixes[prefix]) == 0 {
+			delete(idx.prefixes, prefix)
+		}
+	}
+}
+
+// prefixOf returns the first prefixLength characters of a term, terms shorter than that have none
+func prefixOf(term string) (string, bool) {
+	n := 0
+	for i := range term {
+		if n == prefixLength {
+			return term[:i], true
+		}
+		n++
+	}
+	return term, n == prefixLength
+}
+
+// Search returns the servers that best match the query, at most limit of them. Each word of the
+// query matches terms that are equal to it, start with it or are a small number of typos away.
+// Servers that match more of the words come first and, for equally good matches, servers with more
+// players come first. Queries longer than MaxQueryLength or with more than MaxWords words fail.
+func (idx *Index) Search(query string, limit int) (results []types.SearchResult, err error) {
+	if utf8.RuneCountInString(query) > MaxQueryLength {
+		return nil, errors.Errorf("query can't be longer than %d characters", MaxQueryLength)
+	}
+
+	words := []string{}
+	seen := map[string]bool{}
+	for _, t := range tokenise(StripColours(query), true) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			words = append(words, t.term)
+		}
+	}
+	if len(words) > MaxWords {
+		return nil, errors.Errorf("query can't have more than %d words", MaxWords)
+	}
+	if len(words) == 0 {
+		return
+	}
+
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	type match struct {
+		relevance float64
+		words     int
+		tokens    [][]bool // which tokens of each field matched, for highlighting
+	}
+	matches := map[string]*match{}
+
+	for _, word := range words {
+		best := map[string]float64{}
+		for _, c := range idx.candidates(word) {
+			rarity := 1 + math.Log(float64(len(idx.servers))/float64(len(idx.postings[c.term])))
+			for address := range idx.postings[c.term] {
+				doc := idx.servers[address]
+				m, ok := matches[address]
+				if !ok {
+					m = &match{tokens: make([][]bool, len(fields))}
+					matches[address] = m
+				}
+				for i, f := range doc.fields {
+					for j, t := range f.tokens {
+						if t.term != c.term {
+							continue
+						}
+						if m.tokens[i] == nil {
+							m.tokens[i] = make([]bool, len(f.tokens))
+						}
+						m.tokens[i][j] = true
+						if score := fields[i].weight * c.similarity * rarity; score > best[address] {
+							best[address] = score
+						}
+					}
+				}
+			}
+		}
+		for address, score := range best {
+			matches[address].relevance += score
+			matches[address].words++
+		}
+	}
+
+	for address, m := range matches {
+		doc := idx.servers[address]
+		coverage := float64(m.words) / float64(len(words))
+		popularity := 1 + math.Log1p(float64(doc.core.Players))/10
+		result := types.SearchResult{
+			Core:       doc.core,
+			Score:      math.Round(m.relevance*coverage*popularity*1000) / 1000,
+			Highlights: []types.Highlight{},
+		}
+		for i, f := range doc.fields {
+			if m.tokens[i] == nil {
+				continue
+			}
+			highlight := types.Highlight{Field: fields[i].name, Text: f.text}
+			for j, matched := range m.tokens[i] {
+				if matched {
+					highlight.Matches = append(highlight.Matches, [2]int{f.tokens[j].start, f.tokens[j].end})
+				}
+			}
+			result.Highlights = append(result.Highlights, highlight)
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Core.Players != results[j].Core.Players {
+			return results[i].Core.Players > results[j].Core.Players
+		}
+		return results[i].Core.Address < results[j].Core.Address
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return
+}
+
+// candidates returns the indexed terms that match a word of the query and how closely they match,
+// only terms that share the word's first characters or are close to its length are looked at
+func (idx *Index) candidates(word string) (candidates []candidate) {
+	length := utf8.RuneCountInString(word)
+	typos := 0
+	switch {
+	case length >= 8:
+		typos = 2
+	case length >= 4:
+		typos = 1
+	}
+
+	similarities := map[string]float64{}
+	if _, ok := idx.postings[word]; ok {
+		similarities[word] = similarityExact
+	}
+	if prefix, ok := prefixOf(word); ok {
+		for term := range idx.prefixes[prefix] {
+			if len(term) > len(word) && strings.HasPrefix(term, word) {
+				similarities[term] = similarityPrefix
+			}
+		}
+	}
+	for n := length - typos; typos > 0 && n <= length+typos; n++ {
+		for term := range idx.lengths[n] {
+			if term == word {
+				continue
+			}
+			if d := distance(word, term, typos); d <= typos && similarityFuzzy/float64(d) > similarities[term] {
+				similarities[term] = similarityFuzzy / float64(d)
+			}
+		}
+	}
+
+	for term, similarity := range similarities {
+		candidates = append(candidates, candidate{term, similarity})
+	}
+	return
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestTokenise(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		tags bool
+		want []token
+	}{
+		{"words", "Grand Larceny", true, []token{{"grand", 0, 5}, {"larceny", 6, 13}}},
+		{"punctuation and short words", "LS-RP v2 & a DM", true, []token{{"ls", 0, 2}, {"rp", 3, 5}, {"v2", 6, 8}, {"dm", 13, 15}}},
+		{"tags skipped", "[EN] Los Santos [LSRP]", false, []token{{"los", 5, 8}, {"santos", 9, 15}}},
+		{"tags kept", "[EN] Los", true, []token{{"en", 1, 3}, {"los", 5, 8}}},
+		{"unclosed tag", "[EN Los", false, []token{{"en", 1, 3}, {"los", 4, 7}}},
+		{"unicode", "Сервер RUS", true, []token{{"сервер", 0, 12}, {"rus", 13, 16}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tokenise(tt.text, tt.tags))
+		})
+	}
+}
+
+func TestStripColours(t *testing.T) {
+	assert.Equal(t, "Los Santos Roleplay", StripColours("{FF0000}Los {ffffff}Santos ~r~Roleplay"))
+	assert.Equal(t, "{not a colour}", StripColours("{not a colour}"))
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, distance("roleplay", "roleplay", 2))
+	assert.Equal(t, 1, distance("roleplay", "roleply", 2))
+	assert.Equal(t, 2, distance("rolepaly", "roleplay", 2))
+	assert.Equal(t, 3, distance("freeroam", "roleplay", 2))
+	assert.Equal(t, 2, distance("abc", "abcdef", 1))
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := New()
+	idx.Rebuild([]types.Server{
+		{
+			Core:   types.ServerCore{Address: "a.example.com:7777", Hostname: "[EN] {FF0000}Los Santos Roleplay [LSRP]", Gamemode: "LS-RP v2", Players: 100},
+			Active: true,
+		},
+		{
+			Core:   types.ServerCore{Address: "b.example.com:7777", Hostname: "Los Santos Freeroam", Gamemode: "Freeroam", Players: 300},
+			Active: true,
+		},
+		{
+			Core:        types.ServerCore{Address: "c.example.com:7777", Hostname: "SA-MP SERVER CLAN tdm [NGRP] [GF EDIT]", Gamemode: "Grand Larceny", Players: 32},
+			Description: "An awesome roleplay server",
+			Active:      true,
+		},
+		{
+			Core:   types.ServerCore{Address: "d.example.com:7777", Hostname: "Archived Roleplay"},
+			Active: false,
+		},
+	})
+	assert.Equal(t, 3, idx.Len())
+
+	addresses := func(query string, limit int) (result []string) {
+		results, err := idx.Search(query, limit)
+		assert.NoError(t, err)
+		for _, r := range results {
+			result = append(result, r.Core.Address)
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"a.example.com:7777", "c.example.com:7777"}, addresses("roleplay", 0))
+	assert.Equal(t, []string{"a.example.com:7777", "c.example.com:7777"}, addresses("ROLEPALY", 0), "typos")
+	assert.Equal(t, []string{"c.example.com:7777"}, addresses("larc", 0), "prefixes")
+	assert.Equal(t, []string{"b.example.com:7777", "a.example.com:7777"}, addresses("los santos", 0), "players break ties")
+	assert.Equal(t, []string{"a.example.com:7777", "b.example.com:7777", "c.example.com:7777"}, addresses("santos roleplay", 0), "matching every word first")
+	assert.Equal(t, []string{"b.example.com:7777"}, addresses("los santos", 1))
+	assert.Empty(t, addresses("ngrp", 0), "hostname tags aren't indexed")
+	assert.Empty(t, addresses("!!", 0))
+	assert.Equal(t, []string{"a.example.com:7777", "c.example.com:7777"}, addresses("roleplay roleplay roleplay roleplay roleplay roleplay roleplay roleplay roleplay", 0), "repeated words count once")
+
+	_, err := idx.Search(strings.Repeat("a", MaxQueryLength+1), 0)
+	assert.Error(t, err)
+	_, err = idx.Search("one two three four five six seven eight nine", 0)
+	assert.Error(t, err)
+
+	results, err := idx.Search("roleplay", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, []types.Highlight{
+			{Field: "hostname", Text: "[EN] Los Santos Roleplay [LSRP]", Matches: [][2]int{{16, 24}}},
+		}, results[0].Highlights)
+		assert.Equal(t, []types.Highlight{
+			{Field: "description", Text: "An awesome roleplay server", Matches: [][2]int{{11, 19}}},
+		}, results[1].Highlights)
+		assert.True(t, results[0].Score > results[1].Score)
+	}
+
+	idx.Remove("a.example.com:7777")
+	idx.Update(types.Server{Core: types.ServerCore{Address: "b.example.com:7777", Hostname: "Los Santos Roleplay"}})
+	assert.Equal(t, []string{"b.example.com:7777", "c.example.com:7777"}, addresses("roleplay", 0))
+	assert.Empty(t, addresses("freeroam", 0))
+	assert.Equal(t, 2, idx.Len())
+
+	idx.Remove("b.example.com:7777")
+	idx.Remove("c.example.com:7777")
+	assert.Empty(t, idx.postings)
+	assert.Empty(t, idx.lengths)
+	assert.Empty(t, idx.prefixes)
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// colourCodes matches embedded colours such as `{FF0000}` and game text styles such as `~r~`
+	colourCodes = regexp.MustCompile(`\{[0-9A-Fa-f]{6}\}|~[A-Za-z]~`)
+	// bracketTags matches the tags hostnames are padded with, such as `[NGRP]` or `[RUS]`
+	bracketTags = regexp.MustCompile(`\[[^\[\]]*\]`)
+)
+
+// token is a normalised word and where it appears in the text it was taken from
+type token struct {
+	term       string
+	start, end int
+}
+
+// StripColours removes SA:MP colour codes from text
+func StripColours(text string) string {
+	return colourCodes.ReplaceAllString(text, "")
+}
+
+// tokenise splits text into lower case words of at least two letters or digits, text must already
+// have its colour codes stripped so the offsets can be used for highlighting. When tags is false the
+// contents of bracket tags are skipped.
+func tokenise(text string, tags bool) (tokens []token) {
+	if !tags {
+		// blanked rather than removed so the offsets still point into text
+		text = bracketTags.ReplaceAllStringFunc(text, func(tag string) string {
+			return strings.Repeat(" ", len(tag))
+		})
+	}
+
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := text[start:end]
+		if utf8.RuneCountInString(word) >= 2 {
+			tokens = append(tokens, token{term: strings.ToLower(word), start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return
+}
+
+// distance returns the Levenshtein distance between two terms, or max+1 once it's known to be
+// greater than max
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		lowest := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if current[j] < lowest {
+				lowest = current[j]
+			}
+		}
+		if lowest > max {
+			return max + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
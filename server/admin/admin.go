@@ -0,0 +1,150 @@
+// Package admin implements the routes for moderating the index and managing the API, every route
+// requires an admin key
+package admin
+
+import (
+	"github.com/Southclaws/samp-servers-api/scraper"
+	"github.com/Southclaws/samp-servers-api/search"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Admin represents an API endpoint handler
+type Admin struct {
+	Storage storage.Store
+	Scraper *scraper.Scraper
+	Search  *search.Index
+	Bans    *storage.BanList
+	Config  types.Config
+}
+
+// Init initialises and returns a handler group
+func Init(Storage storage.Store, Scraper *scraper.Scraper, Search *search.Index, Bans *storage.BanList, Config types.Config) *Admin {
+	return &Admin{
+		Storage: Storage,
+		Scraper: Scraper,
+		Search:  Search,
+		Bans:    Bans,
+		Config:  Config,
+	}
+}
+
+// Version returns the route group version name
+func (a *Admin) Version() string { return "admin" }
+
+// Routes returns the admin routes
+// nolint:lll
+func (a *Admin) Routes() []types.Route {
+	return []types.Route{
+		{
+			Name:        "serverStatusList",
+			Path:        "/servers",
+			Method:      "GET",
+			Description: "Returns every server in the index including archived servers, along with the number of failed queries in a row and whether the server has been moved to the less frequent rotation for failing servers. Failing servers come first.",
+			Accepts:     nil,
+			Returns:     []types.ServerStatus{types.ServerStatus{}.Example()},
+			Scope:       types.ScopeAdmin,
+			Handler:     a.serverStatusList,
+		},
+		{
+			Name:        "serverMeta",
+			Path:        "/server/{address}",
+			Method:      "PATCH",
+			Description: "Edits the description and banner of a server, fields left out of the body are not changed.",
+			Accepts:     types.ServerMeta{}.Example(),
+			Returns:     nil,
+			Scope:       types.ScopeAdmin,
+			Handler:     a.serverMeta,
+		},
+		{
+			Name:        "serverArchive",
+			Path:        "/server/{address}/archive",
+			Method:      "POST",
+			Description: "Archives a server immediately, hiding it from the list. It's moved to the rotation for failing servers and restored if it responds to a later query, ban the server to remove it permanently.",
+			Accepts:     nil,
+			Returns:     nil,
+			Scope:       types.ScopeAdmin,
+			Handler:     a.serverArchive,
+		},
+		{
+			Name:        "serverRemove",
+			Path:        "/server/{address}",
+			Method:      "DELETE",
+			Description: "Stops querying a server and deletes it from the index. Nothing stops it from being added again, ban the server to remove it permanently.",
+			Accepts:     nil,
+			Returns:     nil,
+			Scope:       types.ScopeAdmin,
+			Handler:     a.serverRemove,
+		},
+		{
+			Name:        "banList",
+			Path:        "/bans",
+			Method:      "GET",
+			Description: "Returns every ban.",
+			Accepts:     nil,
+			Returns:     []types.Ban{types.Ban{}.Example()},
+			Scope:       types.ScopeAdmin,
+			Handler:     a.banList,
+		},
+		{
+			Name:        "banCreate",
+			Path:        "/bans",
+			Method:      "POST",
+			Description: "Bans an `address`, a `cidr` range (a bare IP is treated as a single host), an exact `hostname` or a hostname `regex`. Every server the ban matches is removed from the index and returned in `removed`. Banned servers can't be added again and are rejected with a 403.",
+			Accepts:     types.BanParams{}.Example(),
+			Returns:     types.BanResult{}.Example(),
+			Scope:       types.ScopeAdmin,
+			Handler:     a.banCreate,
+		},
+		{
+			Name:        "banRemove",
+			Path:        "/bans/{id}",
+			Method:      "DELETE",
+			Description: "Lifts a ban. Servers removed by the ban are not restored but may be added again.",
+			Accepts:     nil,
+			Returns:     nil,
+			Scope:       types.ScopeAdmin,
+			Handler:     a.banRemove,
+		},
+		{
+			Name:        "keyList",
+			Path:        "/keys",
+			Method:      "GET",
+			Description: "Returns every API key, including the inactive keys of pending server claims. The keys themselves are never returned since only a hash of them is stored.",
+			Accepts:     nil,
+			Returns:     []types.APIKey{types.CreatedAPIKey{}.Example().APIKey},
+			Scope:       types.ScopeAdmin,
+			Handler:     a.keyList,
+		},
+		{
+			Name:        "keyCreate",
+			Path:        "/keys",
+			Method:      "POST",
+			Description: "Creates an active API key with the given scopes: `admin`, `read` or `server:write:<address>`. The response is the only time the key is visible.",
+			Accepts:     types.KeyCreateParams{}.Example(),
+			Returns:     types.CreatedAPIKey{}.Example(),
+			Scope:       types.ScopeAdmin,
+			Handler:     a.keyCreate,
+		},
+		{
+			Name:        "keyRevoke",
+			Path:        "/keys/{id}",
+			Method:      "DELETE",
+			Description: "Revokes an API key, requests using it are rejected immediately.",
+			Accepts:     nil,
+			Returns:     nil,
+			Scope:       types.ScopeAdmin,
+			Handler:     a.keyRevoke,
+		},
+		{
+			Name:        "clusterMembers",
+			Path:        "/cluster",
+			Method:      "GET",
+			Description: "Lists the API replicas sharing the scraping work and when their leases expire. Replicas whose leases have expired are listed until another replica clears them out. Empty unless clustering is enabled.",
+			Accepts:     nil,
+			Returns:     []types.Member{types.Member{}.Example()},
+			Scope:       types.ScopeAdmin,
+			Handler:     a.clusterMembers,
+		},
+	}
+}
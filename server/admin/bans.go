@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/server/v2"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// banList returns every ban
+func (a *Admin) banList(w http.ResponseWriter, r *http.Request) {
+	bans := a.Bans.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(bans)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// banCreate stores a ban and removes every server it matches from the index
+func (a *Admin) banCreate(w http.ResponseWriter, r *http.Request) {
+	params := types.BanParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		v2.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ban, errs := types.NewBan(params.Kind, params.Value, params.Reason)
+	if errs != nil {
+		v2.WriteErrors(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+
+	matches, err := ban.Matcher()
+	if err != nil {
+		v2.WriteError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	err = a.Bans.Add(ban)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to store ban"))
+		return
+	}
+
+	servers, err := a.Storage.GetAllServers()
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get servers"))
+		return
+	}
+
+	result := types.BanResult{Ban: ban, Removed: []string{}}
+	for _, server := range servers {
+		if !matches(server.Core.Address, server.IP, server.Core.Hostname) {
+			continue
+		}
+		err = a.removeServer(server.Core.Address)
+		if err != nil {
+			v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to remove banned server"))
+			return
+		}
+		result.Removed = append(result.Removed, server.Core.Address)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// banRemove lifts a ban, servers it removed are not restored but may be added again
+func (a *Admin) banRemove(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	found := false
+	for _, ban := range a.Bans.List() {
+		if ban.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		v2.WriteError(w, http.StatusNotFound, errors.Errorf("could not find ban '%s'", id))
+		return
+	}
+
+	err := a.Bans.Remove(id)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to remove ban"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/server/v2"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// clusterMembers returns the replicas holding a lease
+func (a *Admin) clusterMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := a.Storage.GetMembers()
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get members"))
+		return
+	}
+	if members == nil {
+		members = []types.Member{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(members)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/server/v2"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// keyList returns every stored key
+func (a *Admin) keyList(w http.ResponseWriter, r *http.Request) {
+	keys, err := a.Storage.GetAPIKeys()
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get keys"))
+		return
+	}
+	if keys == nil {
+		keys = []types.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(keys)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// keyCreate creates an active key with the requested scopes
+func (a *Admin) keyCreate(w http.ResponseWriter, r *http.Request) {
+	params := types.KeyCreateParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		v2.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	errs := params.Validate()
+	if errs != nil {
+		v2.WriteErrors(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+
+	plain, key, err := types.NewAPIKey(true, params.Scopes...)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = a.Storage.PutAPIKey(key)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to store key"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(types.CreatedAPIKey{APIKey: key, Key: plain})
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// keyRevoke deletes a key
+func (a *Admin) keyRevoke(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	err := a.Storage.RemoveAPIKey(id)
+	if errors.Cause(err) == storage.ErrNotFound {
+		v2.WriteError(w, http.StatusNotFound, errors.Errorf("could not find key '%s'", id))
+		return
+	} else if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to revoke key"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
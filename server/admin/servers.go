@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/server/v2"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// serverStatusList returns every server with its scraper state, failing servers first
+func (a *Admin) serverStatusList(w http.ResponseWriter, r *http.Request) {
+	servers, err := a.Storage.GetAllServers()
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get servers"))
+		return
+	}
+
+	statuses := make([]types.ServerStatus, 0, len(servers))
+	for _, server := range servers {
+		failures, failing := a.Scraper.Failures(server.Core.Address)
+		statuses = append(statuses, types.ServerStatus{
+			Core:     server.Core,
+			IP:       server.IP,
+			Active:   server.Active,
+			Failures: failures,
+			Failing:  failing,
+		})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Failing != statuses[j].Failing {
+			return statuses[i].Failing
+		}
+		return statuses[i].Failures > statuses[j].Failures
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(statuses)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// serverMeta edits the description and banner of a server
+func (a *Admin) serverMeta(w http.ResponseWriter, r *http.Request) {
+	address, ok := serverAddress(w, r)
+	if !ok {
+		return
+	}
+
+	meta := types.ServerMeta{}
+	err := json.NewDecoder(r.Body).Decode(&meta)
+	if err != nil {
+		v2.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	server, found, err := a.Storage.GetServer(address)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		v2.WriteError(w, http.StatusNotFound, errors.Errorf("could not find server by address '%s'", address))
+		return
+	}
+
+	if meta.Description != nil {
+		server.Description = *meta.Description
+	}
+	if meta.Banner != nil {
+		server.Banner = *meta.Banner
+	}
+
+	errs := server.Validate()
+	if errs != nil {
+		v2.WriteErrors(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+
+	err = a.Storage.UpsertServer(server)
+	if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to update server"))
+		return
+	}
+	a.Search.Update(server)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serverArchive hides a server from the list until it next responds to a query
+func (a *Admin) serverArchive(w http.ResponseWriter, r *http.Request) {
+	address, ok := serverAddress(w, r)
+	if !ok {
+		return
+	}
+
+	if !a.Scraper.Exists(address) {
+		v2.WriteError(w, http.StatusNotFound, errors.Errorf("address '%s' is not being queried", address))
+		return
+	}
+	a.Scraper.Archive(address)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serverRemove stops querying a server and deletes it from the index
+func (a *Admin) serverRemove(w http.ResponseWriter, r *http.Request) {
+	address, ok := serverAddress(w, r)
+	if !ok {
+		return
+	}
+
+	err := a.removeServer(address)
+	if errors.Cause(err) == storage.ErrNotFound {
+		v2.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		v2.WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to remove server"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeServer deletes a server and its history from storage then stops querying it, the address
+// is only dropped from the scraper once the delete succeeded so failures can be reported. Addresses
+// that are being queried but were never stored, such as pending ones, are only dropped.
+func (a *Admin) removeServer(address string) error {
+	err := a.Storage.RemoveServer(address)
+	if errors.Cause(err) == storage.ErrNotFound && a.Scraper.Exists(address) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	err = storage.RemoveHistory(a.Storage, storage.ServerSeries(address))
+	if err != nil {
+		return errors.Wrap(err, "failed to remove player history")
+	}
+
+	a.Scraper.Drop(address)
+	a.Search.Remove(address)
+
+	err = a.Storage.UpsertPlayers(address, nil, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to clear players")
+	}
+	return nil
+}
+
+func serverAddress(w http.ResponseWriter, r *http.Request) (address string, ok bool) {
+	address, ok = mux.Vars(r)["address"]
+	if !ok {
+		v2.WriteError(w, http.StatusBadRequest, errors.New("no address specified"))
+		return
+	}
+
+	_, errs := types.AddressFromString(address)
+	if errs != nil {
+		v2.WriteErrors(w, http.StatusBadRequest, errs)
+		return "", false
+	}
+	return address, true
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/Southclaws/samp-servers-api/server/v2"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// authorise wraps a route handler with API key authentication. A key may be passed with any request
+// and is made available to the handler via the request context, routes with a scope reject requests
+// without a key that grants it. Errors are written in the format of the route group.
+func (app *App) authorise(route types.Route, handler types.RouteHandler) http.Handler {
+	writeError := func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		v2.WriteError(w, status, err)
+	}
+	if writer, ok := handler.(types.ErrorWriter); ok {
+		writeError = writer.WriteError
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plain := types.KeyFromRequest(r)
+
+		key, ok, err := app.authenticate(plain)
+		if err != nil {
+			logger.Error("failed to authenticate request",
+				zap.Error(err),
+				zap.String("route", route.Name))
+			writeError(w, r, http.StatusInternalServerError, errors.New("failed to authenticate request"))
+			return
+		}
+		if plain != "" && !ok {
+			writeError(w, r, http.StatusUnauthorized, errors.New("invalid or unverified key"))
+			return
+		}
+
+		switch {
+		case route.Scope == "":
+		case route.Scope == types.ScopeRead && !app.config.RequireReadKey:
+		case route.Scope == types.ScopeServerOwner && !ok:
+			// owner routes may verify requests without a key by other means
+		case !ok:
+			writeError(w, r, http.StatusUnauthorized, errors.Errorf("a key with the '%s' scope is required", route.Scope))
+			return
+		case !key.Allows(route.Scope):
+			writeError(w, r, http.StatusForbidden, errors.Errorf("key does not grant the '%s' scope", route.Scope))
+			return
+		}
+
+		if ok {
+			r = r.WithContext(types.WithAPIKey(r.Context(), key))
+		}
+		route.Handler(w, r)
+	})
+}
+
+// authenticate looks up a key, the admin key from the config is not stored so it's checked first
+func (app *App) authenticate(plain string) (key types.APIKey, ok bool, err error) {
+	if plain == "" {
+		return
+	}
+	if app.config.AdminKey != "" && subtle.ConstantTimeCompare([]byte(plain), []byte(app.config.AdminKey)) == 1 {
+		return types.APIKey{
+			ID:     "config",
+			Scopes: []types.Scope{types.ScopeAdmin},
+			Active: true,
+		}, true, nil
+	}
+	return storage.Authenticate(app.db, plain)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// owns checks whether this replica is responsible for an address or a job that only one replica
+// should run, such as recording statistics. Everything is owned when the API runs alone.
+func (app *App) owns(key string) bool {
+	if app.cluster == nil {
+		return true
+	}
+	return app.cluster.Owns(key)
+}
+
+func (app *App) onMembershipChange() {
+	logger.Info("cluster membership changed",
+		zap.Strings("members", app.cluster.Members()))
+
+	select {
+	case app.changed <- struct{}{}:
+	default: // a sync is already due
+	}
+}
+
+func (app *App) onMembershipError(err error) {
+	logger.Error("failed to renew cluster lease",
+		zap.Error(err))
+}
+
+// Synchronise periodically reloads the addresses and bans from storage so this replica takes over
+// addresses added by others, stops querying ones they removed and enforces their bans. The search
+// index is rebuilt too since other replicas update the servers they query. It also runs straight
+// away whenever a replica joins or leaves.
+func (app *App) Synchronise() {
+	ticker := time.NewTicker(app.config.QueryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-app.changed:
+		}
+
+		err := app.bans.Reload()
+		if err != nil {
+			logger.Error("failed to reload bans",
+				zap.Error(err))
+		}
+
+		servers, err := app.db.GetAllServers()
+		if err != nil {
+			logger.Error("failed to load servers for search",
+				zap.Error(err))
+		} else {
+			app.search.Rebuild(servers)
+		}
+
+		addresses, err := app.db.LoadAllAddresses()
+		if err != nil {
+			logger.Error("failed to load addresses",
+				zap.Error(err))
+			continue
+		}
+		app.qd.Sync(addresses)
+	}
+}
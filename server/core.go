@@ -5,14 +5,21 @@ import (
 	"net/http"
 	"path"
 
-	"github.com/Southclaws/go-samp-query"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
+	"github.com/Southclaws/samp-servers-api/cluster"
+	"github.com/Southclaws/samp-servers-api/heuristics"
+	"github.com/Southclaws/samp-servers-api/probe"
 	"github.com/Southclaws/samp-servers-api/scraper"
+	"github.com/Southclaws/samp-servers-api/search"
+	"github.com/Southclaws/samp-servers-api/server/admin"
+	"github.com/Southclaws/samp-servers-api/server/legacy"
 	"github.com/Southclaws/samp-servers-api/server/v2"
+	"github.com/Southclaws/samp-servers-api/server/v3"
 	"github.com/Southclaws/samp-servers-api/storage"
 	"github.com/Southclaws/samp-servers-api/types"
 )
@@ -22,8 +29,14 @@ type App struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	config     types.Config
-	db         *storage.Manager
+	db         storage.Store
+	bans       *storage.BanList
+	cluster    *cluster.Membership // nil unless the scraping is shared with other replicas
+	changed    chan struct{}       // signalled when replicas join or leave
+	engine     *probe.Engine       // shared by every query the scraper makes
 	qd         *scraper.Scraper
+	heuristics *heuristics.Engine
+	search     *search.Index
 	handlers   map[string]types.RouteHandler
 	httpServer *http.Server
 	metrics    *metrics
@@ -34,36 +47,82 @@ func Initialise(config types.Config) (app *App, err error) {
 	logger.Debug("initialising samp-servers-api with debug logging", zap.Any("config", config))
 
 	app = &App{
-		config:  config,
-		metrics: newMetricsRecorder(),
+		config:     config,
+		heuristics: heuristics.New(heuristics.DefaultConfig),
+		search:     search.New(),
+		changed:    make(chan struct{}, 1),
+		metrics:    newMetricsRecorder(),
 	}
 	app.ctx, app.cancel = context.WithCancel(context.Background())
 
-	app.db, err = storage.New(storage.Config{
-		MongoHost:       config.MongoHost,
-		MongoPort:       config.MongoPort,
-		MongoName:       config.MongoName,
-		MongoUser:       config.MongoUser,
-		MongoPass:       config.MongoPass,
-		MongoCollection: config.MongoCollection,
-	})
+	if config.Cluster && (config.Storage == "memory" || config.Storage == "bolt") {
+		return nil, errors.Errorf("clustering requires a shared storage backend, '%s' is local to each replica", config.Storage)
+	}
+
+	app.db, err = openStorage(config)
+	if err != nil {
+		return
+	}
+
+	app.bans, err = storage.NewBanList(app.db)
 	if err != nil {
 		return
 	}
 
+	// The search index is kept current as servers are queried, it only needs loading once
+	servers, err := app.db.GetAllServers()
+	if err != nil {
+		return
+	}
+	app.search.Rebuild(servers)
+
+	if config.Cluster {
+		app.cluster, err = cluster.New(app.db, cluster.Config{
+			ID:       config.ClusterID,
+			Lease:    config.ClusterLease,
+			OnChange: app.onMembershipChange,
+			OnError:  app.onMembershipError,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to join cluster")
+		}
+		logger.Info("joined cluster",
+			zap.String("id", app.cluster.ID()),
+			zap.Strings("members", app.cluster.Members()))
+	}
+
 	// Grab existing addresses from database and pass to the Query Daemon
 	addresses, err := app.db.LoadAllAddresses()
 	if err != nil {
 		return
 	}
 
+	app.engine, err = probe.NewEngine(probe.EngineConfig{
+		Sockets:  config.QuerySockets,
+		Timeout:  config.QueryTimeout,
+		Rate:     config.QueryPacketRate,
+		HostRate: config.QueryHostRate,
+	})
+	if err != nil {
+		return
+	}
+
 	app.qd, err = scraper.New(
 		app.ctx,
 		addresses,
 		scraper.Config{
 			QueryInterval:    config.QueryInterval,
+			MinInterval:      config.QueryMinInterval,
+			MaxInterval:      config.QueryMaxInterval,
+			QueryBudget:      config.QueryBudget,
+			QueryTimeout:     config.QueryTimeout,
 			MaxFailed:        config.MaxFailedQuery,
-			QueryFunction:    sampquery.GetServerInfo,
+			Querier:          app.engine,
+			QueryPlayers:     config.QueryPlayers,
+			PlayersFunction:  app.engine.GetPlayers,
+			PingFunction:     app.engine.GetPing,
+			Banned:           app.bans.Check,
+			Owns:             app.owns,
 			OnRequestArchive: app.onRequestArchive,
 			OnRequestRemove:  app.onRequestRemove,
 			OnRequestUpdate:  app.onRequestUpdate,
@@ -72,14 +131,34 @@ func Initialise(config types.Config) (app *App, err error) {
 		return
 	}
 
-	if config.LegacyList {
-		// Start a periodic query against the SA:MP official internet list (if it's even online...)
-		go app.LegacyListQuery()
+	// Periodically record the index statistics, roll up history into coarser resolutions and look
+	// for groups of servers faking their player counts
+	go app.RecordStatistics()
+	go app.DownsampleHistory()
+	go app.UpdateHeuristics()
+	go app.RemoveExpiredClaims()
+
+	if app.cluster != nil {
+		// Keep the lease alive and pick up the addresses and bans added by other replicas
+		go app.cluster.Run(app.ctx)
+		go app.Synchronise()
+	}
+
+	// Periodically import the addresses from whichever masterlists are configured, or the SA:MP
+	// internet list (if it's even online...) when only the legacy list is enabled
+	sources := config.Masterlists
+	if len(sources) == 0 && config.LegacyList {
+		sources = types.DefaultMasterlists
+	}
+	if len(sources) > 0 {
+		app.ImportMasterlists(sources)
 	}
 
 	app.handlers = map[string]types.RouteHandler{
-		"v2": v2.Init(app.db, app.qd, config),
-		// "v3": v3.Init(app.db, app.qd, config),
+		"v2":    v2.Init(app.db, app.qd, app.search, config),
+		"admin": admin.Init(app.db, app.qd, app.search, app.bans, config),
+		"0.3.7": legacy.Init(app.db, app.qd, config),
+		"v3":    v3.Init(app.db, app.qd, config),
 	}
 
 	router := mux.NewRouter().StrictSlash(true)
@@ -95,12 +174,13 @@ func Initialise(config types.Config) (app *App, err error) {
 			router.Methods(route.Method).
 				Path(path.Join("/", name, route.Path)).
 				Name(route.Name).
-				Handler(route.Handler)
+				Handler(app.authorise(route, handler))
 
 			logger.Debug("registered handler route",
 				zap.String("name", route.Name),
 				zap.String("method", route.Method),
-				zap.String("path", path.Join(name, route.Path)))
+				zap.String("path", path.Join(name, route.Path)),
+				zap.String("scope", string(route.Scope)))
 		}
 
 		router.Methods("GET").
@@ -112,15 +192,47 @@ func Initialise(config types.Config) (app *App, err error) {
 	app.httpServer = &http.Server{
 		Addr: app.config.Bind,
 		Handler: handlers.CORS(
-			handlers.AllowedHeaders([]string{"X-Requested-With"}),
+			handlers.AllowedHeaders([]string{"X-Requested-With", "Authorization", "X-API-Key"}),
 			handlers.AllowedOrigins([]string{"*"}),
-			handlers.AllowedMethods([]string{"HEAD", "GET", "POST", "PUT", "OPTIONS"}),
+			handlers.AllowedMethods([]string{"HEAD", "GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
 		)(router),
 	}
 
 	return app, nil
 }
 
+// openStorage creates the storage backend selected by the `Storage` config value
+func openStorage(config types.Config) (db storage.Store, err error) {
+	switch config.Storage {
+	case "", "mongo":
+		return storage.New(storage.Config{
+			MongoHost:       config.MongoHost,
+			MongoPort:       config.MongoPort,
+			MongoName:       config.MongoName,
+			MongoUser:       config.MongoUser,
+			MongoPass:       config.MongoPass,
+			MongoCollection: config.MongoCollection,
+		})
+	case "postgres":
+		return storage.NewPostgres(storage.PostgresConfig{
+			PostgresHost:    config.PostgresHost,
+			PostgresPort:    config.PostgresPort,
+			PostgresName:    config.PostgresName,
+			PostgresUser:    config.PostgresUser,
+			PostgresPass:    config.PostgresPass,
+			PostgresSSLMode: config.PostgresSSLMode,
+		})
+	case "bolt":
+		return storage.NewBolt(storage.BoltConfig{
+			BoltPath: config.BoltPath,
+		})
+	case "memory":
+		return storage.NewMemory(), nil
+	default:
+		return nil, errors.Errorf("unknown storage backend '%s'", config.Storage)
+	}
+}
+
 // Start begins listening for requests and blocks until fatal error
 func (app *App) Start() error {
 	defer app.cancel()
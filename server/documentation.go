@@ -7,7 +7,6 @@ import (
 	"net/http"
 
 	"github.com/alecthomas/template"
-	"github.com/dyninc/qstring"
 
 	"github.com/Southclaws/samp-servers-api/types"
 )
@@ -29,7 +28,9 @@ This is an automatically generated documentation page for the %s API endpoints.
 const documentationRouteTemplate = `## {{ .Name }}
 
 ` + "`" + `{{ .Method }}` + "`" + `: ` + "`" + `/{{ .Version }}{{ .Path }}` + "`" + `
-
+{{ if .Scope }}
+Scope: ` + "`" + `{{ .Scope }}` + "`" + `
+{{ end }}
 {{ .Description }}
 {{ if .Params }}
 ### Query parameters
@@ -70,17 +71,7 @@ func docsForRoute(version string, route types.Route, w io.Writer) {
 	}
 
 	if route.Params != nil {
-		tmp := types.ServerListParams{}
-		err = qstring.Unmarshal(route.Params, &tmp)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-		obj.ParamsSerialised, err = qstring.MarshalString(&tmp)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+		obj.ParamsSerialised = route.Params.Encode()
 	}
 
 	if route.Accepts != nil {
@@ -1,9 +1,14 @@
 package server
 
 import (
-	"github.com/Southclaws/samp-servers-api/types"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+
+	"github.com/Southclaws/samp-servers-api/scraper"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
 )
 
 func (app *App) onRequestArchive(address string) {
@@ -18,6 +23,9 @@ func (app *App) onRequestArchive(address string) {
 		return
 	}
 
+	app.search.Remove(address)
+	app.clearPlayers(address)
+
 	app.updateIndexMetrics()
 }
 
@@ -33,20 +41,118 @@ func (app *App) onRequestRemove(address string) {
 		return
 	}
 
+	err = storage.RemoveHistory(app.db, storage.ServerSeries(address))
+	if err != nil {
+		logger.Error("failed to remove player history",
+			zap.Error(err),
+			zap.String("address", address))
+	}
+
+	app.search.Remove(address)
+	app.clearPlayers(address)
+
 	app.updateIndexMetrics()
 }
 
-func (app *App) onRequestUpdate(server types.Server) {
+func (app *App) onRequestUpdate(server types.Server, missed int, create bool) {
 	logger.Debug("updating server",
 		zap.String("address", server.Core.Address))
 
-	err := app.db.UpsertServer(server)
+	// the description and banner aren't part of the query response so keep the stored ones, the
+	// probe results are never replaced by an upsert
+	existing, found, err := app.db.GetStoredServer(server.Core.Address)
+	if err != nil {
+		logger.Error("failed to get existing server",
+			zap.Error(err),
+			zap.String("address", server.Core.Address))
+		return
+	}
+	if !found && !create {
+		// the server was removed while it was being queried, possibly by another replica
+		logger.Debug("not recreating removed server",
+			zap.String("address", server.Core.Address))
+		return
+	}
+	now := time.Now()
+	server.Core.FirstSeen = now
+	server.Core.Updated = now
+	server.Core.Uptime = 100
+	if found {
+		server.Description = existing.Description
+		server.Banner = existing.Banner
+		if server.Extra == nil && server.Platform == existing.Platform {
+			// the platform's extra queries failed this time so keep what they returned last time
+			server.Extra = existing.Extra
+		}
+		if !existing.Core.FirstSeen.IsZero() {
+			server.Core.FirstSeen = existing.Core.FirstSeen
+		}
+		if !existing.Core.Updated.IsZero() {
+			if !existing.Active {
+				// archived servers are only retried at the failing rotation's longer interval, which
+				// undercounts the misses, so count those of a normal interval since it last answered
+				if archived := scraper.Missed(now.Sub(existing.Core.Updated), app.config.QueryInterval); archived > missed {
+					missed = archived
+				}
+			}
+			server.Core.Uptime = scraper.Uptime(existing.Core.Uptime, missed)
+		}
+	}
+
+	history, err := app.db.GetSamples(storage.ServerSeries(server.Core.Address), types.ResolutionRaw, time.Now().Add(-app.heuristics.Window()), time.Now())
+	if err != nil {
+		logger.Error("failed to get player history for heuristics",
+			zap.Error(err),
+			zap.String("address", server.Core.Address))
+	}
+	server.Suspicious = app.heuristics.Check(server, history)
+	if len(server.Suspicious) > 0 {
+		logger.Debug("server flagged as suspicious",
+			zap.String("address", server.Core.Address),
+			zap.Any("reasons", server.Suspicious))
+	}
+
+	err = app.db.UpsertServer(server)
 	if err != nil {
 		logger.Error("failed to upsert server",
 			zap.Error(err),
 			zap.String("address", server.Core.Address))
 		return
 	}
+	app.search.Update(server)
+
+	err = storage.RecordSample(app.db, storage.ServerSeries(server.Core.Address), time.Now(), float64(server.Core.Players))
+	if err != nil {
+		logger.Error("failed to record player history",
+			zap.Error(err),
+			zap.String("address", server.Core.Address))
+	}
+
+	if server.PlayerList != nil {
+		if server.PlayerList.Available {
+			err = app.db.UpsertPlayers(server.Core.Address, server.PlayerList.Names(), time.Now())
+			if err != nil {
+				logger.Error("failed to index players",
+					zap.Error(err),
+					zap.String("address", server.Core.Address))
+			}
+		} else {
+			// without a list there's no telling who is still online so nobody is
+			app.clearPlayers(server.Core.Address)
+		}
+	}
+
+	verified, err := storage.VerifyClaims(app.db, server.Core.Address, server.Rules, time.Now())
+	if err != nil {
+		logger.Error("failed to verify claims",
+			zap.Error(err),
+			zap.String("address", server.Core.Address))
+	}
+	for _, claim := range verified {
+		logger.Info("verified server ownership claim",
+			zap.String("address", claim.Address),
+			zap.String("key", claim.KeyID))
+	}
 
 	app.metrics.Players.With(
 		prometheus.Labels{"addr": server.Core.Address},
@@ -55,6 +161,17 @@ func (app *App) onRequestUpdate(server types.Server) {
 	app.updateIndexMetrics()
 }
 
+// clearPlayers marks everyone on a server as no longer online there, for servers that have gone
+// offline or whose player list couldn't be collected
+func (app *App) clearPlayers(address string) {
+	err := app.db.UpsertPlayers(address, nil, time.Now())
+	if err != nil {
+		logger.Error("failed to clear players",
+			zap.Error(err),
+			zap.String("address", address))
+	}
+}
+
 func (app *App) updateIndexMetrics() {
 	c, err := app.db.GetActiveServers()
 	if err != nil {
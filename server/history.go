@@ -0,0 +1,108 @@
+package server
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Southclaws/samp-servers-api/storage"
+)
+
+// DownsampleHistory periodically rolls up old time series samples into coarser resolutions.
+func (app *App) DownsampleHistory() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+			if !app.owns("job:downsample") {
+				continue
+			}
+			err := storage.Downsample(app.db, time.Now())
+			if err != nil {
+				logger.Error("failed to downsample history",
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// RecordStatistics periodically stores a snapshot of the index statistics for the history endpoint.
+func (app *App) RecordStatistics() {
+	ticker := time.NewTicker(app.config.QueryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+			if !app.owns("job:statistics") {
+				continue
+			}
+			stats, err := storage.GetStatistics(app.db)
+			if err != nil {
+				logger.Error("failed to get statistics",
+					zap.Error(err))
+				continue
+			}
+			err = storage.RecordStatistics(app.db, stats, time.Now())
+			if err != nil {
+				logger.Error("failed to record statistics",
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// RemoveExpiredClaims periodically removes the ownership claims that were never verified.
+func (app *App) RemoveExpiredClaims() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+			if !app.owns("job:claims") {
+				continue
+			}
+			removed, err := storage.RemoveExpiredClaims(app.db, time.Now())
+			if err != nil {
+				logger.Error("failed to remove expired claims",
+					zap.Error(err))
+				continue
+			}
+			if removed > 0 {
+				logger.Debug("removed expired claims",
+					zap.Int("claims", removed))
+			}
+		}
+	}
+}
+
+// UpdateHeuristics periodically runs the heuristics that need to compare servers against each other,
+// the results are applied to each server the next time it's queried.
+func (app *App) UpdateHeuristics() {
+	ticker := time.NewTicker(app.config.QueryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+			servers, err := app.db.GetAllServers()
+			if err != nil {
+				logger.Error("failed to get servers for heuristics",
+					zap.Error(err))
+				continue
+			}
+			app.heuristics.UpdateShared(servers)
+		}
+	}
+}
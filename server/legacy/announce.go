@@ -0,0 +1,39 @@
+package legacy
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// announce handles a server registering itself, the address is the IP the request came from and
+// the port the server is listening on.
+func (l *Legacy) announce(w http.ResponseWriter, r *http.Request) {
+	ip := l.Config.TrustedProxies.RemoteIP(r)
+	if net.ParseIP(ip).To4() == nil {
+		WriteError(w, http.StatusBadRequest, errors.Errorf("'%s' is not an IPv4 address", ip))
+		return
+	}
+
+	address, errs := types.AddressFromString(net.JoinHostPort(ip, mux.Vars(r)["port"]))
+	if errs != nil {
+		WriteError(w, http.StatusBadRequest, errs[0])
+		return
+	}
+
+	if err := l.Scraper.Announce(r.Context(), address); err != nil {
+		if _, banned := errors.Cause(err).(types.BannedError); banned {
+			WriteError(w, http.StatusForbidden, err)
+			return
+		}
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,71 @@
+// Package legacy serves the index in the formats of the official SA:MP masterlist so clients and
+// launchers can use the API in its place.
+package legacy
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/Southclaws/samp-servers-api/scraper"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Legacy represents an API endpoint handler
+type Legacy struct {
+	Storage storage.Store
+	Scraper *scraper.Scraper
+	Config  types.Config
+	lists   *listCache
+}
+
+// Init initialises and returns a handler group
+func Init(Storage storage.Store, Scraper *scraper.Scraper, Config types.Config) *Legacy {
+	return &Legacy{
+		Storage: Storage,
+		Scraper: Scraper,
+		Config:  Config,
+		lists:   newListCache(),
+	}
+}
+
+// Version returns the route group version name, the routes are at the same paths as the SA:MP
+// masterlist which is versioned by the client release
+func (l *Legacy) Version() string { return "0.3.7" }
+
+// Routes returns the version routes, they don't have a scope since the SA:MP client can't send a key
+// nolint:lll
+func (l *Legacy) Routes() []types.Route {
+	return []types.Route{
+		{
+			Name:        "masterlistServers",
+			Path:        "/servers",
+			Method:      "GET",
+			Description: "Returns the address of every active server as plain text, one per line, in the same format as the SA:MP masterlist used by the client's internet tab. The `filters` query parameter accepts the same filters as `/v2/servers`. Lists are cached for 10 seconds.",
+			Params:      url.Values{"filters": []string{string(types.FilterPassword), string(types.FilterPlatform(types.PlatformSAMP))}},
+			Handler:     l.servers,
+		},
+		{
+			Name:        "masterlistHosted",
+			Path:        "/hosted",
+			Method:      "GET",
+			Description: "Returns the addresses of the active servers whose owners have verified them, for the client's hosted tab, in the same format and with the same filters as `masterlistServers`.",
+			Params:      url.Values{"filters": []string{string(types.FilterEmpty)}},
+			Handler:     l.hosted,
+		},
+		{
+			Name:        "masterlistAnnounce",
+			Path:        "/announce/{port}",
+			Method:      "GET",
+			Description: "Called by SA:MP servers with `announce 1` on startup to register themselves with the masterlist. The server is queried at the address the request came from and the port in the path, once it responds it's added to the index.",
+			Handler:     l.announce,
+		},
+	}
+}
+
+// WriteError writes an error as plain text, like the rest of the responses of this group
+func WriteError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(err.Error())) // nolint:errcheck
+}
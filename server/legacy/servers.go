@@ -0,0 +1,214 @@
+package legacy
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dyninc/qstring"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// listParams are the query parameters of the masterlist routes
+type listParams struct {
+	Filters []types.FilterAttribute
+}
+
+// listLifetime is how long a rendered list is served before it's generated again, clients fetch the
+// whole list every time the internet tab is opened so it isn't read from storage for each of them
+const listLifetime = 10 * time.Second
+
+// maxCachedLists is the most combinations of filters that are cached at once
+const maxCachedLists = 64
+
+func (l *Legacy) servers(w http.ResponseWriter, r *http.Request) {
+	l.writeList(w, r, "servers", nil)
+}
+
+func (l *Legacy) hosted(w http.ResponseWriter, r *http.Request) {
+	l.writeList(w, r, "hosted", l.verified)
+}
+
+// writeList writes the addresses of the active servers that match the filters, if only isn't nil
+// the servers must also be in the set it returns
+func (l *Legacy) writeList(w http.ResponseWriter, r *http.Request, name string, only func() (map[string]bool, error)) {
+	var params listParams
+	err := qstring.Unmarshal(r.URL.Query(), &params)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, errors.Wrap(err, "invalid parameters"))
+		return
+	}
+
+	if _, err = types.FilterConditions(params.Filters); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err = types.FilterPlatforms(params.Filters); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key := name + "?" + strings.Join(filterStrings(params.Filters), "&")
+	now := time.Now()
+	body, ok := l.lists.get(key, now)
+	if !ok {
+		body, err = l.render(params.Filters, only)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		l.lists.put(key, body, now)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(body) // nolint:errcheck
+}
+
+// render generates a list of addresses, one per line
+func (l *Legacy) render(filters []types.FilterAttribute, only func() (map[string]bool, error)) ([]byte, error) {
+	addresses, err := l.addresses(filters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get servers")
+	}
+	var include map[string]bool
+	if only != nil {
+		if include, err = only(); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	for _, address := range addresses {
+		if include != nil && !include[address] {
+			continue
+		}
+		out.WriteString(address)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// addresses reads the whole listing, most players first. It's read a page at a time after a cursor
+// ordered by when each server was first seen, which doesn't change when a server is queried, so
+// servers aren't skipped while it's read. A server that's removed and added again between pages
+// would come back with a new first seen time so the addresses are deduplicated, then sorted by
+// players once the whole list has been read.
+func (l *Legacy) addresses(filters []types.FilterAttribute) (addresses []string, err error) {
+	query := types.ServerQuery{
+		Limit:   int(types.PageSizeDefault),
+		Sort:    types.SortAsc,
+		By:      types.ByFirstSeen,
+		Filters: filters,
+	}
+	seen := map[string]bool{}
+	listed := []types.ServerCore{}
+	for {
+		servers, _, errPage := l.Storage.ListServers(query)
+		if errPage != nil {
+			return nil, errPage
+		}
+		for _, server := range servers {
+			if !seen[server.Address] {
+				seen[server.Address] = true
+				listed = append(listed, server)
+			}
+		}
+		if len(servers) < query.Limit {
+			break
+		}
+		last := servers[len(servers)-1]
+		query.After = &types.Cursor{Key: types.SortValue(last, query.By, false), Address: last.Address}
+	}
+
+	sort.Slice(listed, func(i, j int) bool {
+		if listed[i].Players != listed[j].Players {
+			return listed[i].Players > listed[j].Players
+		}
+		return listed[i].Address < listed[j].Address
+	})
+	for _, server := range listed {
+		addresses = append(addresses, server.Address)
+	}
+	return
+}
+
+// verified returns the addresses of the servers that have been claimed by their owners
+func (l *Legacy) verified() (map[string]bool, error) {
+	keys, err := l.Storage.GetAPIKeys()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get keys")
+	}
+	return verified(keys), nil
+}
+
+// verified returns the addresses that an active key grants write access to, these have been claimed
+// by their owners
+func verified(keys []types.APIKey) map[string]bool {
+	addresses := make(map[string]bool)
+	for _, key := range keys {
+		if !key.Active {
+			continue
+		}
+		for _, scope := range key.Scopes {
+			if strings.HasPrefix(string(scope), types.ScopeServerWritePrefix) {
+				addresses[strings.TrimPrefix(string(scope), types.ScopeServerWritePrefix)] = true
+			}
+		}
+	}
+	return addresses
+}
+
+func filterStrings(filters []types.FilterAttribute) []string {
+	result := make([]string, len(filters))
+	for i, filter := range filters {
+		result[i] = string(filter)
+	}
+	return result
+}
+
+// listCache holds rendered lists for a short time, keyed by the route and filters
+type listCache struct {
+	lock  sync.Mutex
+	lists map[string]cachedList
+}
+
+type cachedList struct {
+	body    []byte
+	expires time.Time
+}
+
+func newListCache() *listCache {
+	return &listCache{lists: make(map[string]cachedList)}
+}
+
+func (c *listCache) get(key string, now time.Time) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	list, ok := c.lists[key]
+	if !ok || !now.Before(list.expires) {
+		return nil, false
+	}
+	return list.body, true
+}
+
+// put stores a list, expired lists are dropped first and if there's still no room it isn't cached
+func (c *listCache) put(key string, body []byte, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for k, list := range c.lists {
+		if !now.Before(list.expires) {
+			delete(c.lists, k)
+		}
+	}
+	if len(c.lists) >= maxCachedLists {
+		return
+	}
+	c.lists[key] = cachedList{body: body, expires: now.Add(listLifetime)}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Southclaws/samp-servers-api/masterlist"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// masterlistTimeout is the longest a masterlist can take to download
+const masterlistTimeout = time.Minute
+
+// ImportMasterlists periodically imports the addresses from each masterlist source, every source
+// runs on its own interval so a slow or broken one doesn't hold up the others.
+func (app *App) ImportMasterlists(sources []types.MasterlistSource) {
+	client := &http.Client{Timeout: masterlistTimeout}
+	for _, source := range sources {
+		go app.importMasterlist(client, source)
+	}
+}
+
+func (app *App) importMasterlist(client *http.Client, source types.MasterlistSource) {
+	ticker := time.NewTicker(source.Interval)
+	defer ticker.Stop()
+
+	for {
+		app.getMasterlist(client, source)
+
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *App) getMasterlist(client *http.Client, source types.MasterlistSource) {
+	if !app.owns("job:masterlist:" + source.Name) {
+		return // another replica fetches the list and this one picks up the addresses when syncing
+	}
+
+	list, err := masterlist.Fetch(app.ctx, client, source)
+	app.metrics.MasterlistInvalid.WithLabelValues(source.Name).Add(float64(len(list.Invalid)))
+	if len(list.Invalid) > 0 {
+		logger.Debug("skipped invalid entries in masterlist",
+			zap.String("source", source.Name),
+			zap.Int("invalid", len(list.Invalid)),
+			zap.Error(list.Invalid[0]))
+	}
+	if err != nil {
+		app.metrics.MasterlistFailures.WithLabelValues(source.Name).Inc()
+		logger.Error("failed to import masterlist",
+			zap.String("source", source.Name),
+			zap.String("url", source.URL),
+			zap.Error(err))
+		return
+	}
+	app.metrics.MasterlistSize.WithLabelValues(source.Name).Set(float64(len(list.Addresses)))
+
+	count := 0
+	for _, address := range list.Addresses {
+		if errAdd := app.qd.Add(address); errAdd != nil {
+			logger.Debug("skipping server from masterlist",
+				zap.String("source", source.Name),
+				zap.String("address", address),
+				zap.Error(errAdd))
+			continue
+		}
+		count++
+	}
+	app.metrics.MasterlistImported.WithLabelValues(source.Name).Add(float64(count))
+
+	logger.Debug("added servers from masterlist",
+		zap.String("source", source.Name),
+		zap.Int("servers", count))
+}
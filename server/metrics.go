@@ -7,6 +7,11 @@ type metrics struct {
 	Active   prometheus.Gauge
 	Inactive prometheus.Gauge
 	Players  *prometheus.GaugeVec
+
+	MasterlistSize     *prometheus.GaugeVec
+	MasterlistImported *prometheus.CounterVec
+	MasterlistInvalid  *prometheus.CounterVec
+	MasterlistFailures *prometheus.CounterVec
 }
 
 // newMetricsRecorder initialises a new metrics recorder
@@ -30,11 +35,39 @@ func newMetricsRecorder() (m *metrics) {
 			Name:      "players",
 			Help:      "Total players across all servers",
 		}, []string{"addr"}),
+		MasterlistSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "samplist",
+			Subsystem: "masterlist",
+			Name:      "size",
+			Help:      "Valid addresses in the last fetch of each masterlist.",
+		}, []string{"source"}),
+		MasterlistImported: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "masterlist",
+			Name:      "imported",
+			Help:      "Addresses added to the index from each masterlist.",
+		}, []string{"source"}),
+		MasterlistInvalid: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "masterlist",
+			Name:      "invalid",
+			Help:      "Entries of each masterlist that weren't valid addresses.",
+		}, []string{"source"}),
+		MasterlistFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "samplist",
+			Subsystem: "masterlist",
+			Name:      "failures",
+			Help:      "Fetches of each masterlist that failed or were rejected.",
+		}, []string{"source"}),
 	}
 	prometheus.MustRegister(
 		m.Active,
 		m.Inactive,
 		m.Players,
+		m.MasterlistSize,
+		m.MasterlistImported,
+		m.MasterlistInvalid,
+		m.MasterlistFailures,
 	)
 	return m
 }
@@ -0,0 +1,47 @@
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// serverClaim issues a verification token and an inactive key scoped to a server
+func (v *V2) serverClaim(w http.ResponseWriter, r *http.Request) {
+	address, ok := mux.Vars(r)["address"]
+	if !ok {
+		WriteError(w, http.StatusBadRequest, errors.New("no address specified"))
+		return
+	}
+
+	normalised, errs := types.AddressFromString(address)
+	if errs != nil {
+		WriteErrors(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	response, err := storage.CreateClaim(v.Storage, normalised, time.Now())
+	switch errors.Cause(err) {
+	case nil:
+	case storage.ErrNotFound:
+		WriteError(w, http.StatusNotFound, errors.Errorf("could not find server by address '%s'", normalised))
+		return
+	default:
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to create claim"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(&response)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
@@ -0,0 +1,70 @@
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dyninc/qstring"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// serverHistory returns the player count history of a server over a range of time
+func (v *V2) serverHistory(w http.ResponseWriter, r *http.Request) {
+	address, ok := mux.Vars(r)["address"]
+	if !ok {
+		WriteError(w, http.StatusBadRequest, errors.New("no address specified"))
+		return
+	}
+
+	_, errs := types.AddressFromString(address)
+	if errs != nil {
+		WriteErrors(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	params, err := historyParams(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	samples, err := storage.GetHistory(v.Storage, storage.ServerSeries(address), params.From, params.To, params.Resolution)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get history"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(samples)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// historyParams decodes the range and resolution of a history request, the range defaults to the
+// last 24 hours.
+func historyParams(r *http.Request) (params types.HistoryParams, err error) {
+	err = qstring.Unmarshal(r.URL.Query(), &params)
+	if err != nil {
+		return params, errors.Wrap(err, "invalid parameters")
+	}
+
+	if params.To.IsZero() {
+		params.To = time.Now()
+	}
+	if params.From.IsZero() {
+		params.From = params.To.Add(-24 * time.Hour)
+	}
+
+	if !params.From.Before(params.To) {
+		return params, errors.New("'from' must be before 'to'")
+	}
+
+	return params, params.Resolution.Validate()
+}
@@ -0,0 +1,78 @@
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// serverPlayers returns the most recently scraped player list of a server
+func (v *V2) serverPlayers(w http.ResponseWriter, r *http.Request) {
+	address, ok := mux.Vars(r)["address"]
+	if !ok {
+		WriteError(w, http.StatusBadRequest, errors.New("no address specified"))
+		return
+	}
+
+	_, errs := types.AddressFromString(address)
+	if errs != nil {
+		WriteErrors(w, http.StatusBadRequest, errs)
+		return
+	}
+
+	server, found, err := v.Storage.GetServer(address)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !found {
+		WriteError(w, http.StatusNotFound, errors.Errorf("could not find server by address '%s'", address))
+		return
+	}
+	v.Scraper.Viewed(address)
+
+	list := types.PlayerList{Players: []types.Player{}}
+	if server.PlayerList != nil {
+		list = *server.PlayerList
+		if list.Players == nil {
+			list.Players = []types.Player{}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&list)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// playerSearch returns the servers a player name is currently on or has been seen on
+func (v *V2) playerSearch(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if name == "" {
+		WriteError(w, http.StatusBadRequest, errors.New("no name specified"))
+		return
+	}
+
+	sightings, err := v.Storage.SearchPlayers(name)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to search players"))
+		return
+	}
+	if sightings == nil {
+		sightings = []types.PlayerSighting{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(sightings)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
@@ -0,0 +1,102 @@
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// probeAssignments lists the addresses a probe agent should query, every agent is given every active
+// server so each region has a complete picture.
+func (v *V2) probeAssignments(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	if err := types.ValidateRegion(region); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !allowsRegion(w, r, region) {
+		return
+	}
+
+	servers, err := v.Storage.GetAllServers()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	assignments := types.ProbeAssignments{
+		Region:    region,
+		Addresses: []string{},
+	}
+	for _, server := range servers {
+		if server.Active {
+			assignments.Addresses = append(assignments.Addresses, server.Core.Address)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&assignments)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// probeResults stores the measurements of a probe agent against each server
+func (v *V2) probeResults(w http.ResponseWriter, r *http.Request) {
+	var report types.ProbeReport
+	err := json.NewDecoder(r.Body).Decode(&report)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, errors.Wrap(err, "failed to decode probe report"))
+		return
+	}
+
+	if errs := report.Validate(); errs != nil {
+		WriteErrors(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+	if !allowsRegion(w, r, report.Region) {
+		return
+	}
+
+	result := types.ProbeReportResult{}
+	now := time.Now().UTC()
+	for _, probed := range report.Results {
+		found, err := v.Storage.PutRegionStatus(probed.Address, report.Region, types.RegionStatus{
+			Reachable: probed.Reachable,
+			Ping:      probed.Ping,
+			Updated:   now,
+		})
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if found {
+			result.Accepted++
+		} else {
+			result.Ignored = append(result.Ignored, probed.Address)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&result)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
+
+// allowsRegion checks the key of a request grants probing from a region so an agent can't report on
+// behalf of another region
+func allowsRegion(w http.ResponseWriter, r *http.Request, region string) bool {
+	key, _ := types.APIKeyFromContext(r.Context())
+	if !key.Allows(types.ScopeProbeRegion(region)) {
+		WriteError(w, http.StatusForbidden, errors.Errorf("key does not grant probing from region '%s'", region))
+		return false
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -26,19 +27,19 @@ func (v *V2) serverAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	v.Scraper.Add(normalised)
+	err := v.Scraper.Add(normalised)
+	if err != nil {
+		WriteError(w, http.StatusForbidden, err)
+		return
+	}
 
 	w.Header().Set("Location", "https://samp-servers.net/")
 	w.WriteHeader(http.StatusFound)
 }
 
-// serverPost handles posting a server object
+// serverPost handles posting a server object, the request must carry a key scoped to the server or,
+// failing that, come from the server's own IP when VerifyByHost is enabled.
 func (v *V2) serverPost(w http.ResponseWriter, r *http.Request) {
-	var from string
-	if from = r.Header.Get("X-Forwarded-For"); from == "" {
-		from = strings.Split(r.RemoteAddr, ":")[0]
-	}
-
 	server := types.Server{}
 	err := json.NewDecoder(r.Body).Decode(&server)
 	if err != nil {
@@ -46,29 +47,80 @@ func (v *V2) serverPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if v.Config.VerifyByHost {
-		addressIP := strings.Split(server.Core.Address, ":")[0]
+	normalised, errs := types.AddressFromString(server.Core.Address)
+	if errs != nil {
+		WriteErrors(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+	server.Core.Address = normalised
+
+	if key, ok := types.APIKeyFromContext(r.Context()); ok {
+		if !key.Allows(types.ScopeServerWrite(normalised)) {
+			WriteError(w, http.StatusForbidden, errors.Errorf("key does not grant access to server '%s'", normalised))
+			return
+		}
+	} else if v.Config.VerifyByHost {
+		from := v.Config.TrustedProxies.RemoteIP(r)
+		addressIP := strings.Split(normalised, ":")[0]
 		if from != addressIP {
 			WriteError(w, http.StatusBadRequest,
 				errors.Errorf("request address '%v' does not match declared server address '%s'", from, addressIP))
 			return
 		}
+	} else {
+		WriteError(w, http.StatusUnauthorized, errors.New("a key scoped to the server is required"))
+		return
 	}
 
-	errs := server.Validate()
+	errs = server.Validate()
 	if errs != nil {
 		WriteErrors(w, http.StatusUnprocessableEntity, errs)
 		return
 	}
 
+	err = v.Scraper.Check(server.Core.Address, "", server.Core.Hostname)
+	if err != nil {
+		WriteError(w, http.StatusForbidden, err)
+		return
+	}
+
+	err = v.Scraper.Add(server.Core.Address)
+	if err != nil {
+		WriteError(w, http.StatusForbidden, err)
+		return
+	}
+
+	// latency, uptime, heuristics flags, probe results and the platform are measured by the API so
+	// they can't be set by the server owner
+	existing, found, err := v.Storage.GetServer(server.Core.Address)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	server.Core.Ping = 0
+	server.Core.FirstSeen = time.Time{}
+	server.Core.Updated = time.Time{}
+	server.Core.Uptime = 0
+	server.Suspicious = nil
+	server.Platform = ""
+	server.Extra = nil
+	if found {
+		server.Core.Ping = existing.Core.Ping
+		server.Core.FirstSeen = existing.Core.FirstSeen
+		server.Core.Updated = existing.Core.Updated
+		server.Core.Uptime = existing.Core.Uptime
+		server.Suspicious = existing.Suspicious
+		server.Platform = existing.Platform
+		server.Extra = existing.Extra
+	}
 	server.Active = true
 
 	err = v.Storage.UpsertServer(server)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, err)
+		return
 	}
-
-	v.Scraper.Add(server.Core.Address)
+	v.Search.Update(server)
 }
 
 // serverGet handles responding to a request by server address
@@ -96,6 +148,7 @@ func (v *V2) serverGet(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusNotFound, errors.Errorf("could not find server by address '%s'", address))
 		return
 	}
+	v.Scraper.Viewed(address)
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(&server)
@@ -3,10 +3,13 @@ package v2
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/dyninc/qstring"
 	"github.com/pkg/errors"
 
+	"github.com/Southclaws/samp-servers-api/search"
 	"github.com/Southclaws/samp-servers-api/types"
 )
 
@@ -19,6 +22,15 @@ func (v *V2) serverList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err = types.FilterConditions(params.Filters); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err = types.FilterPlatforms(params.Filters); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	servers, err := v.Storage.GetServers(params.Page, params.PageSize, params.Sort, params.By, params.Filters)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get servers"))
@@ -32,3 +44,38 @@ func (v *V2) serverList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// serverSearch returns the servers that best match a text query
+func (v *V2) serverSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.FormValue("q")
+	if strings.TrimSpace(q) == "" {
+		WriteError(w, http.StatusBadRequest, errors.New("no query specified"))
+		return
+	}
+
+	limit := search.DefaultLimit
+	if raw := r.FormValue("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > search.MaxLimit {
+			WriteError(w, http.StatusBadRequest, errors.Errorf("limit must be between 1 and %d", search.MaxLimit))
+			return
+		}
+	}
+
+	results, err := v.Search.Search(q, limit)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if results == nil {
+		results = []types.SearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(results)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+}
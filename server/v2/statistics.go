@@ -6,31 +6,41 @@ import (
 
 	"github.com/pkg/errors"
 
-	"github.com/Southclaws/samp-servers-api/types"
+	"github.com/Southclaws/samp-servers-api/storage"
 )
 
 // serverStats returns a set of statistics about the indexed servers
 func (v *V2) serverStats(w http.ResponseWriter, r *http.Request) {
-	var (
-		stats types.Statistics
-		err   error
-	)
+	stats, err := storage.GetStatistics(v.Storage)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	stats.Servers, err = v.Storage.GetActiveServers()
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(stats)
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get servers"))
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
 	}
-	stats.Players, err = v.Storage.GetTotalPlayers()
+}
+
+// serverStatsHistory returns the history of the index statistics over a range of time
+func (v *V2) serverStatsHistory(w http.ResponseWriter, r *http.Request) {
+	params, err := historyParams(r)
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get servers"))
+		WriteError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	if stats.Servers > 0 {
-		stats.PlayersPerServer = float32(stats.Players / stats.Servers)
+	samples, err := storage.GetStatisticsHistory(v.Storage, params.From, params.To, params.Resolution)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to get history"))
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(stats)
+	err = json.NewEncoder(w).Encode(samples)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
 		return
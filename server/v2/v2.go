@@ -3,24 +3,29 @@ package v2
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/Southclaws/samp-servers-api/scraper"
+	"github.com/Southclaws/samp-servers-api/search"
 	"github.com/Southclaws/samp-servers-api/storage"
 	"github.com/Southclaws/samp-servers-api/types"
 )
 
 // V2 represents an API endpoint handler
 type V2 struct {
-	Storage *storage.Manager
+	Storage storage.Store
 	Scraper *scraper.Scraper
+	Search  *search.Index
 	Config  types.Config
 }
 
 // Init initialises and returns a handler group
-func Init(Storage *storage.Manager, Scraper *scraper.Scraper, Config types.Config) *V2 {
+func Init(Storage storage.Store, Scraper *scraper.Scraper, Search *search.Index, Config types.Config) *V2 {
 	return &V2{
 		Storage: Storage,
 		Scraper: Scraper,
+		Search:  Search,
 		Config:  Config,
 	}
 }
@@ -36,20 +41,32 @@ func (v *V2) Routes() []types.Route {
 			Name:        "serverAdd",
 			Path:        "/server",
 			Method:      "POST",
-			Description: `Add a server to the index using just the IP address. The address is specified via the form body. The address is added to an internal queue and will be queried periodically for information via the legacy server API. This allows any server to be added with the basic information provided by SA:MP itself.`,
+			Description: `Add a server to the index using just the IP address. The address is specified via the form body. The address is added to an internal queue and will be queried periodically for information via the legacy server API. This allows any server to be added with the basic information provided by SA:MP itself. Requests must be authenticated with a key that has the ` + "`write`" + ` scope, servers can add themselves without a key through the legacy announce endpoint.`,
 			Accepts:     nil,
 			Returns:     nil,
+			Scope:       types.ScopeWrite,
 			Handler:     v.serverAdd,
 		},
 		{
 			Name:        "serverPost",
 			Path:        "/server",
 			Method:      "PATCH",
-			Description: `Provide additional information for a server such as a description and a banner image. This requires a body to be posted which contains information for the server.`,
+			Description: "Provide additional information for a server such as a description and a banner image. This requires a body to be posted which contains information for the server. Requests must be authenticated with a key scoped to the server, such as one obtained via `serverClaim`, in the `Authorization: Bearer` header unless the API verifies requests by their source address.",
 			Accepts:     types.Server{}.Example(),
 			Returns:     nil,
+			Scope:       types.ScopeServerOwner,
 			Handler:     v.serverPost,
 		},
+		{
+			Name:        "serverClaim",
+			Path:        "/server/{address}/claim",
+			Method:      "POST",
+			Description: "Starts verifying ownership of a server. The response contains a token and an API key, set the token as the value of any server rule (such as `weburl`) and once the server is next queried the key is activated. The key can then be passed to `serverPost` via the `Authorization: Bearer` header and is only shown once. Claims expire after 24 hours and a server can only have 5 pending claims at once.",
+			Accepts:     nil,
+			Returns:     types.ClaimResponse{}.Example(),
+			Scope:       types.ScopeRead,
+			Handler:     v.serverClaim,
+		},
 		{
 			Name:        "serverGet",
 			Path:        "/server/{address}",
@@ -57,18 +74,63 @@ func (v *V2) Routes() []types.Route {
 			Description: `Returns a full server object using the specified address.`,
 			Accepts:     nil,
 			Returns:     types.Server{}.Example(),
+			Scope:       types.ScopeRead,
 			Handler:     v.serverGet,
 		},
+		{
+			Name:        "serverPlayers",
+			Path:        "/server/{address}/players",
+			Method:      "GET",
+			Description: "Returns the player list of a server from the most recent query. Player lists are only collected when enabled on the API and SA:MP refuses to send them for servers with more than 100 players, in which case `available` is false.",
+			Accepts:     nil,
+			Returns:     types.PlayerList{}.Example(),
+			Scope:       types.ScopeRead,
+			Handler:     v.serverPlayers,
+		},
+		{
+			Name:        "playerSearch",
+			Path:        "/players/search",
+			Method:      "GET",
+			Description: "Returns the servers a player name has been seen on, servers the player is currently on come first followed by the most recently seen. The `name` query parameter is matched case-insensitively against the scraped player lists.",
+			Params:      url.Values{"name": []string{"Southclaws"}},
+			Accepts:     nil,
+			Returns:     []types.PlayerSighting{types.PlayerSighting{}.Example()},
+			Scope:       types.ScopeRead,
+			Handler:     v.playerSearch,
+		},
+		{
+			Name:        "serverHistory",
+			Path:        "/server/{address}/history",
+			Method:      "GET",
+			Description: "Returns the player count history of a server. Supported query parameters are: `from` `to` (RFC3339, defaults to the last 24 hours) and `resolution` (`raw`, `hour` or `day`, picked from the range when omitted). Raw samples are kept for 24 hours, hourly samples for 30 days and daily samples forever.",
+			Params:      types.HistoryParams{}.Example(),
+			Accepts:     nil,
+			Returns:     []types.Sample{types.Sample{}.Example()},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverHistory,
+		},
 		{
 			Name:        "serverList",
 			Path:        "/servers",
 			Method:      "GET",
-			Description: "Returns a list of servers based on the specified query parameters. Supported query parameters are: `page` `sort` `by` `filters`.",
+			Description: "Returns a list of servers based on the specified query parameters. Supported query parameters are: `page` `sort` `by` `filters`. Servers can be sorted `by` `player` (the default), `hostname`, `maxplayers`, `fill` (the fraction of slots in use), `firstseen`, `updated` (when the server last responded to a query), `uptime` (the percentage of recent queries it answered) or `ping`, the median latency from the API host in milliseconds. `hostname` and `ping` are sorted in ascending order and the others in descending order unless `sort` is `asc` or `desc`, servers with the same value are always ordered by address so pages don't shuffle. Filters are `password` `empty` `full` and `suspicious`, which hides servers flagged as possibly faking their player count, and `platform:<name>` which only keeps servers running `samp`, `openmp` or `samp-dl`. Filters can also be conditions on a field, written as the field, an operator and a value: `language=English` `gamemode~roleplay` `players>=10` `rules.mapname=San Andreas`. Text fields (`address` `hostname` `gamemode` `language` `version` and `rules.<name>`) support `=` and `!=`, which ignore case, and `~` for contains. `players` `maxplayers` and `ping` support `=` `!=` `>` `>=` `<` `<=` and `password` supports `=` and `!=` with `true` or `false`. A server must match every condition.",
 			Params:      types.ServerListParams{}.Example(),
 			Accepts:     nil,
 			Returns:     []types.ServerCore{types.Server{}.Example().Core, types.Server{}.Example().Core, types.Server{}.Example().Core},
+			Scope:       types.ScopeRead,
 			Handler:     v.serverList,
 		},
+		{
+			Name:        "serverSearch",
+			Path:        "/servers/search",
+			Method:      "GET",
+			Description: "Searches the hostnames, gamemodes and descriptions of the active servers. The `q` query parameter is split into words which match whole words, the start of words and words with a typo or two, ignoring case, colour codes and bracket tags such as `[RUS]` in hostnames. Results are ranked by how well they match then by player count and each one lists the fields that matched with the offsets of the matching words. `limit` sets how many results are returned (default 20, at most 100).",
+			Params:      url.Values{"q": []string{"larceny"}},
+			Accepts:     nil,
+			Returns:     []types.SearchResult{types.SearchResult{}.Example()},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverSearch,
+		},
 		{
 			Name:        "serverStats",
 			Path:        "/stats",
@@ -76,8 +138,41 @@ func (v *V2) Routes() []types.Route {
 			Description: `Returns a some statistics of the server index.`,
 			Accepts:     nil,
 			Returns:     types.Statistics{}.Example(),
+			Scope:       types.ScopeRead,
 			Handler:     v.serverStats,
 		},
+		{
+			Name:        "serverStatsHistory",
+			Path:        "/stats/history",
+			Method:      "GET",
+			Description: "Returns the history of the index statistics. Supported query parameters are the same as `serverHistory`: `from` `to` and `resolution`.",
+			Params:      types.HistoryParams{}.Example(),
+			Accepts:     nil,
+			Returns:     []types.StatisticsSample{types.StatisticsSample{}.Example()},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverStatsHistory,
+		},
+		{
+			Name:        "probeAssignments",
+			Path:        "/probe/assignments",
+			Method:      "GET",
+			Description: "Returns the addresses a probe agent should query from its region, the `region` query parameter names the region of the agent. Requires a key with the `probe` scope.",
+			Params:      url.Values{"region": []string{"eu-west"}},
+			Accepts:     nil,
+			Returns:     types.ProbeAssignments{}.Example(),
+			Scope:       types.ScopeProbe,
+			Handler:     v.probeAssignments,
+		},
+		{
+			Name:        "probeResults",
+			Path:        "/probe/results",
+			Method:      "POST",
+			Description: "Stores the reachability and median ping of each server as measured by a probe agent, they appear on the full server object under `regions`. Results for servers that have left the index are ignored. Requires a key with the `probe` scope.",
+			Accepts:     types.ProbeReport{}.Example(),
+			Returns:     types.ProbeReportResult{}.Example(),
+			Scope:       types.ScopeProbe,
+			Handler:     v.probeResults,
+		},
 	}
 }
 
@@ -89,10 +184,12 @@ func WriteError(w http.ResponseWriter, status int, err error) {
 	w.Write([]byte(err.Error()))
 }
 
-// WriteErrors does the same but for groups of errors
+// WriteErrors does the same but for groups of errors, separated by commas
 func WriteErrors(w http.ResponseWriter, status int, errs []error) {
-	w.WriteHeader(status)
-	for _, err := range errs {
-		w.Write([]byte(err.Error() + ", "))
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
 	}
+	w.WriteHeader(status)
+	w.Write([]byte(strings.Join(messages, ", ")))
 }
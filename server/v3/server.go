@@ -0,0 +1,68 @@
+package v3
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// serverGet returns a full server object
+func (v *V3) serverGet(w http.ResponseWriter, r *http.Request) {
+	server, ok := v.lookup(w, r)
+	if !ok {
+		return
+	}
+	write(w, r, server, types.Meta{})
+}
+
+// serverPlayers returns the most recently scraped player list of a server
+func (v *V3) serverPlayers(w http.ResponseWriter, r *http.Request) {
+	server, ok := v.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	list := types.PlayerList{Players: []types.Player{}}
+	if server.PlayerList != nil {
+		list = *server.PlayerList
+		if list.Players == nil {
+			list.Players = []types.Player{}
+		}
+	}
+	write(w, r, list, types.Meta{})
+}
+
+// serverStats returns a set of statistics about the indexed servers
+func (v *V3) serverStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := storage.GetStatistics(v.Storage)
+	if err != nil {
+		WriteProblem(w, r, http.StatusInternalServerError, errors.Wrap(err, "failed to get statistics"))
+		return
+	}
+	write(w, r, stats, types.Meta{})
+}
+
+// lookup finds the server named in the path, the problem is written if it can't be found
+func (v *V3) lookup(w http.ResponseWriter, r *http.Request) (server types.Server, ok bool) {
+	address := mux.Vars(r)["address"]
+	if _, errs := types.AddressFromString(address); errs != nil {
+		WriteProblem(w, r, http.StatusBadRequest, errs[0])
+		return
+	}
+
+	server, found, err := v.Storage.GetServer(address)
+	if err != nil {
+		WriteProblem(w, r, http.StatusInternalServerError, errors.Wrap(err, "failed to get server"))
+		return
+	}
+	if !found {
+		WriteProblem(w, r, http.StatusNotFound, errors.Errorf("could not find server by address '%s'", address))
+		return
+	}
+	v.Scraper.Viewed(address)
+	return server, true
+}
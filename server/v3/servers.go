@@ -0,0 +1,103 @@
+package v3
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dyninc/qstring"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// serverList returns a page of servers after the cursor
+func (v *V3) serverList(w http.ResponseWriter, r *http.Request) {
+	var params types.ServerPageParams
+	err := qstring.Unmarshal(r.URL.Query(), &params)
+	if err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, errors.Wrap(err, "invalid parameters"))
+		return
+	}
+
+	if params.Limit == 0 {
+		params.Limit = DefaultLimit
+	}
+	if params.Limit < 1 || params.Limit > MaxLimit {
+		WriteProblem(w, r, http.StatusBadRequest, errors.Errorf("limit must be between 1 and %d", MaxLimit))
+		return
+	}
+
+	column, desc, err := types.SortOptions(params.Sort, params.By)
+	if err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if _, err = types.FilterConditions(params.Filters); err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if _, err = types.FilterPlatforms(params.Filters); err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, err)
+		return
+	}
+	fingerprint := queryFingerprint(column, desc, params.Filters)
+
+	// one extra server is requested to find out if there's another page
+	query := types.ServerQuery{
+		Limit:   params.Limit + 1,
+		Sort:    params.Sort,
+		By:      params.By,
+		Filters: params.Filters,
+	}
+	if params.Cursor != "" {
+		cursor, errCursor := types.DecodeCursor(params.Cursor)
+		if errCursor != nil {
+			WriteProblem(w, r, http.StatusBadRequest, errCursor)
+			return
+		}
+		if cursor.Query != fingerprint {
+			WriteProblem(w, r, http.StatusBadRequest, errors.New("cursor was returned for a different sort or filters"))
+			return
+		}
+		query.After = &cursor
+	}
+
+	servers, total, err := v.Storage.ListServers(query)
+	if err != nil {
+		WriteProblem(w, r, http.StatusInternalServerError, errors.Wrap(err, "failed to get servers"))
+		return
+	}
+
+	meta := types.Meta{Total: &total}
+	if len(servers) > params.Limit {
+		servers = servers[:params.Limit]
+		last := servers[len(servers)-1]
+		meta.Next = types.Cursor{
+			Key:     types.SortValue(last, column, desc),
+			Address: last.Address,
+			Query:   fingerprint,
+		}.Encode()
+	}
+	if servers == nil {
+		servers = []types.ServerCore{}
+	}
+
+	write(w, r, servers, meta)
+}
+
+// queryFingerprint identifies the order and selection of a listing so a cursor can't be used with a
+// listing it doesn't belong to
+func queryFingerprint(column types.SortColumn, desc bool, filters []types.FilterAttribute) string {
+	sorted := make([]string, len(filters))
+	for i, filter := range filters {
+		sorted[i] = string(filter)
+	}
+	sort.Strings(sorted)
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%s:%t:%s", column, desc, strings.Join(sorted, ",")) // nolint:errcheck
+	return fmt.Sprintf("%x", hash.Sum64())
+}
@@ -0,0 +1,120 @@
+// Package v3 implements version 3 of the public API. Every response is wrapped in an envelope with
+// metadata, listings are paginated with cursors and errors are RFC 7807 problem details.
+package v3
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/scraper"
+	"github.com/Southclaws/samp-servers-api/storage"
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+const (
+	// DefaultLimit is the page size of a listing when none is specified
+	DefaultLimit = 100
+	// MaxLimit is the largest page size of a listing
+	MaxLimit = 1000
+)
+
+// V3 represents an API endpoint handler
+type V3 struct {
+	Storage storage.Store
+	Scraper *scraper.Scraper
+	Config  types.Config
+}
+
+// Init initialises and returns a handler group
+func Init(Storage storage.Store, Scraper *scraper.Scraper, Config types.Config) *V3 {
+	return &V3{
+		Storage: Storage,
+		Scraper: Scraper,
+		Config:  Config,
+	}
+}
+
+// Version returns the route group version name
+func (v *V3) Version() string { return "v3" }
+
+// Routes returns the version routes
+// nolint:lll
+func (v *V3) Routes() []types.Route {
+	example := types.Server{}.Example()
+	return []types.Route{
+		{
+			Name:        "serverList",
+			Path:        "/servers",
+			Method:      "GET",
+			Description: "Returns a page of servers. Supported query parameters are: `limit` (default 100, at most 1000) `cursor` `sort` `by` `filters`, sorting and filters are the same as `/v2/servers`. `meta.total` is the number of servers matching the filters and `meta.next` is the cursor of the next page, it's omitted on the last page. A cursor can only be used with the sort and filters it was returned for.",
+			Params:      types.ServerPageParams{}.Example(),
+			Accepts:     nil,
+			Returns:     types.Envelope{Data: []types.ServerCore{example.Core, example.Core}, Meta: types.Meta{}.Example()},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverList,
+		},
+		{
+			Name:        "serverGet",
+			Path:        "/server/{address}",
+			Method:      "GET",
+			Description: "Returns a full server object using the specified address.",
+			Accepts:     nil,
+			Returns:     types.Envelope{Data: example, Meta: types.Meta{Generated: types.Meta{}.Example().Generated}},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverGet,
+		},
+		{
+			Name:        "serverPlayers",
+			Path:        "/server/{address}/players",
+			Method:      "GET",
+			Description: "Returns the player list of a server from the most recent query, `available` is false when SA:MP refused to send it.",
+			Accepts:     nil,
+			Returns:     types.Envelope{Data: types.PlayerList{}.Example(), Meta: types.Meta{Generated: types.Meta{}.Example().Generated}},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverPlayers,
+		},
+		{
+			Name:        "serverStats",
+			Path:        "/stats",
+			Method:      "GET",
+			Description: "Returns some statistics of the server index.",
+			Accepts:     nil,
+			Returns:     types.Envelope{Data: types.Statistics{}.Example(), Meta: types.Meta{Generated: types.Meta{}.Example().Generated}},
+			Scope:       types.ScopeRead,
+			Handler:     v.serverStats,
+		},
+	}
+}
+
+// WriteError implements types.ErrorWriter so errors from authenticating requests are also problems
+func (v *V3) WriteError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	WriteProblem(w, r, status, err)
+}
+
+// WriteProblem writes an error as an RFC 7807 problem
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(types.Problem{ // nolint:errcheck
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+	})
+}
+
+// write wraps data in the envelope, the time it was generated is filled in
+func write(w http.ResponseWriter, r *http.Request, data interface{}, meta types.Meta) {
+	meta.Generated = time.Now().UTC()
+	encoded, err := json.Marshal(types.Envelope{Data: data, Meta: meta})
+	if err != nil {
+		WriteProblem(w, r, http.StatusInternalServerError, errors.Wrap(err, "failed to encode response"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(encoded) // nolint:errcheck
+}
@@ -2,24 +2,110 @@ package storage
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/Southclaws/samp-servers-api/types"
 )
 
-var mgr *Manager
+var mgr Store
 
 func TestMain(m *testing.M) {
 	var err error
-	mgr, err = New(Config{
-		MongoHost:       "localhost",
-		MongoPort:       "27017",
-		MongoName:       "samplist",
-		MongoUser:       "root",
-		MongoPass:       "",
-		MongoCollection: "servers",
-	})
+	switch os.Getenv("TEST_STORAGE") {
+	case "mongo":
+		mgr, err = New(Config{
+			MongoHost:       "localhost",
+			MongoPort:       "27017",
+			MongoName:       "samplist",
+			MongoUser:       "root",
+			MongoPass:       "",
+			MongoCollection: "servers",
+		})
+	case "postgres":
+		mgr, err = NewPostgres(PostgresConfig{
+			PostgresHost: "localhost",
+			PostgresPort: "5432",
+			PostgresName: "samplist",
+			PostgresUser: "postgres",
+		})
+	case "bolt":
+		mgr, err = NewBolt(BoltConfig{
+			BoltPath: filepath.Join(os.TempDir(), "samplist_test.db"),
+		})
+	default:
+		mgr = NewMemory()
+	}
 	if err != nil {
 		panic(err)
 	}
 
+	for _, server := range fixtures {
+		err = mgr.UpsertServer(server)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	os.Exit(m.Run())
 }
+
+var fixtures = []types.Server{
+	{
+		Core: types.ServerCore{
+			Address:    "ss.southcla.ws",
+			Hostname:   "Scavenge and Survive Official",
+			Players:    4,
+			MaxPlayers: 32,
+			Gamemode:   "Scavenge & Survive by Southclaws",
+			Language:   "English",
+			Password:   false,
+			Version:    "0.3.7-R2",
+		},
+		Rules:       map[string]string{"mapname": "San Androcalypse"},
+		Description: "Scavenge and Survive is a very fun server!",
+		Banner:      "https://i.imgur.com/o13jh8h",
+	},
+	{
+		Core: types.ServerCore{
+			Address:    "s2.example.com",
+			Hostname:   "test server 2",
+			Players:    0,
+			MaxPlayers: 100,
+			Gamemode:   "Grand Larceny",
+			Language:   "English",
+			Password:   false,
+			Version:    "0.3.7-R2",
+		},
+		Rules:       map[string]string{"mapname": "Los Santos"},
+		Description: "Test gamemode!",
+	},
+	{
+		Core: types.ServerCore{
+			Address:    "s3.example.com",
+			Hostname:   "test server 3",
+			Players:    948,
+			MaxPlayers: 1000,
+			Gamemode:   "Grand Larceny",
+			Language:   "English",
+			Password:   false,
+			Version:    "0.3.7-R2",
+		},
+		Rules:       map[string]string{"mapname": "San Fierro"},
+		Description: "Best gamemode!",
+	},
+	{
+		Core: types.ServerCore{
+			Address:    "s4.example.com",
+			Hostname:   "test server 4",
+			Players:    50,
+			MaxPlayers: 50,
+			Gamemode:   "rivershell",
+			Language:   "Polish",
+			Password:   true,
+			Version:    "0.3.7-R2",
+		},
+		Rules:       map[string]string{"mapname": "rivershell"},
+		Description: "Rivershell 4 ever",
+	},
+}
@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// MaxPendingClaims is how many unexpired claims a server can have at once, claims are issued
+// without authentication so this stops them piling up for a single server
+const MaxPendingClaims = 5
+
+// CreateClaim starts the ownership verification of an indexed server by issuing a token and an
+// inactive API key scoped to the server. The plain text key is only returned here, it becomes
+// usable once the token has been seen in the server's rules. When the server already has
+// MaxPendingClaims claims the oldest are removed with their keys, refusing new claims instead
+// would let anyone lock the owner out by claiming the server first.
+func CreateClaim(store Store, address string, now time.Time) (response types.ClaimResponse, err error) {
+	_, found, err := store.GetServer(address)
+	if err != nil {
+		return
+	}
+	if !found {
+		return response, errors.Wrapf(ErrNotFound, "server '%s'", address)
+	}
+
+	claims, err := store.GetClaims(address)
+	if err != nil {
+		return
+	}
+	pending := []types.Claim{}
+	for _, claim := range claims {
+		if now.After(claim.Expires) {
+			if err = expireClaim(store, claim); err != nil {
+				return
+			}
+			continue
+		}
+		pending = append(pending, claim)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Expires.Before(pending[j].Expires) })
+	for len(pending) >= MaxPendingClaims {
+		if err = expireClaim(store, pending[0]); err != nil {
+			return
+		}
+		pending = pending[1:]
+	}
+
+	plain, key, err := types.NewAPIKey(false, types.ScopeRead, types.ScopeServerWrite(address))
+	if err != nil {
+		return
+	}
+	claim, err := types.NewClaim(address, key.ID, now)
+	if err != nil {
+		return
+	}
+
+	if err = store.PutAPIKey(key); err != nil {
+		return
+	}
+	if err = store.PutClaim(claim); err != nil {
+		return
+	}
+
+	return types.ClaimResponse{Claim: claim, Key: plain}, nil
+}
+
+// VerifyClaims checks the rules of a freshly queried server for the tokens of its pending claims,
+// the key of each claim whose token appears in any rule value is activated. Expired claims are
+// removed along with their keys which were never activated.
+func VerifyClaims(store Store, address string, rules map[string]string, now time.Time) (verified []types.Claim, err error) {
+	claims, err := store.GetClaims(address)
+	if err != nil {
+		return
+	}
+
+	for _, claim := range claims {
+		if now.After(claim.Expires) {
+			if err = expireClaim(store, claim); err != nil {
+				return
+			}
+			continue
+		}
+
+		if !hasToken(rules, claim.Token) {
+			continue
+		}
+
+		if err = store.ActivateAPIKey(claim.KeyID); err != nil {
+			return
+		}
+		if err = store.RemoveClaim(claim.Token); err != nil {
+			return
+		}
+		verified = append(verified, claim)
+	}
+	return
+}
+
+// RemoveExpiredClaims removes every claim that has expired along with its key, claims for servers
+// that are never queried again would otherwise be kept forever.
+func RemoveExpiredClaims(store Store, now time.Time) (removed int, err error) {
+	claims, err := store.GetExpiredClaims(now)
+	if err != nil {
+		return
+	}
+	for _, claim := range claims {
+		if err = expireClaim(store, claim); err != nil {
+			return
+		}
+		removed++
+	}
+	return
+}
+
+// expireClaim removes a claim and the key that was issued with it, the key was never activated
+// since verified claims are removed as soon as they're verified.
+func expireClaim(store Store, claim types.Claim) (err error) {
+	if err = store.RemoveClaim(claim.Token); err != nil {
+		return
+	}
+	return store.RemoveAPIKey(claim.KeyID)
+}
+
+// Authenticate looks up an active key from its plain text form
+func Authenticate(store Store, plain string) (key types.APIKey, ok bool, err error) {
+	key, found, err := store.GetAPIKey(types.HashAPIKey(plain))
+	if err != nil || !found || !key.Active {
+		return types.APIKey{}, false, err
+	}
+	return key, true, nil
+}
+
+func hasToken(rules map[string]string, token string) bool {
+	for _, value := range rules {
+		if strings.Contains(value, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKeys orders keys by when they were created
+func sortKeys(keys []types.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].Created.Equal(keys[j].Created) {
+			return keys[i].Created.Before(keys[j].Created)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+}
+
+// sortClaims orders claims by when they expire so results are stable across backends
+func sortClaims(claims []types.Claim) {
+	sort.Slice(claims, func(i, j int) bool {
+		if !claims[i].Expires.Equal(claims[j].Expires) {
+			return claims[i].Expires.Before(claims[j].Expires)
+		}
+		return claims[i].Token < claims[j].Token
+	})
+}
+
+// -
+// MongoDB
+// -
+
+type keyDocument struct {
+	ID      string        `bson:"id"`
+	Hash    string        `bson:"hash"`
+	Scopes  []types.Scope `bson:"scopes"`
+	Active  bool          `bson:"active"`
+	Created time.Time     `bson:"created"`
+}
+
+type claimDocument struct {
+	Address string    `bson:"address"`
+	Token   string    `bson:"token"`
+	KeyID   string    `bson:"key_id"`
+	Expires time.Time `bson:"expires"`
+}
+
+// PutAPIKey creates or replaces an API key, keys are unique by ID
+func (mgr *Manager) PutAPIKey(key types.APIKey) (err error) {
+	_, err = mgr.keys.Upsert(bson.M{"id": key.ID}, keyDocument(key))
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert key")
+	}
+	return
+}
+
+// GetAPIKey looks up an API key via the hash of the plain text key
+func (mgr *Manager) GetAPIKey(hash string) (key types.APIKey, found bool, err error) {
+	var doc keyDocument
+	err = mgr.keys.Find(bson.M{"hash": hash}).One(&doc)
+	if err == mgo.ErrNotFound {
+		return key, false, nil
+	} else if err != nil {
+		return key, false, errors.Wrap(err, "failed to find key")
+	}
+	doc.Created = doc.Created.UTC()
+	return types.APIKey(doc), true, nil
+}
+
+// GetAPIKeys returns every key, including inactive keys for pending claims
+func (mgr *Manager) GetAPIKeys() (keys []types.APIKey, err error) {
+	docs := []keyDocument{}
+	err = mgr.keys.Find(nil).All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find keys")
+	}
+	for _, doc := range docs {
+		doc.Created = doc.Created.UTC()
+		keys = append(keys, types.APIKey(doc))
+	}
+	sortKeys(keys)
+	return
+}
+
+// ActivateAPIKey marks a key as active so it can be used to authenticate requests
+func (mgr *Manager) ActivateAPIKey(id string) (err error) {
+	err = mgr.keys.Update(bson.M{"id": id}, bson.M{"$set": bson.M{"active": true}})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "key '%s'", id)
+	}
+	return
+}
+
+// RemoveAPIKey deletes a key, requests using it are no longer authenticated
+func (mgr *Manager) RemoveAPIKey(id string) (err error) {
+	err = mgr.keys.Remove(bson.M{"id": id})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "key '%s'", id)
+	}
+	return
+}
+
+// PutClaim creates or replaces a claim, claims are unique by token
+func (mgr *Manager) PutClaim(claim types.Claim) (err error) {
+	_, err = mgr.claims.Upsert(bson.M{"token": claim.Token}, claimDocument(claim))
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert claim")
+	}
+	return
+}
+
+// GetClaims returns every pending claim for a server, including expired ones
+func (mgr *Manager) GetClaims(address string) (claims []types.Claim, err error) {
+	docs := []claimDocument{}
+	err = mgr.claims.Find(bson.M{"address": address}).All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find claims")
+	}
+	for _, doc := range docs {
+		doc.Expires = doc.Expires.UTC()
+		claims = append(claims, types.Claim(doc))
+	}
+	sortClaims(claims)
+	return
+}
+
+// GetExpiredClaims returns every claim that expired before a point in time
+func (mgr *Manager) GetExpiredClaims(before time.Time) (claims []types.Claim, err error) {
+	docs := []claimDocument{}
+	err = mgr.claims.Find(bson.M{"expires": bson.M{"$lt": before}}).All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find claims")
+	}
+	for _, doc := range docs {
+		doc.Expires = doc.Expires.UTC()
+		claims = append(claims, types.Claim(doc))
+	}
+	sortClaims(claims)
+	return
+}
+
+// RemoveClaim deletes a claim once it has been verified or has expired
+func (mgr *Manager) RemoveClaim(token string) (err error) {
+	err = mgr.claims.Remove(bson.M{"token": token})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "claim '%s'", token)
+	}
+	return
+}
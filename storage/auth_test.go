@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestVerifyClaims(t *testing.T) {
+	forEachEmbedded(t, testVerifyClaims)
+}
+
+func testVerifyClaims(t *testing.T, store Store) {
+	address := "127.0.0.1:7777"
+	assert.NoError(t, store.UpsertServer(types.Server{Core: types.ServerCore{Address: address}}))
+
+	response, err := CreateClaim(store, address, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, address, response.Address)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.Key)
+
+	expired, err := CreateClaim(store, address, time.Now())
+	assert.NoError(t, err)
+
+	// the key can't be used until the claim is verified
+	_, ok, err := Authenticate(store, response.Key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	now := response.Expires.Add(-time.Hour)
+
+	verified, err := VerifyClaims(store, address, map[string]string{"weburl": "samp-servers.net"}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, verified)
+
+	verified, err = VerifyClaims(store, "127.0.0.2:7777", map[string]string{"weburl": response.Token}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, verified)
+
+	// the other claim expires before the first one is verified
+	expired.Expires = now.Add(-time.Minute)
+	assert.NoError(t, store.PutClaim(expired.Claim))
+
+	verified, err = VerifyClaims(store, address, map[string]string{"weburl": "samp-servers.net " + response.Token}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Claim{response.Claim}, verified)
+
+	key, ok, err := Authenticate(store, response.Key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, response.KeyID, key.ID)
+	assert.True(t, key.Has(types.ScopeServerWrite(address)))
+	assert.False(t, key.Has(types.ScopeServerWrite("127.0.0.2:7777")))
+
+	_, ok, err = Authenticate(store, expired.Key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, found, err := store.GetAPIKey(types.HashAPIKey(expired.Key))
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	keys, err := store.GetAPIKeys()
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.Equal(t, key, keys[0])
+	}
+
+	claims, err := store.GetClaims(address)
+	assert.NoError(t, err)
+	assert.Empty(t, claims)
+}
+
+func TestCreateClaim(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		address := "127.0.0.1:7777"
+		now := time.Now()
+
+		_, err := CreateClaim(store, address, now)
+		assert.Equal(t, ErrNotFound, errors.Cause(err))
+
+		assert.NoError(t, store.UpsertServer(types.Server{Core: types.ServerCore{Address: address}}))
+		var issued []types.ClaimResponse
+		for i := 0; i < MaxPendingClaims; i++ {
+			response, err := CreateClaim(store, address, now.Add(time.Duration(i)*time.Second))
+			assert.NoError(t, err)
+			issued = append(issued, response)
+		}
+
+		// the oldest claim makes room for a new one rather than the new one being refused
+		_, err = CreateClaim(store, address, now.Add(time.Minute))
+		assert.NoError(t, err)
+		_, found, err := store.GetAPIKey(types.HashAPIKey(issued[0].Key))
+		assert.NoError(t, err)
+		assert.False(t, found)
+		_, found, err = store.GetAPIKey(types.HashAPIKey(issued[1].Key))
+		assert.NoError(t, err)
+		assert.True(t, found)
+		claims, err := store.GetClaims(address)
+		assert.NoError(t, err)
+		assert.Len(t, claims, MaxPendingClaims)
+
+		// expired claims are removed before counting
+		later := now.Add(48 * time.Hour)
+		expired := issued[1]
+		expired.Expires = now.Add(-time.Minute)
+		assert.NoError(t, store.PutClaim(expired.Claim))
+		_, err = CreateClaim(store, address, now.Add(time.Minute))
+		assert.NoError(t, err)
+		_, found, err = store.GetAPIKey(types.HashAPIKey(expired.Key))
+		assert.NoError(t, err)
+		assert.False(t, found)
+		_, found, err = store.GetAPIKey(types.HashAPIKey(issued[2].Key))
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		// the sweep removes claims even for servers that aren't queried again
+		removed, err := RemoveExpiredClaims(store, later)
+		assert.NoError(t, err)
+		assert.Equal(t, MaxPendingClaims, removed)
+		claims, err = store.GetClaims(address)
+		assert.NoError(t, err)
+		assert.Empty(t, claims)
+		keys, err := store.GetAPIKeys()
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// BanList keeps the stored bans in memory so they can be checked on every query without hitting
+// storage. Bans should be created and lifted through the list so it stays in sync.
+type BanList struct {
+	store    Store
+	lock     sync.RWMutex
+	bans     []types.Ban
+	matchers []types.BanMatcher
+}
+
+// NewBanList loads the stored bans
+func NewBanList(store Store) (bl *BanList, err error) {
+	bl = &BanList{store: store}
+	err = bl.Reload()
+	return
+}
+
+// Reload replaces the cached bans with the stored ones
+func (bl *BanList) Reload() (err error) {
+	bans, err := bl.store.GetBans()
+	if err != nil {
+		return
+	}
+
+	matchers := make([]types.BanMatcher, len(bans))
+	for i, ban := range bans {
+		matchers[i], err = ban.Matcher()
+		if err != nil {
+			return errors.Wrapf(err, "failed to prepare ban '%s'", ban.ID)
+		}
+	}
+
+	bl.lock.Lock()
+	bl.bans, bl.matchers = bans, matchers
+	bl.lock.Unlock()
+	return
+}
+
+// Add stores a ban and starts enforcing it
+func (bl *BanList) Add(ban types.Ban) (err error) {
+	if err = bl.store.PutBan(ban); err != nil {
+		return
+	}
+	return bl.Reload()
+}
+
+// Remove lifts a ban
+func (bl *BanList) Remove(id string) (err error) {
+	if err = bl.store.RemoveBan(id); err != nil {
+		return
+	}
+	return bl.Reload()
+}
+
+// List returns every ban
+func (bl *BanList) List() (bans []types.Ban) {
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	return append([]types.Ban{}, bl.bans...)
+}
+
+// Check returns a types.BannedError if a server matches any ban, the IP and hostname may be empty
+// if they aren't known yet.
+func (bl *BanList) Check(address, ip, hostname string) error {
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	for i, matcher := range bl.matchers {
+		if matcher(address, ip, hostname) {
+			return types.BannedError{Ban: bl.bans[i]}
+		}
+	}
+	return nil
+}
+
+// sortBans orders bans by when they were created
+func sortBans(bans []types.Ban) {
+	sort.Slice(bans, func(i, j int) bool {
+		if !bans[i].Created.Equal(bans[j].Created) {
+			return bans[i].Created.Before(bans[j].Created)
+		}
+		return bans[i].ID < bans[j].ID
+	})
+}
+
+// sortServers orders servers by address
+func sortServers(servers []types.Server) {
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Core.Address < servers[j].Core.Address
+	})
+}
+
+// -
+// MongoDB
+// -
+
+type banDocument struct {
+	ID      string        `bson:"id"`
+	Kind    types.BanKind `bson:"kind"`
+	Value   string        `bson:"value"`
+	Reason  string        `bson:"reason"`
+	Created time.Time     `bson:"created"`
+}
+
+// PutBan creates or replaces a ban, bans are unique by ID
+func (mgr *Manager) PutBan(ban types.Ban) (err error) {
+	_, err = mgr.bans.Upsert(bson.M{"id": ban.ID}, banDocument(ban))
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert ban")
+	}
+	return
+}
+
+// GetBans returns every ban
+func (mgr *Manager) GetBans() (bans []types.Ban, err error) {
+	docs := []banDocument{}
+	err = mgr.bans.Find(nil).All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find bans")
+	}
+	for _, doc := range docs {
+		doc.Created = doc.Created.UTC()
+		bans = append(bans, types.Ban(doc))
+	}
+	sortBans(bans)
+	return
+}
+
+// RemoveBan deletes a ban
+func (mgr *Manager) RemoveBan(id string) (err error) {
+	err = mgr.bans.Remove(bson.M{"id": id})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "ban '%s'", id)
+	}
+	return
+}
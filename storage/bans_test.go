@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestBans(t *testing.T) {
+	forEachEmbedded(t, testBans)
+}
+
+func testBans(t *testing.T, store Store) {
+	first, errs := types.NewBan(types.BanAddress, "192.168.1.2", "spam")
+	assert.Empty(t, errs)
+	second, errs := types.NewBan(types.BanCIDR, "10.0.0.0/8", "fake players")
+	assert.Empty(t, errs)
+	second.Created = first.Created.Add(1)
+
+	assert.NoError(t, store.PutBan(second))
+	assert.NoError(t, store.PutBan(first))
+
+	bans, err := store.GetBans()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Ban{first, second}, bans)
+
+	assert.NoError(t, store.RemoveBan(first.ID))
+	assert.Error(t, store.RemoveBan(first.ID))
+
+	bans, err = store.GetBans()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Ban{second}, bans)
+}
+
+func TestGetAllServers(t *testing.T) {
+	forEachEmbedded(t, testGetAllServers)
+}
+
+func testGetAllServers(t *testing.T, store Store) {
+	for _, server := range fixtures {
+		assert.NoError(t, store.UpsertServer(server))
+	}
+	assert.NoError(t, store.ArchiveServer(fixtures[1].Core.Address))
+
+	servers, err := store.GetAllServers()
+	assert.NoError(t, err)
+	if !assert.Len(t, servers, len(fixtures)) {
+		return
+	}
+	for i := 1; i < len(servers); i++ {
+		assert.True(t, servers[i-1].Core.Address < servers[i].Core.Address)
+	}
+	for _, server := range servers {
+		assert.Nil(t, server.Rules)
+		assert.Equal(t, server.Core.Address != fixtures[1].Core.Address, server.Active)
+	}
+}
+
+func TestBanList(t *testing.T) {
+	forEachEmbedded(t, testBanList)
+}
+
+func testBanList(t *testing.T, store Store) {
+	bans, err := NewBanList(store)
+	assert.NoError(t, err)
+	assert.NoError(t, bans.Check("192.168.1.2:7777", "", ""))
+
+	cidr, errs := types.NewBan(types.BanCIDR, "192.168.1.0/24", "fake players")
+	assert.Empty(t, errs)
+	assert.NoError(t, bans.Add(cidr))
+	regex, errs := types.NewBan(types.BanRegex, "(?i)free money", "")
+	assert.Empty(t, errs)
+	assert.NoError(t, bans.Add(regex))
+
+	tests := []struct {
+		name     string
+		address  string
+		ip       string
+		hostname string
+		want     *types.Ban
+	}{
+		{"address in range", "192.168.1.2:7777", "", "", &cidr},
+		{"resolved in range", "s1.example.com:7777", "192.168.1.2", "", &cidr},
+		{"hostname", "s1.example.com:7777", "10.0.0.1", "FREE MONEY", &regex},
+		{"allowed", "s1.example.com:7777", "10.0.0.1", "Scavenge and Survive", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bans.Check(tt.address, tt.ip, tt.hostname)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, types.BannedError{Ban: *tt.want}, err)
+		})
+	}
+
+	// a fresh list loads the stored bans
+	reloaded, err := NewBanList(store)
+	assert.NoError(t, err)
+	assert.Len(t, reloaded.List(), 2)
+
+	assert.NoError(t, bans.Remove(cidr.ID))
+	assert.NoError(t, bans.Check("192.168.1.2:7777", "", ""))
+}
@@ -0,0 +1,665 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// BoltConfig describes where the embedded database file lives
+type BoltConfig struct {
+	BoltPath string `split_words:"true" required:"true"`
+}
+
+// Bolt is an embedded storage backend that persists the index to a single local file. Servers are
+// stored as JSON documents keyed by address so archive state and rules survive restarts.
+type Bolt struct {
+	config BoltConfig
+	db     *bolt.DB
+}
+
+var _ Store = &Bolt{}
+
+var (
+	bucketServers = []byte("servers")
+	bucketHistory = []byte("history")
+	bucketPlayers = []byte("players")        // name key + 0x00 + address -> sighting
+	bucketOnline  = []byte("players_online") // address -> name keys currently online
+	bucketKeys    = []byte("api_keys")       // id -> key record
+	bucketClaims  = []byte("claims")         // token -> claim
+	bucketBans    = []byte("bans")           // id -> ban
+	bucketMembers = []byte("members")        // id -> member
+)
+
+// keyRecord stores the hash alongside the key since it's hidden from the JSON representation
+type keyRecord struct {
+	types.APIKey
+	Hash string `json:"hash"`
+}
+
+// NewBolt opens or creates the database file and ensures all buckets exist
+func NewBolt(config BoltConfig) (b *Bolt, err error) {
+	b = &Bolt{
+		config: config,
+	}
+
+	b.db, err = bolt.Open(config.BoltPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database file '%s'", config.BoltPath)
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketServers, bucketHistory, bucketPlayers, bucketOnline, bucketKeys, bucketClaims, bucketBans, bucketMembers} {
+			if _, errInner := tx.CreateBucketIfNotExists(name); errInner != nil {
+				return errInner
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create buckets")
+	}
+
+	return
+}
+
+// Close releases the lock on the database file
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+// GetServer looks up a server via the address
+func (b *Bolt) GetServer(address string) (server types.Server, found bool, err error) {
+	server, found, err = b.GetStoredServer(address)
+	if err != nil || !found || !server.Active {
+		return types.Server{}, false, err
+	}
+	return
+}
+
+// GetStoredServer looks up a server via the address, including archived servers
+func (b *Bolt) GetStoredServer(address string) (server types.Server, found bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucketServers).Get([]byte(address))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(raw, &server)
+	})
+	if err != nil {
+		return types.Server{}, false, err
+	}
+	return
+}
+
+// UpsertServer creates or updates a server object in the database, implicitly sets `Active` to true.
+// The probe results are left as they are since they're only written by PutRegionStatus.
+func (b *Bolt) UpsertServer(server types.Server) (err error) {
+	server.Active = true
+	return b.db.Update(func(tx *bolt.Tx) error {
+		server.Regions = nil
+		if raw := tx.Bucket(bucketServers).Get([]byte(server.Core.Address)); raw != nil {
+			var existing types.Server
+			if err := json.Unmarshal(raw, &existing); err != nil {
+				return err
+			}
+			server.Regions = existing.Regions
+		}
+		return putServer(tx, server)
+	})
+}
+
+// ArchiveServer marks a server as inactive by setting the `Active` field to false
+func (b *Bolt) ArchiveServer(address string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucketServers).Get([]byte(address))
+		if raw == nil {
+			return errors.Wrapf(ErrNotFound, "server '%s'", address)
+		}
+
+		var server types.Server
+		if errInner := json.Unmarshal(raw, &server); errInner != nil {
+			return errInner
+		}
+		server.Active = false
+
+		return putServer(tx, server)
+	})
+}
+
+// RemoveServer deletes a server from the database
+func (b *Bolt) RemoveServer(address string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketServers)
+		if bucket.Get([]byte(address)) == nil {
+			return errors.Wrapf(ErrNotFound, "server '%s'", address)
+		}
+		return bucket.Delete([]byte(address))
+	})
+}
+
+// PutRegionStatus stores the result of a probe agent on an active server
+func (b *Bolt) PutRegionStatus(address, region string, status types.RegionStatus) (found bool, err error) {
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucketServers).Get([]byte(address))
+		if raw == nil {
+			return nil
+		}
+
+		var server types.Server
+		if errInner := json.Unmarshal(raw, &server); errInner != nil {
+			return errInner
+		}
+		if !server.Active {
+			return nil
+		}
+		found = true
+
+		if server.Regions == nil {
+			server.Regions = make(map[string]types.RegionStatus)
+		}
+		server.Regions[region] = status
+		return putServer(tx, server)
+	})
+	return
+}
+
+// GetServers returns a slice of Core objects
+func (b *Bolt) GetServers(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (servers []types.ServerCore, err error) {
+	selected, err := b.allServers()
+	if err != nil {
+		return
+	}
+	return listServers(selected, pageNum, pageSize, sort, by, filters)
+}
+
+// ListServers returns a page of servers after a cursor and the total that match the filters
+func (b *Bolt) ListServers(query types.ServerQuery) (servers []types.ServerCore, total int, err error) {
+	selected, err := b.allServers()
+	if err != nil {
+		return
+	}
+	return pageServers(selected, query)
+}
+
+// GetActiveServers returns the number of active servers
+func (b *Bolt) GetActiveServers() (servers int, err error) {
+	err = b.eachCount(func(count serverCount) {
+		if count.Active {
+			servers++
+		}
+	})
+	return
+}
+
+// GetInactiveServers returns the number of inactive servers
+func (b *Bolt) GetInactiveServers() (servers int, err error) {
+	err = b.eachCount(func(count serverCount) {
+		if !count.Active {
+			servers++
+		}
+	})
+	return
+}
+
+// GetTotalPlayers returns the number of total players
+func (b *Bolt) GetTotalPlayers() (players int, err error) {
+	err = b.eachCount(func(count serverCount) {
+		players += count.Core.Players
+	})
+	return
+}
+
+// serverCount is the part of a stored server the counts need, decoding only this skips allocating
+// the rules and player lists of every server each time the index metrics are updated
+type serverCount struct {
+	Core struct {
+		Players int `json:"pc"`
+	} `json:"core"`
+	Active bool `json:"active"`
+}
+
+func (b *Bolt) eachCount(fn func(serverCount)) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketServers).Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var count serverCount
+			if err := json.Unmarshal(v, &count); err != nil {
+				return errors.Wrapf(err, "failed to decode server '%s'", k)
+			}
+			fn(count)
+		}
+		return nil
+	})
+}
+
+// LoadAllAddresses loads all addresses from the database as a slice of strings for synchronisation
+// with the QueryDaemon.
+func (b *Bolt) LoadAllAddresses() (result []string, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketServers).ForEach(func(k, v []byte) error {
+			result = append(result, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		err = errors.Wrap(err, "failed to load current addresses for query daemon")
+	}
+	return
+}
+
+// GetAllServers returns every server including archived ones, for moderation. Rules and player
+// lists are left out to keep the result small.
+func (b *Bolt) GetAllServers() (servers []types.Server, err error) {
+	servers, err = b.allServers()
+	for i := range servers {
+		servers[i].Rules = nil
+		servers[i].PlayerList = nil
+	}
+	sortServers(servers)
+	return
+}
+
+// PutSamples creates or replaces samples in a series, samples are unique by time. Each resolution
+// has its own bucket which contains a bucket per series keyed by timestamp.
+func (b *Bolt) PutSamples(series string, resolution types.Resolution, samples []types.Sample) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		tier, err := tx.Bucket(bucketHistory).CreateBucketIfNotExists([]byte(resolution))
+		if err != nil {
+			return err
+		}
+		points, err := tier.CreateBucketIfNotExists([]byte(series))
+		if err != nil {
+			return err
+		}
+		for _, sample := range samples {
+			sample.Time = sample.Time.UTC()
+			raw, err := json.Marshal(sample)
+			if err != nil {
+				return err
+			}
+			if err = points.Put(timeKey(sample.Time), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSamples returns the samples of a series in the range [from, to) ordered by time
+func (b *Bolt) GetSamples(series string, resolution types.Resolution, from, to time.Time) (samples []types.Sample, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		points := seriesBucket(tx, series, resolution)
+		if points == nil {
+			return nil
+		}
+		c := points.Cursor()
+		end := timeKey(to)
+		for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			var sample types.Sample
+			if err := json.Unmarshal(v, &sample); err != nil {
+				return err
+			}
+			samples = append(samples, sample)
+		}
+		return nil
+	})
+	return
+}
+
+// RemoveSamples deletes the samples of a series that are older than `before`
+func (b *Bolt) RemoveSamples(series string, resolution types.Resolution, before time.Time) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		points := seriesBucket(tx, series, resolution)
+		if points == nil {
+			return nil
+		}
+		var expired [][]byte
+		c := points.Cursor()
+		end := timeKey(before)
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		for _, k := range expired {
+			if err := points.Delete(k); err != nil {
+				return err
+			}
+		}
+		if k, _ := points.Cursor().First(); k == nil {
+			return tx.Bucket(bucketHistory).Bucket([]byte(resolution)).DeleteBucket([]byte(series))
+		}
+		return nil
+	})
+}
+
+// GetSeries returns the names of every series with samples at the given resolution
+func (b *Bolt) GetSeries(resolution types.Resolution) (series []string, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		tier := tx.Bucket(bucketHistory).Bucket([]byte(resolution))
+		if tier == nil {
+			return nil
+		}
+		return tier.ForEach(func(k, v []byte) error {
+			series = append(series, string(k))
+			return nil
+		})
+	})
+	return
+}
+
+// UpsertPlayers records the current player list of a server, names absent from the list that were
+// previously online on the server are marked as offline.
+func (b *Bolt) UpsertPlayers(address string, names []string, at time.Time) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		players := tx.Bucket(bucketPlayers)
+		online := tx.Bucket(bucketOnline)
+
+		var previous []string
+		if raw := online.Get([]byte(address)); raw != nil {
+			if err := json.Unmarshal(raw, &previous); err != nil {
+				return err
+			}
+		}
+		for _, key := range previous {
+			raw := players.Get(sightingKey(key, address))
+			if raw == nil {
+				continue
+			}
+			var sighting types.PlayerSighting
+			if err := json.Unmarshal(raw, &sighting); err != nil {
+				return err
+			}
+			sighting.Online = false
+			if err := putJSON(players, sightingKey(key, address), sighting); err != nil {
+				return err
+			}
+		}
+
+		keys := make([]string, 0, len(names))
+		for _, name := range names {
+			key := playerKey(name)
+			keys = append(keys, key)
+
+			err := putJSON(players, sightingKey(key, address), types.PlayerSighting{
+				Name:     name,
+				Address:  address,
+				LastSeen: at.UTC(),
+				Online:   true,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return putJSON(online, []byte(address), keys)
+	})
+}
+
+// SearchPlayers returns every server a player name has been seen on
+func (b *Bolt) SearchPlayers(name string) (sightings []types.PlayerSighting, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		prefix := sightingKey(playerKey(name), "")
+		c := tx.Bucket(bucketPlayers).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var sighting types.PlayerSighting
+			if err := json.Unmarshal(v, &sighting); err != nil {
+				return err
+			}
+			sightings = append(sightings, sighting)
+		}
+		return nil
+	})
+	sortSightings(sightings)
+	return
+}
+
+// PutAPIKey creates or replaces an API key, keys are unique by ID
+func (b *Bolt) PutAPIKey(key types.APIKey) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketKeys), []byte(key.ID), keyRecord{APIKey: key, Hash: key.Hash})
+	})
+}
+
+// GetAPIKey looks up an API key via the hash of the plain text key
+func (b *Bolt) GetAPIKey(hash string) (key types.APIKey, found bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketKeys).ForEach(func(k, v []byte) error {
+			var record keyRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if record.Hash == hash {
+				key, found = record.APIKey, true
+				key.Hash = record.Hash
+			}
+			return nil
+		})
+	})
+	return
+}
+
+// GetAPIKeys returns every key, including inactive keys for pending claims
+func (b *Bolt) GetAPIKeys() (keys []types.APIKey, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketKeys).ForEach(func(k, v []byte) error {
+			var record keyRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			key := record.APIKey
+			key.Hash = record.Hash
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	sortKeys(keys)
+	return
+}
+
+// ActivateAPIKey marks a key as active so it can be used to authenticate requests
+func (b *Bolt) ActivateAPIKey(id string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketKeys)
+		raw := bucket.Get([]byte(id))
+		if raw == nil {
+			return errors.Wrapf(ErrNotFound, "key '%s'", id)
+		}
+		var record keyRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		record.Active = true
+		return putJSON(bucket, []byte(id), record)
+	})
+}
+
+// RemoveAPIKey deletes a key, requests using it are no longer authenticated
+func (b *Bolt) RemoveAPIKey(id string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketKeys)
+		if bucket.Get([]byte(id)) == nil {
+			return errors.Wrapf(ErrNotFound, "key '%s'", id)
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// PutClaim creates or replaces a claim, claims are unique by token
+func (b *Bolt) PutClaim(claim types.Claim) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketClaims), []byte(claim.Token), claim)
+	})
+}
+
+// GetClaims returns every pending claim for a server, including expired ones
+func (b *Bolt) GetClaims(address string) (claims []types.Claim, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketClaims).ForEach(func(k, v []byte) error {
+			var claim types.Claim
+			if err := json.Unmarshal(v, &claim); err != nil {
+				return err
+			}
+			if claim.Address == address {
+				claims = append(claims, claim)
+			}
+			return nil
+		})
+	})
+	sortClaims(claims)
+	return
+}
+
+// GetExpiredClaims returns every claim that expired before a point in time
+func (b *Bolt) GetExpiredClaims(before time.Time) (claims []types.Claim, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketClaims).ForEach(func(k, v []byte) error {
+			var claim types.Claim
+			if err := json.Unmarshal(v, &claim); err != nil {
+				return err
+			}
+			if claim.Expires.Before(before) {
+				claims = append(claims, claim)
+			}
+			return nil
+		})
+	})
+	sortClaims(claims)
+	return
+}
+
+// RemoveClaim deletes a claim once it has been verified or has expired
+func (b *Bolt) RemoveClaim(token string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketClaims)
+		if bucket.Get([]byte(token)) == nil {
+			return errors.Wrapf(ErrNotFound, "claim '%s'", token)
+		}
+		return bucket.Delete([]byte(token))
+	})
+}
+
+// PutBan creates or replaces a ban, bans are unique by ID
+func (b *Bolt) PutBan(ban types.Ban) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketBans), []byte(ban.ID), ban)
+	})
+}
+
+// GetBans returns every ban
+func (b *Bolt) GetBans() (bans []types.Ban, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketBans).ForEach(func(k, v []byte) error {
+			var ban types.Ban
+			if err := json.Unmarshal(v, &ban); err != nil {
+				return err
+			}
+			bans = append(bans, ban)
+			return nil
+		})
+	})
+	sortBans(bans)
+	return
+}
+
+// RemoveBan deletes a ban
+func (b *Bolt) RemoveBan(id string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketBans)
+		if bucket.Get([]byte(id)) == nil {
+			return errors.Wrapf(ErrNotFound, "ban '%s'", id)
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// PutMember creates or renews the lease of a replica
+func (b *Bolt) PutMember(member types.Member) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketMembers), []byte(member.ID), member)
+	})
+}
+
+// GetMembers returns every replica including those with expired leases
+func (b *Bolt) GetMembers() (members []types.Member, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketMembers).ForEach(func(k, v []byte) error {
+			var member types.Member
+			if err := json.Unmarshal(v, &member); err != nil {
+				return err
+			}
+			members = append(members, member)
+			return nil
+		})
+	})
+	sortMembers(members)
+	return
+}
+
+// RemoveMember deletes the lease of a replica
+func (b *Bolt) RemoveMember(id string) (err error) {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketMembers)
+		if bucket.Get([]byte(id)) == nil {
+			return errors.Wrapf(ErrNotFound, "member '%s'", id)
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func sightingKey(key, address string) []byte {
+	return []byte(key + "\x00" + address)
+}
+
+func putJSON(bucket *bolt.Bucket, key []byte, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, raw)
+}
+
+func seriesBucket(tx *bolt.Tx, series string, resolution types.Resolution) *bolt.Bucket {
+	tier := tx.Bucket(bucketHistory).Bucket([]byte(resolution))
+	if tier == nil {
+		return nil
+	}
+	return tier.Bucket([]byte(series))
+}
+
+// timeKey encodes a time as a big-endian key so bucket iteration order is chronological, times
+// before the unix epoch (such as the zero time used for open ranges) are clamped to it.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	if t.After(time.Unix(0, 0)) {
+		binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	}
+	return key
+}
+
+func (b *Bolt) allServers() (servers []types.Server, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketServers).ForEach(func(k, v []byte) error {
+			var server types.Server
+			if errInner := json.Unmarshal(v, &server); errInner != nil {
+				return errors.Wrapf(errInner, "failed to decode server '%s'", k)
+			}
+			servers = append(servers, server)
+			return nil
+		})
+	})
+	return
+}
+
+func putServer(tx *bolt.Tx, server types.Server) error {
+	raw, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketServers).Put([]byte(server.Core.Address), raw)
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBolt_SurvivesRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samplist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := BoltConfig{BoltPath: filepath.Join(dir, "samplist.db")}
+
+	b, err := NewBolt(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, b.UpsertServer(fixtures[0]))
+	assert.NoError(t, b.UpsertServer(fixtures[1]))
+	assert.NoError(t, b.ArchiveServer(fixtures[1].Core.Address))
+	assert.NoError(t, b.Close())
+
+	b, err = NewBolt(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	addresses, err := b.LoadAllAddresses()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ss.southcla.ws", "s2.example.com"}, addresses)
+
+	gotServer, found, err := b.GetServer("ss.southcla.ws")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	wantServer := fixtures[0]
+	wantServer.Active = true
+	assert.Equal(t, wantServer, gotServer)
+
+	_, found, err = b.GetServer("s2.example.com")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	inactive, err := b.GetInactiveServers()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, inactive)
+	active, err := b.GetActiveServers()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, active)
+	players, err := b.GetTotalPlayers()
+	assert.NoError(t, err)
+	assert.Equal(t, fixtures[0].Core.Players+fixtures[1].Core.Players, players)
+}
+
+// forEachEmbedded runs a test against a fresh instance of each backend that needs no external
+// database server.
+func forEachEmbedded(t *testing.T, test func(*testing.T, Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("bolt", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "samplist")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		b, err := NewBolt(BoltConfig{BoltPath: filepath.Join(dir, "samplist.db")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer b.Close()
+
+		test(t, b)
+	})
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// sortMembers orders members by ID, which is the order the hash ring is built in
+func sortMembers(members []types.Member) {
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+}
+
+// -
+// MongoDB
+// -
+
+type memberDocument struct {
+	ID      string    `bson:"id"`
+	Started time.Time `bson:"started"`
+	Expires time.Time `bson:"expires"`
+}
+
+// PutMember creates or renews the lease of a replica
+func (mgr *Manager) PutMember(member types.Member) (err error) {
+	_, err = mgr.members.Upsert(bson.M{"id": member.ID}, memberDocument(member))
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert member")
+	}
+	return
+}
+
+// GetMembers returns every replica including those with expired leases
+func (mgr *Manager) GetMembers() (members []types.Member, err error) {
+	docs := []memberDocument{}
+	err = mgr.members.Find(nil).All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find members")
+	}
+	for _, doc := range docs {
+		doc.Started = doc.Started.UTC()
+		doc.Expires = doc.Expires.UTC()
+		members = append(members, types.Member(doc))
+	}
+	sortMembers(members)
+	return
+}
+
+// RemoveMember deletes the lease of a replica
+func (mgr *Manager) RemoveMember(id string) (err error) {
+	err = mgr.members.Remove(bson.M{"id": id})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "member '%s'", id)
+	}
+	return
+}
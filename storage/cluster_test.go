@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestMembers(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		started := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+		b := types.Member{ID: "b", Started: started, Expires: started.Add(time.Minute)}
+		a := types.Member{ID: "a", Started: started, Expires: started.Add(time.Minute)}
+		assert.NoError(t, store.PutMember(b))
+		assert.NoError(t, store.PutMember(a))
+
+		// renewing a lease replaces the expiry
+		a.Expires = started.Add(time.Minute * 2)
+		assert.NoError(t, store.PutMember(a))
+
+		members, err := store.GetMembers()
+		assert.NoError(t, err)
+		assert.Equal(t, []types.Member{a, b}, members)
+
+		assert.NoError(t, store.RemoveMember("b"))
+		assert.Error(t, store.RemoveMember("b"))
+
+		members, err = store.GetMembers()
+		assert.NoError(t, err)
+		assert.Equal(t, []types.Member{a}, members)
+	})
+}
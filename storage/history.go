@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// RawRetention is how long raw samples are kept before being rolled up into hourly samples
+const RawRetention = 24 * time.Hour
+
+// HourRetention is how long hourly samples are kept before being rolled up into daily samples
+const HourRetention = 30 * 24 * time.Hour
+
+// ServerSeries returns the name of the time series that holds player counts for a server
+func ServerSeries(address string) string {
+	return "players:" + address
+}
+
+// RecordSample stores a single raw point in a time series
+func RecordSample(store Store, series string, at time.Time, value float64) (err error) {
+	return store.PutSamples(series, types.ResolutionRaw, []types.Sample{{
+		Time:  at.UTC().Truncate(time.Second),
+		Value: value,
+		Min:   value,
+		Max:   value,
+		Count: 1,
+	}})
+}
+
+// RemoveHistory deletes every sample of a series at every resolution
+func RemoveHistory(store Store, series string) (err error) {
+	// far enough ahead to include samples from hosts with a fast clock
+	end := time.Now().AddDate(1, 0, 0)
+	for _, resolution := range types.Resolutions {
+		err = store.RemoveSamples(series, resolution, end)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove %s samples", resolution)
+		}
+	}
+	return
+}
+
+// Downsample rolls raw samples older than RawRetention into hourly samples and hourly samples older
+// than HourRetention into daily samples then discards the originals. Only whole periods are rolled
+// up and samples are unique by time, so a rollup that was interrupted is simply repeated.
+func Downsample(store Store, now time.Time) (err error) {
+	err = rollup(store, types.ResolutionRaw, types.ResolutionHour, types.ResolutionHour.Bucket(now.Add(-RawRetention)))
+	if err != nil {
+		return
+	}
+	return rollup(store, types.ResolutionHour, types.ResolutionDay, types.ResolutionDay.Bucket(now.Add(-HourRetention)))
+}
+
+func rollup(store Store, from, to types.Resolution, before time.Time) (err error) {
+	all, err := store.GetSeries(from)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list %s series", from)
+	}
+
+	for _, series := range all {
+		samples, err := store.GetSamples(series, from, time.Time{}, before)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get %s samples for %s", from, series)
+		}
+		if len(samples) == 0 {
+			continue
+		}
+
+		rolled := aggregate(samples, to)
+		err = store.PutSamples(series, to, rolled)
+		if err != nil {
+			return errors.Wrapf(err, "failed to store %s samples for %s", to, series)
+		}
+		err = store.RemoveSamples(series, from, before)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove %s samples for %s", from, series)
+		}
+	}
+	return nil
+}
+
+// GetHistory returns the samples of a series between two points in time. Samples stored at a finer
+// resolution than requested are aggregated on the fly, samples only available at a coarser
+// resolution are returned as they are. The automatic resolution picks raw samples for ranges up to
+// two days, hourly samples up to 60 days and daily samples beyond that.
+func GetHistory(store Store, series string, from, to time.Time, resolution types.Resolution) (samples []types.Sample, err error) {
+	if err = resolution.Validate(); err != nil {
+		return
+	}
+	if !from.Before(to) {
+		return nil, errors.New("'from' must be before 'to'")
+	}
+
+	if resolution == types.ResolutionAuto {
+		switch span := to.Sub(from); {
+		case span <= 2*24*time.Hour:
+			resolution = types.ResolutionRaw
+		case span <= 60*24*time.Hour:
+			resolution = types.ResolutionHour
+		default:
+			resolution = types.ResolutionDay
+		}
+	}
+
+	var finer []types.Sample
+	samples = []types.Sample{}
+	coarser := false
+	for _, r := range types.Resolutions {
+		stored, err := store.GetSamples(series, r, from, to)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %s samples", r)
+		}
+		if r == resolution {
+			coarser = true
+			finer = append(finer, stored...)
+			samples = append(samples, aggregate(finer, resolution)...)
+		} else if coarser {
+			samples = append(samples, stored...)
+		} else {
+			finer = append(finer, stored...)
+		}
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return
+}
+
+// aggregate groups samples into buckets of the given resolution, weighting each mean by the number
+// of points it represents.
+func aggregate(samples []types.Sample, resolution types.Resolution) (result []types.Sample) {
+	if resolution == types.ResolutionRaw {
+		return samples
+	}
+
+	buckets := make(map[time.Time]*types.Sample)
+	for _, sample := range samples {
+		count := sample.Count
+		if count < 1 {
+			count = 1
+		}
+
+		key := resolution.Bucket(sample.Time)
+		bucket, ok := buckets[key]
+		if !ok {
+			buckets[key] = &types.Sample{
+				Time:  key,
+				Value: sample.Value,
+				Min:   sample.Min,
+				Max:   sample.Max,
+				Count: count,
+			}
+			continue
+		}
+
+		total := bucket.Count + count
+		bucket.Value = (bucket.Value*float64(bucket.Count) + sample.Value*float64(count)) / float64(total)
+		bucket.Count = total
+		if sample.Min < bucket.Min {
+			bucket.Min = sample.Min
+		}
+		if sample.Max > bucket.Max {
+			bucket.Max = sample.Max
+		}
+	}
+
+	for _, bucket := range buckets {
+		result = append(result, *bucket)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return
+}
+
+// -
+// MongoDB
+// -
+
+type sampleDocument struct {
+	Series     string           `bson:"series"`
+	Resolution types.Resolution `bson:"resolution"`
+	Time       time.Time        `bson:"time"`
+	Value      float64          `bson:"value"`
+	Min        float64          `bson:"min"`
+	Max        float64          `bson:"max"`
+	Count      int              `bson:"count"`
+}
+
+// PutSamples creates or replaces samples in a series, samples are unique by time
+func (mgr *Manager) PutSamples(series string, resolution types.Resolution, samples []types.Sample) (err error) {
+	for _, sample := range samples {
+		_, err = mgr.history.Upsert(
+			bson.M{"series": series, "resolution": resolution, "time": sample.Time},
+			sampleDocument{
+				Series:     series,
+				Resolution: resolution,
+				Time:       sample.Time,
+				Value:      sample.Value,
+				Min:        sample.Min,
+				Max:        sample.Max,
+				Count:      sample.Count,
+			})
+		if err != nil {
+			return errors.Wrap(err, "failed to upsert sample")
+		}
+	}
+	return
+}
+
+// GetSamples returns the samples of a series in the range [from, to) ordered by time
+func (mgr *Manager) GetSamples(series string, resolution types.Resolution, from, to time.Time) (samples []types.Sample, err error) {
+	docs := []sampleDocument{}
+	err = mgr.history.Find(bson.M{
+		"series":     series,
+		"resolution": resolution,
+		"time":       bson.M{"$gte": from, "$lt": to},
+	}).Sort("time").All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find samples")
+	}
+	for _, doc := range docs {
+		samples = append(samples, types.Sample{
+			Time:  doc.Time.UTC(),
+			Value: doc.Value,
+			Min:   doc.Min,
+			Max:   doc.Max,
+			Count: doc.Count,
+		})
+	}
+	return
+}
+
+// RemoveSamples deletes the samples of a series that are older than `before`
+func (mgr *Manager) RemoveSamples(series string, resolution types.Resolution, before time.Time) (err error) {
+	_, err = mgr.history.RemoveAll(bson.M{
+		"series":     series,
+		"resolution": resolution,
+		"time":       bson.M{"$lt": before},
+	})
+	return
+}
+
+// GetSeries returns the names of every series with samples at the given resolution
+func (mgr *Manager) GetSeries(resolution types.Resolution) (series []string, err error) {
+	err = mgr.history.Find(bson.M{"resolution": resolution}).Distinct("series", &series)
+	return
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestDownsample(t *testing.T) {
+	forEachEmbedded(t, testDownsample)
+}
+
+func testDownsample(t *testing.T, store Store) {
+	series := ServerSeries("ss.southcla.ws")
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// two days of samples every 30 minutes, alternating between 10 and 20 players
+	start := now.Add(-48 * time.Hour)
+	for at, i := start, 0; at.Before(now); at, i = at.Add(30*time.Minute), i+1 {
+		assert.NoError(t, RecordSample(store, series, at, float64(10+10*(i%2))))
+	}
+
+	assert.NoError(t, Downsample(store, now))
+
+	raw, err := store.GetSamples(series, types.ResolutionRaw, time.Time{}, now)
+	assert.NoError(t, err)
+	assert.Len(t, raw, 48)
+	assert.True(t, raw[0].Time.Equal(now.Add(-RawRetention).Truncate(time.Hour)))
+
+	hourly, err := store.GetSamples(series, types.ResolutionHour, time.Time{}, now)
+	assert.NoError(t, err)
+	assert.Len(t, hourly, 24)
+	assert.Equal(t, types.Sample{
+		Time:  start,
+		Value: 15,
+		Min:   10,
+		Max:   20,
+		Count: 2,
+	}, hourly[0])
+
+	// running again must not change anything
+	assert.NoError(t, Downsample(store, now))
+	again, err := store.GetSamples(series, types.ResolutionHour, time.Time{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, hourly, again)
+
+	// a month later, the hourly samples are rolled up into days
+	assert.NoError(t, Downsample(store, now.Add(HourRetention+48*time.Hour)))
+	daily, err := store.GetSamples(series, types.ResolutionDay, time.Time{}, now)
+	assert.NoError(t, err)
+	assert.Len(t, daily, 3)
+	for _, sample := range daily {
+		assert.Equal(t, float64(15), sample.Value)
+	}
+	series2, err := store.GetSeries(types.ResolutionRaw)
+	assert.NoError(t, err)
+	assert.Empty(t, series2)
+}
+
+func TestGetHistory(t *testing.T) {
+	forEachEmbedded(t, testGetHistory)
+}
+
+func testGetHistory(t *testing.T, store Store) {
+	series := ServerSeries("ss.southcla.ws")
+	day := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, store.PutSamples(series, types.ResolutionDay, []types.Sample{
+		{Time: day.Add(-24 * time.Hour), Value: 5, Min: 1, Max: 9, Count: 24},
+	}))
+	assert.NoError(t, store.PutSamples(series, types.ResolutionHour, []types.Sample{
+		{Time: day, Value: 10, Min: 10, Max: 10, Count: 1},
+		{Time: day.Add(time.Hour), Value: 20, Min: 20, Max: 20, Count: 1},
+	}))
+	assert.NoError(t, RecordSample(store, series, day.Add(90*time.Minute), 30))
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		resolution  types.Resolution
+		wantSamples []types.Sample
+		wantErr     bool
+	}{
+		{"raw", day.Add(time.Hour), day.Add(2 * time.Hour), types.ResolutionRaw, []types.Sample{
+			{Time: day.Add(time.Hour), Value: 20, Min: 20, Max: 20, Count: 1},
+			{Time: day.Add(90 * time.Minute), Value: 30, Min: 30, Max: 30, Count: 1},
+		}, false},
+		{"hour", day, day.Add(2 * time.Hour), types.ResolutionHour, []types.Sample{
+			{Time: day, Value: 10, Min: 10, Max: 10, Count: 1},
+			{Time: day.Add(time.Hour), Value: 25, Min: 20, Max: 30, Count: 2},
+		}, false},
+		{"day", day.Add(-24 * time.Hour), day.Add(24 * time.Hour), types.ResolutionDay, []types.Sample{
+			{Time: day.Add(-24 * time.Hour), Value: 5, Min: 1, Max: 9, Count: 24},
+			{Time: day, Value: 20, Min: 10, Max: 30, Count: 3},
+		}, false},
+		{"empty", day.Add(48 * time.Hour), day.Add(72 * time.Hour), types.ResolutionAuto, []types.Sample{}, false},
+		{"invalid resolution", day, day.Add(time.Hour), "week", nil, true},
+		{"invalid range", day, day, types.ResolutionRaw, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSamples, err := GetHistory(store, series, tt.from, tt.to, tt.resolution)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSamples, gotSamples)
+		})
+	}
+}
+
+func TestRemoveHistory(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		series := ServerSeries("s2.example.com")
+		at := time.Now().Add(-time.Hour)
+		assert.NoError(t, RecordSample(store, series, at, 10))
+		assert.NoError(t, store.PutSamples(series, types.ResolutionHour, []types.Sample{{Time: at.Truncate(time.Hour), Value: 10, Min: 10, Max: 10, Count: 1}}))
+		assert.NoError(t, RecordSample(store, ServerSeries("s3.example.com"), at, 20))
+
+		assert.NoError(t, RemoveHistory(store, series))
+
+		for _, resolution := range types.Resolutions {
+			samples, err := store.GetSamples(series, resolution, time.Time{}, time.Now())
+			assert.NoError(t, err)
+			assert.Empty(t, samples, resolution)
+		}
+		other, err := store.GetSamples(ServerSeries("s3.example.com"), types.ResolutionRaw, time.Time{}, time.Now())
+		assert.NoError(t, err)
+		assert.Len(t, other, 1)
+	})
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
-	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+
 	"gopkg.in/mgo.v2/bson"
 
 	"github.com/Southclaws/samp-servers-api/types"
@@ -9,8 +11,30 @@ import (
 
 // GetServers returns a slice of Core objects
 func (mgr *Manager) GetServers(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (servers []types.ServerCore, err error) {
+	skip, limit, column, desc, err := listOptions(pageNum, pageSize, sort, by)
+	if err != nil {
+		return
+	}
+
+	query, err := filterQuery(filters)
+	if err != nil {
+		return
+	}
+
 	selected := []types.Server{}
+	err = mgr.collection.
+		Pipe(append(listPipeline(query, column, desc, nil), bson.M{"$skip": skip}, bson.M{"$limit": limit})).
+		All(&selected)
+	if err == nil {
+		for i := range selected {
+			servers = append(servers, selected[i].Core)
+		}
+	}
+	return
+}
 
+// listOptions normalises the pagination and sorting arguments to GetServers
+func listOptions(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn) (skip, limit int, column types.SortColumn, desc bool, err error) {
 	if pageNum <= 0 {
 		pageNum = 0
 	} else {
@@ -21,34 +45,114 @@ func (mgr *Manager) GetServers(pageNum int, pageSize types.PageSize, sort types.
 		pageSize = types.PageSizeDefault
 	}
 
-	var sortBy types.SortOrder
+	if column, desc, err = types.SortOptions(sort, by); err != nil {
+		return
+	}
 
-	if sort == "" {
-		sortBy = "-"
-	} else {
-		switch sort {
-		case types.SortAsc:
-			sortBy = ""
-		case types.SortDesc:
-			sortBy = "-"
-		default:
-			err = errors.Errorf("invalid 'sort' argument '%s'", sort)
-			return
-		}
+	return pageNum * int(pageSize), int(pageSize), column, desc, nil
+}
+
+// ListServers returns a page of servers after a cursor and the total that match the filters
+func (mgr *Manager) ListServers(query types.ServerQuery) (servers []types.ServerCore, total int, err error) {
+	column, desc, err := types.SortOptions(query.Sort, query.By)
+	if err != nil {
+		return
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = int(types.PageSizeDefault)
 	}
 
-	if by == "" {
-		sortBy += "core.players"
-	} else {
-		switch by {
-		case types.ByPlayers:
-			sortBy += "core.players"
-		default:
-			err = errors.Errorf("invalid 'by' argument '%s'", by)
-			return
+	selector, err := filterQuery(query.Filters)
+	if err != nil {
+		return
+	}
+	total, err = mgr.collection.Find(selector).Count()
+	if err != nil {
+		return
+	}
+
+	selected := []types.Server{}
+	err = mgr.collection.
+		Pipe(append(listPipeline(selector, column, desc, query.After), bson.M{"$limit": limit})).
+		All(&selected)
+	for i := range selected {
+		servers = append(servers, selected[i].Core)
+	}
+	return
+}
+
+// listPipeline builds an aggregation that selects the servers matching the query, after the cursor
+// if there is one, in the order of a validated column then the address. The value of the column is
+// added to each server as `sortkey` so computed columns sort the same way as stored ones.
+func listPipeline(query bson.M, column types.SortColumn, desc bool, after *types.Cursor) []bson.M {
+	direction := 1
+	if desc {
+		direction = -1
+	}
+
+	pipeline := []bson.M{
+		{"$match": query},
+		{"$addFields": bson.M{"sortkey": sortExpression(column, desc)}},
+	}
+	if after != nil {
+		compare := "$gt"
+		if desc {
+			compare = "$lt"
 		}
+		key := keyValue(after.Key, column)
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"$or": []bson.M{
+			{"sortkey": bson.M{compare: key}},
+			{"sortkey": key, "core.address": bson.M{"$gt": after.Address}},
+		}}})
+	}
+	return append(pipeline, bson.M{"$sort": bson.D{{Name: "sortkey", Value: direction}, {Name: "core.address", Value: 1}}})
+}
+
+// sortExpression returns the aggregation expression for the value of a validated sort column, the
+// direction is needed for the key of servers without a measured ping
+func sortExpression(column types.SortColumn, desc bool) interface{} {
+	switch column {
+	case types.ByPing:
+		return bson.M{"$cond": []interface{}{
+			bson.M{"$gt": []interface{}{"$core.ping", 0}},
+			"$core.ping",
+			types.UnmeasuredPing(desc),
+		}}
+	case types.ByHostname:
+		return bson.M{"$toLower": "$core.hostname"}
+	case types.ByMaxPlayers:
+		return "$core.maxplayers"
+	case types.ByFill:
+		return bson.M{"$cond": []interface{}{
+			bson.M{"$gt": []interface{}{"$core.maxplayers", 0}},
+			bson.M{"$divide": []interface{}{"$core.players", "$core.maxplayers"}},
+			0,
+		}}
+	case types.ByFirstSeen:
+		return "$core.firstseen"
+	case types.ByUpdated:
+		return "$core.updated"
+	case types.ByUptime:
+		return "$core.uptime"
+	}
+	return "$core.players"
+}
+
+// keyValue returns the value of a cursor's key for a validated sort column, for use as a query
+// argument
+func keyValue(key types.SortKey, column types.SortColumn) interface{} {
+	switch column {
+	case types.ByHostname:
+		return key.Text
+	case types.ByFirstSeen, types.ByUpdated:
+		return key.Time
 	}
+	return key.Number
+}
 
+// filterQuery builds the MongoDB query that selects the active servers matching the filters
+func filterQuery(filters []types.FilterAttribute) (bson.M, error) {
 	query := bson.M{"active": true}
 
 	if len(filters) > 0 {
@@ -59,21 +163,85 @@ func (mgr *Manager) GetServers(pageNum int, pageSize types.PageSize, sort types.
 			case types.FilterEmpty:
 				query["core.players"] = bson.M{"$gt": 0}
 			case types.FilterFull:
-				query["$where"] = "this.core.players < this.core.maxplayers"
+				query["$expr"] = bson.M{"$lt": []interface{}{"$core.players", "$core.maxplayers"}}
+			case types.FilterSuspicious:
+				query["suspicious.0"] = bson.M{"$exists": false}
+			}
+		}
+		platforms, err := types.FilterPlatforms(filters)
+		if err != nil {
+			return nil, err
+		}
+		if platforms != nil {
+			values := []interface{}{}
+			for _, platform := range platforms {
+				values = append(values, platform)
+				if platform == types.PlatformSAMP {
+					values = append(values, nil, "") // stored before platforms were detected
+				}
+			}
+			query["platform"] = bson.M{"$in": values}
+		}
+
+		conditions, err := types.FilterConditions(filters)
+		if err != nil {
+			return nil, err
+		}
+		// conditions go in $and so they can't replace the filters above that use the same field
+		if len(conditions) > 0 {
+			and := []bson.M{}
+			for _, condition := range conditions {
+				and = append(and, conditionQuery(condition))
 			}
+			query["$and"] = and
 		}
 	}
 
-	err = mgr.collection.
-		Find(query).
-		Sort(string(sortBy)).
-		Skip(pageNum * int(pageSize)).
-		Limit(int(pageSize)).
-		All(&selected)
-	if err == nil {
-		for i := range selected {
-			servers = append(servers, selected[i].Core)
+	return query, nil
+}
+
+// conditionQuery compiles a filter condition to a MongoDB query. Text is compared with the lower
+// cased copy of the field stored by UpsertServer so equality can use an index, contains is matched
+// with an escaped case-insensitive regular expression.
+func conditionQuery(condition types.Condition) bson.M {
+	field := "core." + condition.Field
+	if condition.Rule != "" {
+		field = "rules." + condition.Rule
+	}
+
+	switch condition.Kind {
+	case types.FieldNumber:
+		switch condition.Operator {
+		case types.OpNotEqual:
+			return bson.M{field: bson.M{"$ne": condition.Number}}
+		case types.OpGreater:
+			return bson.M{field: bson.M{"$gt": condition.Number}}
+		case types.OpGreaterEqual:
+			return bson.M{field: bson.M{"$gte": condition.Number}}
+		case types.OpLess:
+			return bson.M{field: bson.M{"$lt": condition.Number}}
+		case types.OpLessEqual:
+			return bson.M{field: bson.M{"$lte": condition.Number}}
+		}
+		return bson.M{field: condition.Number}
+	case types.FieldBool:
+		if condition.Operator == types.OpNotEqual {
+			return bson.M{field: bson.M{"$ne": condition.Bool}}
 		}
+		return bson.M{field: condition.Bool}
 	}
-	return
+
+	if condition.Operator == types.OpContains {
+		return bson.M{field: bson.RegEx{Pattern: regexp.QuoteMeta(condition.Text), Options: "i"}}
+	}
+
+	lower := "lower." + condition.Field
+	if condition.Rule != "" {
+		lower = "lower.rules." + condition.Rule
+	}
+	value := strings.ToLower(condition.Text)
+	if condition.Operator == types.OpNotEqual {
+		return bson.M{lower: bson.M{"$ne": value}}
+	}
+	return bson.M{lower: value}
 }
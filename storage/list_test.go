@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/Southclaws/samp-servers-api/types"
 )
@@ -27,10 +28,10 @@ func TestManager_GetServers(t *testing.T) {
 			"v no sort",
 			args{1, 0, "", "", []types.FilterAttribute{}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"s4.example.com", "test server 4", 50, 50, "rivershell", "Polish", true, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
-				{"s2.example.com", "test server 2", 0, 100, "Grand Larceny", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s4.example.com", Hostname: "test server 4", Players: 50, MaxPlayers: 50, Gamemode: "rivershell", Language: "Polish", Password: true, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s2.example.com", Hostname: "test server 2", Players: 0, MaxPlayers: 100, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -38,10 +39,10 @@ func TestManager_GetServers(t *testing.T) {
 			"v desc",
 			args{1, 0, "asc", "", []types.FilterAttribute{}},
 			[]types.ServerCore{
-				{"s2.example.com", "test server 2", 0, 100, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
-				{"s4.example.com", "test server 4", 50, 50, "rivershell", "Polish", true, "0.3.7-R2"},
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
+				{Address: "s2.example.com", Hostname: "test server 2", Players: 0, MaxPlayers: 100, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s4.example.com", Hostname: "test server 4", Players: 50, MaxPlayers: 50, Gamemode: "rivershell", Language: "Polish", Password: true, Version: "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -49,9 +50,9 @@ func TestManager_GetServers(t *testing.T) {
 			"v pass",
 			args{1, 0, "", "", []types.FilterAttribute{types.FilterPassword}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
-				{"s2.example.com", "test server 2", 0, 100, "Grand Larceny", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s2.example.com", Hostname: "test server 2", Players: 0, MaxPlayers: 100, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -59,9 +60,9 @@ func TestManager_GetServers(t *testing.T) {
 			"v empty",
 			args{1, 0, "", "", []types.FilterAttribute{types.FilterEmpty}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"s4.example.com", "test server 4", 50, 50, "rivershell", "Polish", true, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s4.example.com", Hostname: "test server 4", Players: 50, MaxPlayers: 50, Gamemode: "rivershell", Language: "Polish", Password: true, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -69,9 +70,9 @@ func TestManager_GetServers(t *testing.T) {
 			"v full",
 			args{1, 0, "", "", []types.FilterAttribute{types.FilterFull}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
-				{"s2.example.com", "test server 2", 0, 100, "Grand Larceny", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s2.example.com", Hostname: "test server 2", Players: 0, MaxPlayers: 100, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -79,8 +80,8 @@ func TestManager_GetServers(t *testing.T) {
 			"v pass empty",
 			args{1, 0, "", "", []types.FilterAttribute{types.FilterPassword, types.FilterEmpty}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -88,9 +89,9 @@ func TestManager_GetServers(t *testing.T) {
 			"v pass full",
 			args{1, 0, "", "", []types.FilterAttribute{types.FilterPassword, types.FilterFull}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
-				{"s2.example.com", "test server 2", 0, 100, "Grand Larceny", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "s2.example.com", Hostname: "test server 2", Players: 0, MaxPlayers: 100, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -98,8 +99,8 @@ func TestManager_GetServers(t *testing.T) {
 			"v empty full",
 			args{1, 0, "", "", []types.FilterAttribute{types.FilterEmpty, types.FilterFull}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -107,7 +108,7 @@ func TestManager_GetServers(t *testing.T) {
 			"limit to 1",
 			args{1, 1, "", "", []types.FilterAttribute{types.FilterPassword, types.FilterFull}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -115,7 +116,7 @@ func TestManager_GetServers(t *testing.T) {
 			"get second page",
 			args{2, 1, "", "", []types.FilterAttribute{types.FilterPassword, types.FilterFull}},
 			[]types.ServerCore{
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -123,8 +124,8 @@ func TestManager_GetServers(t *testing.T) {
 			"get multiple per page",
 			args{1, 2, "", "", []types.FilterAttribute{types.FilterPassword, types.FilterFull}},
 			[]types.ServerCore{
-				{"s3.example.com", "test server 3", 948, 1000, "Grand Larceny", "English", false, "0.3.7-R2"},
-				{"ss.southcla.ws", "Scavenge and Survive Official", 4, 32, "Scavenge & Survive by Southclaws", "English", false, "0.3.7-R2"},
+				{Address: "s3.example.com", Hostname: "test server 3", Players: 948, MaxPlayers: 1000, Gamemode: "Grand Larceny", Language: "English", Password: false, Version: "0.3.7-R2"},
+				{Address: "ss.southcla.ws", Hostname: "Scavenge and Survive Official", Players: 4, MaxPlayers: 32, Gamemode: "Scavenge & Survive by Southclaws", Language: "English", Password: false, Version: "0.3.7-R2"},
 			},
 			false,
 		},
@@ -137,3 +138,48 @@ func TestManager_GetServers(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []types.FilterAttribute
+		want    bson.M
+		wantErr bool
+	}{
+		{"none", nil, bson.M{"active": true}, false},
+		{
+			"full",
+			[]types.FilterAttribute{types.FilterFull},
+			bson.M{"active": true, "$expr": bson.M{"$lt": []interface{}{"$core.players", "$core.maxplayers"}}},
+			false,
+		},
+		{
+			"conditions",
+			[]types.FilterAttribute{types.FilterEmpty, "language=English", "players<=200", "gamemode~r.p", "rules.mapname!=San Andreas", "password=false"},
+			bson.M{
+				"active":       true,
+				"core.players": bson.M{"$gt": 0},
+				"$and": []bson.M{
+					{"lower.language": "english"},
+					{"core.players": bson.M{"$lte": 200}},
+					{"core.gamemode": bson.RegEx{Pattern: `r\.p`, Options: "i"}},
+					{"lower.rules.mapname": bson.M{"$ne": "san andreas"}},
+					{"core.password": false},
+				},
+			},
+			false,
+		},
+		{"invalid", []types.FilterAttribute{"rules.$where=1"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterQuery(tt.filters)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -21,3 +21,14 @@ func (mgr *Manager) LoadAllAddresses() (result []string, err error) {
 	}
 	return
 }
+
+// GetAllServers returns every server including archived ones, for moderation. Rules and player
+// lists are left out to keep the result small.
+func (mgr *Manager) GetAllServers() (servers []types.Server, err error) {
+	err = mgr.collection.Find(bson.M{}).Select(bson.M{"rules": 0, "playerlist": 0}).All(&servers)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load all servers")
+	}
+	sortServers(servers)
+	return
+}
@@ -0,0 +1,670 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// Memory is an in-memory storage backend. Nothing is persisted between restarts so it's suitable
+// for development, testing and CI environments where running MongoDB is not an option.
+type Memory struct {
+	lock    sync.RWMutex
+	servers map[string]types.Server
+	history map[types.Resolution]map[string]map[time.Time]types.Sample
+	players map[string]map[string]types.PlayerSighting // name key -> address -> sighting
+	online  map[string][]string                        // address -> name keys currently online
+	keys    map[string]types.APIKey                    // id -> key
+	claims  map[string]types.Claim                     // token -> claim
+	bans    map[string]types.Ban                       // id -> ban
+	members map[string]types.Member                    // id -> member
+}
+
+var _ Store = &Memory{}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		servers: make(map[string]types.Server),
+		history: make(map[types.Resolution]map[string]map[time.Time]types.Sample),
+		players: make(map[string]map[string]types.PlayerSighting),
+		online:  make(map[string][]string),
+		keys:    make(map[string]types.APIKey),
+		claims:  make(map[string]types.Claim),
+		bans:    make(map[string]types.Ban),
+		members: make(map[string]types.Member),
+	}
+}
+
+// GetServer looks up a server via the address
+func (mem *Memory) GetServer(address string) (server types.Server, found bool, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	server, found = mem.servers[address]
+	if !found || !server.Active {
+		return types.Server{}, false, nil
+	}
+	return copyServer(server), true, nil
+}
+
+// GetStoredServer looks up a server via the address, including archived servers
+func (mem *Memory) GetStoredServer(address string) (server types.Server, found bool, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	server, found = mem.servers[address]
+	if !found {
+		return types.Server{}, false, nil
+	}
+	return copyServer(server), true, nil
+}
+
+// UpsertServer creates or updates a server object in the store, implicitly sets `Active` to true.
+// The probe results are left as they are since they're only written by PutRegionStatus.
+func (mem *Memory) UpsertServer(server types.Server) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	server.Active = true
+	server.Regions = mem.servers[server.Core.Address].Regions
+	mem.servers[server.Core.Address] = copyServer(server)
+	return
+}
+
+// ArchiveServer marks a server as inactive by setting the `Active` field to false
+func (mem *Memory) ArchiveServer(address string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	server, ok := mem.servers[address]
+	if !ok {
+		return errors.Wrapf(ErrNotFound, "server '%s'", address)
+	}
+	server.Active = false
+	mem.servers[address] = server
+	return
+}
+
+// RemoveServer deletes a server from the store
+func (mem *Memory) RemoveServer(address string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	if _, ok := mem.servers[address]; !ok {
+		return errors.Wrapf(ErrNotFound, "server '%s'", address)
+	}
+	delete(mem.servers, address)
+	return
+}
+
+// PutRegionStatus stores the result of a probe agent on an active server
+func (mem *Memory) PutRegionStatus(address, region string, status types.RegionStatus) (found bool, err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	server, ok := mem.servers[address]
+	if !ok || !server.Active {
+		return false, nil
+	}
+	server = copyServer(server)
+	if server.Regions == nil {
+		server.Regions = make(map[string]types.RegionStatus)
+	}
+	server.Regions[region] = status
+	mem.servers[address] = server
+	return true, nil
+}
+
+// GetServers returns a slice of Core objects
+func (mem *Memory) GetServers(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (servers []types.ServerCore, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	selected := make([]types.Server, 0, len(mem.servers))
+	for _, server := range mem.servers {
+		selected = append(selected, server)
+	}
+
+	return listServers(selected, pageNum, pageSize, sort, by, filters)
+}
+
+// ListServers returns a page of servers after a cursor and the total that match the filters
+func (mem *Memory) ListServers(query types.ServerQuery) (servers []types.ServerCore, total int, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	selected := make([]types.Server, 0, len(mem.servers))
+	for _, server := range mem.servers {
+		selected = append(selected, server)
+	}
+
+	return pageServers(selected, query)
+}
+
+// GetActiveServers returns the number of active servers
+func (mem *Memory) GetActiveServers() (servers int, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, server := range mem.servers {
+		if server.Active {
+			servers++
+		}
+	}
+	return
+}
+
+// GetInactiveServers returns the number of inactive servers
+func (mem *Memory) GetInactiveServers() (servers int, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, server := range mem.servers {
+		if !server.Active {
+			servers++
+		}
+	}
+	return
+}
+
+// GetTotalPlayers returns the number of total players
+func (mem *Memory) GetTotalPlayers() (players int, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, server := range mem.servers {
+		players += server.Core.Players
+	}
+	return
+}
+
+// LoadAllAddresses loads all addresses from the store as a slice of strings for synchronisation
+// with the QueryDaemon.
+func (mem *Memory) LoadAllAddresses() (result []string, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for address := range mem.servers {
+		result = append(result, address)
+	}
+	return
+}
+
+// GetAllServers returns every server including archived ones, for moderation. Rules and player
+// lists are left out to keep the result small.
+func (mem *Memory) GetAllServers() (servers []types.Server, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, server := range mem.servers {
+		server.Rules = nil
+		server.PlayerList = nil
+		servers = append(servers, server)
+	}
+	sortServers(servers)
+	return
+}
+
+// PutSamples creates or replaces samples in a series, samples are unique by time
+func (mem *Memory) PutSamples(series string, resolution types.Resolution, samples []types.Sample) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	all, ok := mem.history[resolution]
+	if !ok {
+		all = make(map[string]map[time.Time]types.Sample)
+		mem.history[resolution] = all
+	}
+	points, ok := all[series]
+	if !ok {
+		points = make(map[time.Time]types.Sample)
+		all[series] = points
+	}
+	for _, sample := range samples {
+		sample.Time = sample.Time.UTC()
+		points[sample.Time] = sample
+	}
+	return
+}
+
+// GetSamples returns the samples of a series in the range [from, to) ordered by time
+func (mem *Memory) GetSamples(series string, resolution types.Resolution, from, to time.Time) (samples []types.Sample, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for t, sample := range mem.history[resolution][series] {
+		if !t.Before(from) && t.Before(to) {
+			samples = append(samples, sample)
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return
+}
+
+// RemoveSamples deletes the samples of a series that are older than `before`
+func (mem *Memory) RemoveSamples(series string, resolution types.Resolution, before time.Time) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	points := mem.history[resolution][series]
+	for t := range points {
+		if t.Before(before) {
+			delete(points, t)
+		}
+	}
+	if len(points) == 0 {
+		delete(mem.history[resolution], series)
+	}
+	return
+}
+
+// GetSeries returns the names of every series with samples at the given resolution
+func (mem *Memory) GetSeries(resolution types.Resolution) (series []string, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for name := range mem.history[resolution] {
+		series = append(series, name)
+	}
+	sort.Strings(series)
+	return
+}
+
+// UpsertPlayers records the current player list of a server, names absent from the list that were
+// previously online on the server are marked as offline.
+func (mem *Memory) UpsertPlayers(address string, names []string, at time.Time) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	for _, key := range mem.online[address] {
+		sighting := mem.players[key][address]
+		sighting.Online = false
+		mem.players[key][address] = sighting
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		key := playerKey(name)
+		keys = append(keys, key)
+
+		sightings, ok := mem.players[key]
+		if !ok {
+			sightings = make(map[string]types.PlayerSighting)
+			mem.players[key] = sightings
+		}
+		sightings[address] = types.PlayerSighting{
+			Name:     name,
+			Address:  address,
+			LastSeen: at.UTC(),
+			Online:   true,
+		}
+	}
+	mem.online[address] = keys
+	return
+}
+
+// SearchPlayers returns every server a player name has been seen on
+func (mem *Memory) SearchPlayers(name string) (sightings []types.PlayerSighting, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, sighting := range mem.players[playerKey(name)] {
+		sightings = append(sightings, sighting)
+	}
+	sortSightings(sightings)
+	return
+}
+
+// PutAPIKey creates or replaces an API key, keys are unique by ID
+func (mem *Memory) PutAPIKey(key types.APIKey) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	key.Scopes = append([]types.Scope(nil), key.Scopes...)
+	mem.keys[key.ID] = key
+	return
+}
+
+// GetAPIKey looks up an API key via the hash of the plain text key
+func (mem *Memory) GetAPIKey(hash string) (key types.APIKey, found bool, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, key = range mem.keys {
+		if key.Hash == hash {
+			key.Scopes = append([]types.Scope(nil), key.Scopes...)
+			return key, true, nil
+		}
+	}
+	return types.APIKey{}, false, nil
+}
+
+// GetAPIKeys returns every key, including inactive keys for pending claims
+func (mem *Memory) GetAPIKeys() (keys []types.APIKey, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, key := range mem.keys {
+		key.Scopes = append([]types.Scope(nil), key.Scopes...)
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return
+}
+
+// ActivateAPIKey marks a key as active so it can be used to authenticate requests
+func (mem *Memory) ActivateAPIKey(id string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	key, ok := mem.keys[id]
+	if !ok {
+		return errors.Wrapf(ErrNotFound, "key '%s'", id)
+	}
+	key.Active = true
+	mem.keys[id] = key
+	return
+}
+
+// RemoveAPIKey deletes a key, requests using it are no longer authenticated
+func (mem *Memory) RemoveAPIKey(id string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	if _, ok := mem.keys[id]; !ok {
+		return errors.Wrapf(ErrNotFound, "key '%s'", id)
+	}
+	delete(mem.keys, id)
+	return
+}
+
+// PutClaim creates or replaces a claim, claims are unique by token
+func (mem *Memory) PutClaim(claim types.Claim) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	mem.claims[claim.Token] = claim
+	return
+}
+
+// GetClaims returns every pending claim for a server, including expired ones
+func (mem *Memory) GetClaims(address string) (claims []types.Claim, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, claim := range mem.claims {
+		if claim.Address == address {
+			claims = append(claims, claim)
+		}
+	}
+	sortClaims(claims)
+	return
+}
+
+// GetExpiredClaims returns every claim that expired before a point in time
+func (mem *Memory) GetExpiredClaims(before time.Time) (claims []types.Claim, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, claim := range mem.claims {
+		if claim.Expires.Before(before) {
+			claims = append(claims, claim)
+		}
+	}
+	sortClaims(claims)
+	return
+}
+
+// RemoveClaim deletes a claim once it has been verified or has expired
+func (mem *Memory) RemoveClaim(token string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	if _, ok := mem.claims[token]; !ok {
+		return errors.Wrapf(ErrNotFound, "claim '%s'", token)
+	}
+	delete(mem.claims, token)
+	return
+}
+
+// PutBan creates or replaces a ban, bans are unique by ID
+func (mem *Memory) PutBan(ban types.Ban) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	mem.bans[ban.ID] = ban
+	return
+}
+
+// GetBans returns every ban
+func (mem *Memory) GetBans() (bans []types.Ban, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, ban := range mem.bans {
+		bans = append(bans, ban)
+	}
+	sortBans(bans)
+	return
+}
+
+// RemoveBan deletes a ban
+func (mem *Memory) RemoveBan(id string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	if _, ok := mem.bans[id]; !ok {
+		return errors.Wrapf(ErrNotFound, "ban '%s'", id)
+	}
+	delete(mem.bans, id)
+	return
+}
+
+// PutMember creates or renews the lease of a replica
+func (mem *Memory) PutMember(member types.Member) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	mem.members[member.ID] = member
+	return
+}
+
+// GetMembers returns every replica including those with expired leases
+func (mem *Memory) GetMembers() (members []types.Member, err error) {
+	mem.lock.RLock()
+	defer mem.lock.RUnlock()
+
+	for _, member := range mem.members {
+		members = append(members, member)
+	}
+	sortMembers(members)
+	return
+}
+
+// RemoveMember deletes the lease of a replica
+func (mem *Memory) RemoveMember(id string) (err error) {
+	mem.lock.Lock()
+	defer mem.lock.Unlock()
+
+	if _, ok := mem.members[id]; !ok {
+		return errors.Wrapf(ErrNotFound, "member '%s'", id)
+	}
+	delete(mem.members, id)
+	return
+}
+
+// listServers applies the same filtering, sorting and pagination rules as the MongoDB GetServers
+// query to a set of servers held in process. Ties are broken by address so pages are stable.
+func listServers(selected []types.Server, pageNum int, pageSize types.PageSize, order types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (servers []types.ServerCore, err error) {
+	skip, limit, column, desc, err := listOptions(pageNum, pageSize, order, by)
+	if err != nil {
+		return
+	}
+
+	matched, err := selectServers(selected, filters, column, desc)
+	if err != nil {
+		return
+	}
+
+	if skip >= len(matched) {
+		return
+	}
+	end := skip + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	for _, server := range matched[skip:end] {
+		servers = append(servers, server.Core)
+	}
+	return
+}
+
+// pageServers applies the same filtering and ordering as listServers then returns the servers after
+// the cursor along with how many servers matched in total
+func pageServers(selected []types.Server, query types.ServerQuery) (servers []types.ServerCore, total int, err error) {
+	column, desc, err := types.SortOptions(query.Sort, query.By)
+	if err != nil {
+		return
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = int(types.PageSizeDefault)
+	}
+
+	matched, err := selectServers(selected, query.Filters, column, desc)
+	if err != nil {
+		return
+	}
+	total = len(matched)
+
+	start := 0
+	if query.After != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return after(matched[i].Core, *query.After, column, desc)
+		})
+	}
+	for i := start; i < len(matched) && i < start+limit; i++ {
+		servers = append(servers, matched[i].Core)
+	}
+	return
+}
+
+// selectServers filters the servers and sorts them by a validated column, ties are broken by address
+func selectServers(selected []types.Server, filters []types.FilterAttribute, column types.SortColumn, desc bool) ([]types.Server, error) {
+	conditions, err := types.FilterConditions(filters)
+	if err != nil {
+		return nil, err
+	}
+	platforms, err := types.FilterPlatforms(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := selected[:0]
+	for _, server := range selected {
+		if matchFilters(server, filters, conditions, platforms) {
+			matched = append(matched, server)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		compare := types.SortValue(matched[i].Core, column, desc).Compare(types.SortValue(matched[j].Core, column, desc))
+		if compare != 0 {
+			return (compare > 0) == desc
+		}
+		return matched[i].Core.Address < matched[j].Core.Address
+	})
+	return matched, nil
+}
+
+// after checks whether a server comes after a cursor in the order of the listing
+func after(core types.ServerCore, cursor types.Cursor, column types.SortColumn, desc bool) bool {
+	compare := types.SortValue(core, column, desc).Compare(cursor.Key)
+	if compare != 0 {
+		return (compare < 0) == desc
+	}
+	return core.Address > cursor.Address
+}
+
+// matchFilters checks a server against the filters, the conditions and platforms are parsed from the
+// filters once by the caller rather than for every server
+func matchFilters(server types.Server, filters []types.FilterAttribute, conditions []types.Condition, platforms []types.Platform) bool {
+	if !server.Active {
+		return false
+	}
+	for _, filter := range filters {
+		switch filter {
+		case types.FilterPassword:
+			if server.Core.Password {
+				return false
+			}
+		case types.FilterEmpty:
+			if server.Core.Players <= 0 {
+				return false
+			}
+		case types.FilterFull:
+			if server.Core.Players >= server.Core.MaxPlayers {
+				return false
+			}
+		case types.FilterSuspicious:
+			if len(server.Suspicious) > 0 {
+				return false
+			}
+		}
+	}
+	for _, condition := range conditions {
+		if !condition.Match(server) {
+			return false
+		}
+	}
+	if platforms != nil {
+		platform := types.PlatformOf(server)
+		for _, p := range platforms {
+			if p == platform {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyServer performs a deep copy of a server so callers can't mutate stored state
+func copyServer(server types.Server) types.Server {
+	if server.Rules != nil {
+		rules := make(map[string]string, len(server.Rules))
+		for k, v := range server.Rules {
+			rules[k] = v
+		}
+		server.Rules = rules
+	}
+	if server.PlayerList != nil {
+		list := *server.PlayerList
+		list.Players = append([]types.Player(nil), list.Players...)
+		server.PlayerList = &list
+	}
+	if server.Suspicious != nil {
+		server.Suspicious = append([]types.Suspicion(nil), server.Suspicious...)
+	}
+	if server.Regions != nil {
+		regions := make(map[string]types.RegionStatus, len(server.Regions))
+		for k, v := range server.Regions {
+			regions[k] = v
+		}
+		server.Regions = regions
+	}
+	if server.Extra != nil {
+		extra := make(map[string]string, len(server.Extra))
+		for k, v := range server.Extra {
+			extra[k] = v
+		}
+		server.Extra = extra
+	}
+	return server
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestMemory_ArchiveServer(t *testing.T) {
+	mem := NewMemory()
+	assert.NoError(t, mem.UpsertServer(types.Server{Core: types.ServerCore{Address: "a.example.com", Players: 3}}))
+	assert.NoError(t, mem.UpsertServer(types.Server{Core: types.ServerCore{Address: "b.example.com", Players: 5}}))
+
+	assert.NoError(t, mem.ArchiveServer("a.example.com"))
+	assert.Error(t, mem.ArchiveServer("c.example.com"))
+
+	_, found, err := mem.GetServer("a.example.com")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	active, _ := mem.GetActiveServers()
+	inactive, _ := mem.GetInactiveServers()
+	assert.Equal(t, 1, active)
+	assert.Equal(t, 1, inactive)
+
+	servers, err := mem.GetServers(0, 0, "", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.ServerCore{{Address: "b.example.com", Players: 5}}, servers)
+
+	addresses, err := mem.LoadAllAddresses()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a.example.com", "b.example.com"}, addresses)
+
+	assert.NoError(t, mem.RemoveServer("a.example.com"))
+	assert.Error(t, mem.RemoveServer("a.example.com"))
+}
+
+func TestMemory_UpsertServerCopiesRules(t *testing.T) {
+	mem := NewMemory()
+	rules := map[string]string{"mapname": "San Andreas"}
+	assert.NoError(t, mem.UpsertServer(types.Server{Core: types.ServerCore{Address: "a.example.com"}, Rules: rules}))
+
+	rules["mapname"] = "Los Santos"
+
+	server, found, err := mem.GetServer("a.example.com")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "San Andreas", server.Rules["mapname"])
+}
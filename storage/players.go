@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// playerKey normalises a player name for case-insensitive lookups
+func playerKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// sortSightings orders sightings with current ones first then by most recently seen
+func sortSightings(sightings []types.PlayerSighting) {
+	sort.Slice(sightings, func(i, j int) bool {
+		if sightings[i].Online != sightings[j].Online {
+			return sightings[i].Online
+		}
+		if !sightings[i].LastSeen.Equal(sightings[j].LastSeen) {
+			return sightings[i].LastSeen.After(sightings[j].LastSeen)
+		}
+		return sightings[i].Address < sightings[j].Address
+	})
+}
+
+// -
+// MongoDB
+// -
+
+type playerDocument struct {
+	Key      string    `bson:"key"`
+	Name     string    `bson:"name"`
+	Address  string    `bson:"address"`
+	LastSeen time.Time `bson:"last_seen"`
+	Online   bool      `bson:"online"`
+}
+
+// UpsertPlayers records the current player list of a server. Each name is marked as online on the
+// server and any name that was previously online there but is absent from the list is marked as
+// offline, keeping its last seen time.
+func (mgr *Manager) UpsertPlayers(address string, names []string, at time.Time) (err error) {
+	keys := []string{}
+	for _, name := range names {
+		key := playerKey(name)
+		keys = append(keys, key)
+
+		_, err = mgr.players.Upsert(
+			bson.M{"key": key, "address": address},
+			bson.M{"$set": bson.M{"name": name, "last_seen": at, "online": true}})
+		if err != nil {
+			return errors.Wrap(err, "failed to upsert player")
+		}
+	}
+
+	_, err = mgr.players.UpdateAll(
+		bson.M{"address": address, "online": true, "key": bson.M{"$nin": keys}},
+		bson.M{"$set": bson.M{"online": false}})
+	if err != nil {
+		return errors.Wrap(err, "failed to mark players offline")
+	}
+	return
+}
+
+// SearchPlayers returns every server a player name has been seen on
+func (mgr *Manager) SearchPlayers(name string) (sightings []types.PlayerSighting, err error) {
+	docs := []playerDocument{}
+	err = mgr.players.Find(bson.M{"key": playerKey(name)}).All(&docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find players")
+	}
+	for _, doc := range docs {
+		sightings = append(sightings, types.PlayerSighting{
+			Name:     doc.Name,
+			Address:  doc.Address,
+			LastSeen: doc.LastSeen.UTC(),
+			Online:   doc.Online,
+		})
+	}
+	sortSightings(sightings)
+	return
+}
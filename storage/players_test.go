@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestSearchPlayers(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		t1 := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+		t2 := t1.Add(time.Minute)
+
+		assert.NoError(t, store.UpsertPlayers("s2.example.com", []string{"Southclaws", "Y_Less"}, t1))
+		assert.NoError(t, store.UpsertPlayers("s3.example.com", []string{"southclaws"}, t1))
+		assert.NoError(t, store.UpsertPlayers("s2.example.com", []string{"Y_Less"}, t2))
+		assert.NoError(t, store.UpsertPlayers("s4.example.com", []string{"SouthClaws"}, t2))
+
+		gotSightings, err := store.SearchPlayers("SOUTHCLAWS")
+		assert.NoError(t, err)
+		assert.Equal(t, []types.PlayerSighting{
+			{Name: "SouthClaws", Address: "s4.example.com", LastSeen: t2, Online: true},
+			{Name: "southclaws", Address: "s3.example.com", LastSeen: t1, Online: true},
+			{Name: "Southclaws", Address: "s2.example.com", LastSeen: t1, Online: false},
+		}, gotSightings)
+
+		assert.NoError(t, store.UpsertPlayers("s2.example.com", nil, t2))
+		gotSightings, err = store.SearchPlayers("y_less")
+		assert.NoError(t, err)
+		assert.Equal(t, []types.PlayerSighting{
+			{Name: "Y_Less", Address: "s2.example.com", LastSeen: t2, Online: false},
+		}, gotSightings)
+
+		gotSightings, err = store.SearchPlayers("nobody")
+		assert.NoError(t, err)
+		assert.Empty(t, gotSightings)
+	})
+}
@@ -0,0 +1,1100 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+// PostgresConfig describes PostgreSQL connection information
+type PostgresConfig struct {
+	PostgresHost    string `split_words:"true" required:"true"`
+	PostgresPort    string `split_words:"true" required:"true"`
+	PostgresName    string `split_words:"true" required:"true"`
+	PostgresUser    string `split_words:"true" required:"true"`
+	PostgresPass    string `split_words:"true" required:"false"`
+	PostgresSSLMode string `split_words:"true" required:"false"`
+}
+
+// Postgres is a storage backend that keeps servers and their rules in PostgreSQL tables.
+type Postgres struct {
+	config PostgresConfig
+	db     *sql.DB
+}
+
+var _ Store = &Postgres{}
+
+// NewPostgres connects to PostgreSQL and applies any outstanding schema migrations
+func NewPostgres(config PostgresConfig) (pg *Postgres, err error) {
+	pg = &Postgres{
+		config: config,
+	}
+
+	pg.db, err = sql.Open("postgres", postgresDSN(config))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open postgres connection")
+	}
+
+	err = pg.db.Ping()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to postgres")
+	}
+
+	err = migrate(pg.db)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to migrate postgres schema")
+	}
+
+	return
+}
+
+// postgresDSN builds a libpq key/value connection string from the config
+func postgresDSN(config PostgresConfig) string {
+	sslmode := config.PostgresSSLMode
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%s dbname=%s user=%s sslmode=%s",
+		dsnValue(config.PostgresHost),
+		dsnValue(config.PostgresPort),
+		dsnValue(config.PostgresName),
+		dsnValue(config.PostgresUser),
+		dsnValue(sslmode))
+	if config.PostgresPass != "" {
+		dsn += fmt.Sprintf(" password=%s", dsnValue(config.PostgresPass))
+	}
+	return dsn
+}
+
+// dsnValue quotes a connection string value so spaces, quotes and backslashes survive parsing
+func dsnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// GetServer looks up a server via the address
+func (pg *Postgres) GetServer(address string) (server types.Server, found bool, err error) {
+	server, found, err = pg.GetStoredServer(address)
+	if err != nil || !found || !server.Active {
+		return types.Server{}, false, err
+	}
+	return
+}
+
+// GetStoredServer looks up a server via the address, including archived servers
+func (pg *Postgres) GetStoredServer(address string) (server types.Server, found bool, err error) {
+	var suspicious []string
+	err = pg.db.QueryRow(`
+		SELECT address, ip, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime, player_list, description, banner, suspicious, platform, extra, active
+		FROM servers
+		WHERE address = $1`,
+		address,
+	).Scan(
+		&server.Core.Address,
+		&server.IP,
+		&server.Core.Hostname,
+		&server.Core.Players,
+		&server.Core.MaxPlayers,
+		&server.Core.Gamemode,
+		&server.Core.Language,
+		&server.Core.Password,
+		&server.Core.Version,
+		&server.Core.Ping,
+		&server.Core.FirstSeen,
+		&server.Core.Updated,
+		&server.Core.Uptime,
+		jsonColumn{&server.PlayerList},
+		&server.Description,
+		&server.Banner,
+		pq.Array(&suspicious),
+		&server.Platform,
+		jsonColumn{&server.Extra},
+		&server.Active,
+	)
+	if err == sql.ErrNoRows {
+		return types.Server{}, false, nil // the caller does not need to interpret this as an "error"
+	} else if err != nil {
+		return
+	}
+	server.Suspicious = toSuspicions(suspicious)
+
+	server.Rules, err = pg.getRules(address)
+	if err != nil {
+		return
+	}
+
+	server.Regions, err = pg.getRegions(address)
+	if err != nil {
+		return
+	}
+
+	return server, true, nil
+}
+
+func (pg *Postgres) getRules(address string) (rules map[string]string, err error) {
+	rows, err := pg.db.Query(`SELECT name, value FROM rules WHERE address = $1`, address)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, value string
+		if err = rows.Scan(&name, &value); err != nil {
+			return
+		}
+		if rules == nil {
+			rules = make(map[string]string)
+		}
+		rules[name] = value
+	}
+	return rules, rows.Err()
+}
+
+func (pg *Postgres) getRegions(address string) (regions map[string]types.RegionStatus, err error) {
+	rows, err := pg.db.Query(`SELECT region, reachable, ping, updated FROM regions WHERE address = $1`, address)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			region string
+			status types.RegionStatus
+		)
+		if err = rows.Scan(&region, &status.Reachable, &status.Ping, &status.Updated); err != nil {
+			return
+		}
+		status.Updated = status.Updated.UTC()
+		if regions == nil {
+			regions = make(map[string]types.RegionStatus)
+		}
+		regions[region] = status
+	}
+	return regions, rows.Err()
+}
+
+// UpsertServer creates or updates a server and its rules, implicitly sets `Active` to true. The probe
+// results are left as they are since they're only written by PutRegionStatus.
+func (pg *Postgres) UpsertServer(server types.Server) (err error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() // nolint:errcheck
+		}
+	}()
+
+	_, err = tx.Exec(`
+		INSERT INTO servers (address, ip, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime, player_list, description, banner, suspicious, platform, extra, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, TRUE)
+		ON CONFLICT (address) DO UPDATE SET
+			ip = EXCLUDED.ip,
+			hostname = EXCLUDED.hostname,
+			players = EXCLUDED.players,
+			max_players = EXCLUDED.max_players,
+			gamemode = EXCLUDED.gamemode,
+			language = EXCLUDED.language,
+			password = EXCLUDED.password,
+			version = EXCLUDED.version,
+			ping = EXCLUDED.ping,
+			first_seen = EXCLUDED.first_seen,
+			updated = EXCLUDED.updated,
+			uptime = EXCLUDED.uptime,
+			player_list = EXCLUDED.player_list,
+			description = EXCLUDED.description,
+			banner = EXCLUDED.banner,
+			suspicious = EXCLUDED.suspicious,
+			platform = EXCLUDED.platform,
+			extra = EXCLUDED.extra,
+			active = TRUE`,
+		server.Core.Address,
+		server.IP,
+		server.Core.Hostname,
+		server.Core.Players,
+		server.Core.MaxPlayers,
+		server.Core.Gamemode,
+		server.Core.Language,
+		server.Core.Password,
+		server.Core.Version,
+		server.Core.Ping,
+		server.Core.FirstSeen.UTC(),
+		server.Core.Updated.UTC(),
+		server.Core.Uptime,
+		jsonColumn{server.PlayerList},
+		server.Description,
+		server.Banner,
+		pq.Array(fromSuspicions(server.Suspicious)),
+		types.PlatformOf(server),
+		jsonColumn{server.Extra},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert server")
+	}
+
+	_, err = tx.Exec(`DELETE FROM rules WHERE address = $1`, server.Core.Address)
+	if err != nil {
+		return errors.Wrap(err, "failed to clear server rules")
+	}
+
+	for name, value := range server.Rules {
+		_, err = tx.Exec(`INSERT INTO rules (address, name, value) VALUES ($1, $2, $3)`,
+			server.Core.Address, name, value)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert server rule")
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ArchiveServer marks a server as inactive by setting the `active` column to false
+func (pg *Postgres) ArchiveServer(address string) (err error) {
+	result, err := pg.db.Exec(`UPDATE servers SET active = FALSE WHERE address = $1`, address)
+	if err != nil {
+		return
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "server '%s'", address))
+}
+
+// RemoveServer deletes a server from the database, its rules are removed by cascade
+func (pg *Postgres) RemoveServer(address string) (err error) {
+	result, err := pg.db.Exec(`DELETE FROM servers WHERE address = $1`, address)
+	if err != nil {
+		return
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "server '%s'", address))
+}
+
+// PutRegionStatus stores the result of a probe agent on an active server
+func (pg *Postgres) PutRegionStatus(address, region string, status types.RegionStatus) (found bool, err error) {
+	result, err := pg.db.Exec(`
+		INSERT INTO regions (address, region, reachable, ping, updated)
+		SELECT address, $2, $3, $4, $5 FROM servers WHERE address = $1 AND active = TRUE
+		ON CONFLICT (address, region) DO UPDATE SET
+			reachable = EXCLUDED.reachable,
+			ping = EXCLUDED.ping,
+			updated = EXCLUDED.updated`,
+		address, region, status.Reachable, status.Ping, status.Updated.UTC())
+	if err != nil {
+		return false, errors.Wrap(err, "failed to store region status")
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetServers returns a slice of Core objects
+func (pg *Postgres) GetServers(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (servers []types.ServerCore, err error) {
+	query, args, err := buildListQuery(pageNum, pageSize, sort, by, filters)
+	if err != nil {
+		return
+	}
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var core types.ServerCore
+		core, err = scanCore(rows)
+		if err != nil {
+			return
+		}
+		servers = append(servers, core)
+	}
+	return servers, rows.Err()
+}
+
+// scanCore reads a row of the coreColumns
+func scanCore(rows *sql.Rows) (core types.ServerCore, err error) {
+	err = rows.Scan(
+		&core.Address,
+		&core.Hostname,
+		&core.Players,
+		&core.MaxPlayers,
+		&core.Gamemode,
+		&core.Language,
+		&core.Password,
+		&core.Version,
+		&core.Ping,
+		&core.FirstSeen,
+		&core.Updated,
+		&core.Uptime,
+	)
+	return
+}
+
+// ListServers returns a page of servers after a cursor and the total that match the filters
+func (pg *Postgres) ListServers(page types.ServerQuery) (servers []types.ServerCore, total int, err error) {
+	query, args, count, countArgs, err := buildPageQuery(page)
+	if err != nil {
+		return
+	}
+
+	if err = pg.db.QueryRow(count, countArgs...).Scan(&total); err != nil {
+		return
+	}
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var core types.ServerCore
+		core, err = scanCore(rows)
+		if err != nil {
+			return
+		}
+		servers = append(servers, core)
+	}
+	return servers, total, rows.Err()
+}
+
+// GetActiveServers returns the number of active servers
+func (pg *Postgres) GetActiveServers() (servers int, err error) {
+	err = pg.db.QueryRow(`SELECT COUNT(*) FROM servers WHERE active = TRUE`).Scan(&servers)
+	if err != nil {
+		err = errors.Wrap(err, "failed to count active servers")
+	}
+	return
+}
+
+// GetInactiveServers returns the number of inactive servers
+func (pg *Postgres) GetInactiveServers() (servers int, err error) {
+	err = pg.db.QueryRow(`SELECT COUNT(*) FROM servers WHERE active = FALSE`).Scan(&servers)
+	if err != nil {
+		err = errors.Wrap(err, "failed to count inactive servers")
+	}
+	return
+}
+
+// GetTotalPlayers returns the number of total players
+func (pg *Postgres) GetTotalPlayers() (players int, err error) {
+	err = pg.db.QueryRow(`SELECT COALESCE(SUM(players), 0) FROM servers`).Scan(&players)
+	if err != nil {
+		err = errors.Wrap(err, "failed to sum players")
+	}
+	return
+}
+
+// LoadAllAddresses loads all addresses from the database as a slice of strings for synchronisation
+// with the QueryDaemon.
+func (pg *Postgres) LoadAllAddresses() (result []string, err error) {
+	rows, err := pg.db.Query(`SELECT address FROM servers`)
+	if err != nil {
+		err = errors.Wrap(err, "failed to load current addresses for query daemon")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address string
+		if err = rows.Scan(&address); err != nil {
+			return
+		}
+		result = append(result, address)
+	}
+	return result, rows.Err()
+}
+
+// GetAllServers returns every server including archived ones, for moderation. Rules and player
+// lists are left out to keep the result small.
+func (pg *Postgres) GetAllServers() (servers []types.Server, err error) {
+	rows, err := pg.db.Query(`
+		SELECT address, ip, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime, description, banner, suspicious, platform, extra, active
+		FROM servers
+		ORDER BY address ASC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load all servers")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			server     types.Server
+			suspicious []string
+		)
+		err = rows.Scan(
+			&server.Core.Address,
+			&server.IP,
+			&server.Core.Hostname,
+			&server.Core.Players,
+			&server.Core.MaxPlayers,
+			&server.Core.Gamemode,
+			&server.Core.Language,
+			&server.Core.Password,
+			&server.Core.Version,
+			&server.Core.Ping,
+			&server.Core.FirstSeen,
+			&server.Core.Updated,
+			&server.Core.Uptime,
+			&server.Description,
+			&server.Banner,
+			pq.Array(&suspicious),
+			&server.Platform,
+			jsonColumn{&server.Extra},
+			&server.Active,
+		)
+		if err != nil {
+			return
+		}
+		server.Suspicious = toSuspicions(suspicious)
+		servers = append(servers, server)
+	}
+	return servers, rows.Err()
+}
+
+// PutSamples creates or replaces samples in a series, samples are unique by time
+func (pg *Postgres) PutSamples(series string, resolution types.Resolution, samples []types.Sample) (err error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() // nolint:errcheck
+		}
+	}()
+
+	for _, sample := range samples {
+		_, err = tx.Exec(`
+			INSERT INTO samples (series, resolution, time, value, min, max, count)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (series, resolution, time) DO UPDATE SET
+				value = EXCLUDED.value,
+				min = EXCLUDED.min,
+				max = EXCLUDED.max,
+				count = EXCLUDED.count`,
+			series, string(resolution), sample.Time.UTC(), sample.Value, sample.Min, sample.Max, sample.Count)
+		if err != nil {
+			return errors.Wrap(err, "failed to upsert sample")
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSamples returns the samples of a series in the range [from, to) ordered by time
+func (pg *Postgres) GetSamples(series string, resolution types.Resolution, from, to time.Time) (samples []types.Sample, err error) {
+	rows, err := pg.db.Query(`
+		SELECT time, value, min, max, count
+		FROM samples
+		WHERE series = $1 AND resolution = $2 AND time >= $3 AND time < $4
+		ORDER BY time ASC`,
+		series, string(resolution), from.UTC(), to.UTC())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query samples")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sample types.Sample
+		if err = rows.Scan(&sample.Time, &sample.Value, &sample.Min, &sample.Max, &sample.Count); err != nil {
+			return
+		}
+		sample.Time = sample.Time.UTC()
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// RemoveSamples deletes the samples of a series that are older than `before`
+func (pg *Postgres) RemoveSamples(series string, resolution types.Resolution, before time.Time) (err error) {
+	_, err = pg.db.Exec(`DELETE FROM samples WHERE series = $1 AND resolution = $2 AND time < $3`,
+		series, string(resolution), before.UTC())
+	return
+}
+
+// GetSeries returns the names of every series with samples at the given resolution
+func (pg *Postgres) GetSeries(resolution types.Resolution) (series []string, err error) {
+	rows, err := pg.db.Query(`SELECT DISTINCT series FROM samples WHERE resolution = $1`, string(resolution))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query series")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return
+		}
+		series = append(series, name)
+	}
+	return series, rows.Err()
+}
+
+// UpsertPlayers records the current player list of a server, names absent from the list that were
+// previously online on the server are marked as offline.
+func (pg *Postgres) UpsertPlayers(address string, names []string, at time.Time) (err error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() // nolint:errcheck
+		}
+	}()
+
+	keys := []string{}
+	for _, name := range names {
+		key := playerKey(name)
+		keys = append(keys, key)
+
+		_, err = tx.Exec(`
+			INSERT INTO players (key, name, address, last_seen, online)
+			VALUES ($1, $2, $3, $4, TRUE)
+			ON CONFLICT (key, address) DO UPDATE SET
+				name = EXCLUDED.name,
+				last_seen = EXCLUDED.last_seen,
+				online = TRUE`,
+			key, name, address, at.UTC())
+		if err != nil {
+			return errors.Wrap(err, "failed to upsert player")
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE players SET online = FALSE WHERE address = $1 AND online AND NOT (key = ANY($2))`,
+		address, pq.Array(keys))
+	if err != nil {
+		return errors.Wrap(err, "failed to mark players offline")
+	}
+
+	return tx.Commit()
+}
+
+// SearchPlayers returns every server a player name has been seen on
+func (pg *Postgres) SearchPlayers(name string) (sightings []types.PlayerSighting, err error) {
+	rows, err := pg.db.Query(`
+		SELECT name, address, last_seen, online
+		FROM players
+		WHERE key = $1
+		ORDER BY online DESC, last_seen DESC, address ASC`,
+		playerKey(name))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query players")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sighting types.PlayerSighting
+		if err = rows.Scan(&sighting.Name, &sighting.Address, &sighting.LastSeen, &sighting.Online); err != nil {
+			return
+		}
+		sighting.LastSeen = sighting.LastSeen.UTC()
+		sightings = append(sightings, sighting)
+	}
+	return sightings, rows.Err()
+}
+
+// PutAPIKey creates or replaces an API key, keys are unique by ID
+func (pg *Postgres) PutAPIKey(key types.APIKey) (err error) {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	_, err = pg.db.Exec(`
+		INSERT INTO api_keys (id, hash, scopes, active, created)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			hash = EXCLUDED.hash,
+			scopes = EXCLUDED.scopes,
+			active = EXCLUDED.active`,
+		key.ID, key.Hash, pq.Array(scopes), key.Active, key.Created.UTC())
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert key")
+	}
+	return
+}
+
+// GetAPIKey looks up an API key via the hash of the plain text key
+func (pg *Postgres) GetAPIKey(hash string) (key types.APIKey, found bool, err error) {
+	var scopes []string
+	err = pg.db.QueryRow(`SELECT id, hash, scopes, active, created FROM api_keys WHERE hash = $1`, hash).
+		Scan(&key.ID, &key.Hash, pq.Array(&scopes), &key.Active, &key.Created)
+	if err == sql.ErrNoRows {
+		return types.APIKey{}, false, nil
+	} else if err != nil {
+		return types.APIKey{}, false, errors.Wrap(err, "failed to query key")
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, types.Scope(scope))
+	}
+	key.Created = key.Created.UTC()
+	return key, true, nil
+}
+
+// GetAPIKeys returns every key, including inactive keys for pending claims
+func (pg *Postgres) GetAPIKeys() (keys []types.APIKey, err error) {
+	rows, err := pg.db.Query(`SELECT id, hash, scopes, active, created FROM api_keys ORDER BY created ASC, id ASC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query keys")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key    types.APIKey
+			scopes []string
+		)
+		if err = rows.Scan(&key.ID, &key.Hash, pq.Array(&scopes), &key.Active, &key.Created); err != nil {
+			return
+		}
+		for _, scope := range scopes {
+			key.Scopes = append(key.Scopes, types.Scope(scope))
+		}
+		key.Created = key.Created.UTC()
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// ActivateAPIKey marks a key as active so it can be used to authenticate requests
+func (pg *Postgres) ActivateAPIKey(id string) (err error) {
+	result, err := pg.db.Exec(`UPDATE api_keys SET active = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to activate key")
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "key '%s'", id))
+}
+
+// RemoveAPIKey deletes a key, requests using it are no longer authenticated
+func (pg *Postgres) RemoveAPIKey(id string) (err error) {
+	result, err := pg.db.Exec(`DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove key")
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "key '%s'", id))
+}
+
+// PutClaim creates or replaces a claim, claims are unique by token
+func (pg *Postgres) PutClaim(claim types.Claim) (err error) {
+	_, err = pg.db.Exec(`
+		INSERT INTO claims (token, address, key_id, expires)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token) DO UPDATE SET
+			address = EXCLUDED.address,
+			key_id = EXCLUDED.key_id,
+			expires = EXCLUDED.expires`,
+		claim.Token, claim.Address, claim.KeyID, claim.Expires.UTC())
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert claim")
+	}
+	return
+}
+
+// GetClaims returns every pending claim for a server, including expired ones
+func (pg *Postgres) GetClaims(address string) (claims []types.Claim, err error) {
+	rows, err := pg.db.Query(`
+		SELECT address, token, key_id, expires
+		FROM claims
+		WHERE address = $1
+		ORDER BY expires ASC, token ASC`,
+		address)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query claims")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var claim types.Claim
+		if err = rows.Scan(&claim.Address, &claim.Token, &claim.KeyID, &claim.Expires); err != nil {
+			return
+		}
+		claim.Expires = claim.Expires.UTC()
+		claims = append(claims, claim)
+	}
+	return claims, rows.Err()
+}
+
+// GetExpiredClaims returns every claim that expired before a point in time
+func (pg *Postgres) GetExpiredClaims(before time.Time) (claims []types.Claim, err error) {
+	rows, err := pg.db.Query(`
+		SELECT address, token, key_id, expires
+		FROM claims
+		WHERE expires < $1
+		ORDER BY expires ASC, token ASC`,
+		before.UTC())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query claims")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var claim types.Claim
+		if err = rows.Scan(&claim.Address, &claim.Token, &claim.KeyID, &claim.Expires); err != nil {
+			return
+		}
+		claim.Expires = claim.Expires.UTC()
+		claims = append(claims, claim)
+	}
+	return claims, rows.Err()
+}
+
+// RemoveClaim deletes a claim once it has been verified or has expired
+func (pg *Postgres) RemoveClaim(token string) (err error) {
+	result, err := pg.db.Exec(`DELETE FROM claims WHERE token = $1`, token)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove claim")
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "claim '%s'", token))
+}
+
+// PutBan creates or replaces a ban, bans are unique by ID
+func (pg *Postgres) PutBan(ban types.Ban) (err error) {
+	_, err = pg.db.Exec(`
+		INSERT INTO bans (id, kind, value, reason, created)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			value = EXCLUDED.value,
+			reason = EXCLUDED.reason`,
+		ban.ID, string(ban.Kind), ban.Value, ban.Reason, ban.Created.UTC())
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert ban")
+	}
+	return
+}
+
+// GetBans returns every ban
+func (pg *Postgres) GetBans() (bans []types.Ban, err error) {
+	rows, err := pg.db.Query(`SELECT id, kind, value, reason, created FROM bans ORDER BY created ASC, id ASC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query bans")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ban types.Ban
+		if err = rows.Scan(&ban.ID, &ban.Kind, &ban.Value, &ban.Reason, &ban.Created); err != nil {
+			return
+		}
+		ban.Created = ban.Created.UTC()
+		bans = append(bans, ban)
+	}
+	return bans, rows.Err()
+}
+
+// RemoveBan deletes a ban
+func (pg *Postgres) RemoveBan(id string) (err error) {
+	result, err := pg.db.Exec(`DELETE FROM bans WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove ban")
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "ban '%s'", id))
+}
+
+// PutMember creates or renews the lease of a replica
+func (pg *Postgres) PutMember(member types.Member) (err error) {
+	_, err = pg.db.Exec(`
+		INSERT INTO members (id, started, expires)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET
+			expires = EXCLUDED.expires`,
+		member.ID, member.Started.UTC(), member.Expires.UTC())
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert member")
+	}
+	return
+}
+
+// GetMembers returns every replica including those with expired leases
+func (pg *Postgres) GetMembers() (members []types.Member, err error) {
+	rows, err := pg.db.Query(`SELECT id, started, expires FROM members ORDER BY id ASC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query members")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member types.Member
+		if err = rows.Scan(&member.ID, &member.Started, &member.Expires); err != nil {
+			return
+		}
+		member.Started = member.Started.UTC()
+		member.Expires = member.Expires.UTC()
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// RemoveMember deletes the lease of a replica
+func (pg *Postgres) RemoveMember(id string) (err error) {
+	result, err := pg.db.Exec(`DELETE FROM members WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove member")
+	}
+	return expectAffected(result, errors.Wrapf(ErrNotFound, "member '%s'", id))
+}
+
+// coreColumns are the columns of a ServerCore in the order scanCore reads them
+const coreColumns = "address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime"
+
+// orderExpressions are the SQL expressions for the sort columns other than ping, hostnames are compared byte by byte
+// so the order doesn't depend on the database's collation
+var orderExpressions = map[types.SortColumn]string{
+	types.ByPlayers:    "players",
+	types.ByHostname:   `lower(hostname) COLLATE "C"`,
+	types.ByMaxPlayers: "max_players",
+	types.ByFill:       "(CASE WHEN max_players > 0 THEN players::float8 / max_players ELSE 0 END)",
+	types.ByFirstSeen:  "first_seen",
+	types.ByUpdated:    "updated",
+	types.ByUptime:     "uptime",
+}
+
+// orderExpression returns the SQL expression for a validated sort column, servers without a measured
+// ping are given a key that sorts them last in the direction
+func orderExpression(column types.SortColumn, desc bool) string {
+	if column == types.ByPing {
+		return fmt.Sprintf("(CASE WHEN ping > 0 THEN ping ELSE %d END)", int(types.UnmeasuredPing(desc)))
+	}
+	return orderExpressions[column]
+}
+
+// buildListQuery generates the SQL equivalent of the MongoDB GetServers query. The filters are
+// plain column comparisons so no server-side scripting is necessary.
+func buildListQuery(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (query string, args []interface{}, err error) {
+	skip, limit, column, desc, err := listOptions(pageNum, pageSize, sort, by)
+	if err != nil {
+		return
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	order := orderExpression(column, desc)
+
+	where, args, err := listConditions(filters, []interface{}{limit, skip})
+	if err != nil {
+		return
+	}
+
+	query = fmt.Sprintf(`SELECT %s FROM servers WHERE %s ORDER BY %s %s, address ASC LIMIT $1 OFFSET $2`,
+		coreColumns,
+		strings.Join(where, " AND "),
+		order,
+		direction)
+
+	return
+}
+
+// buildPageQuery generates the SQL for ListServers, a query for the page of servers after the cursor
+// and a query that counts every server matching the filters
+func buildPageQuery(page types.ServerQuery) (query string, args []interface{}, count string, countArgs []interface{}, err error) {
+	column, desc, err := types.SortOptions(page.Sort, page.By)
+	if err != nil {
+		return
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = int(types.PageSizeDefault)
+	}
+
+	direction, compare := "ASC", ">"
+	if desc {
+		direction, compare = "DESC", "<"
+	}
+	order := orderExpression(column, desc)
+
+	where, countArgs, err := listConditions(page.Filters, nil)
+	if err != nil {
+		return
+	}
+	count = fmt.Sprintf(`SELECT COUNT(*) FROM servers WHERE %s`, strings.Join(where, " AND "))
+
+	args = append(args, countArgs...)
+	if page.After != nil {
+		args = append(args, keyValue(page.After.Key, column), page.After.Address)
+		where = append(where, fmt.Sprintf("(%s %s $%d OR (%s = $%d AND address > $%d))",
+			order, compare, len(args)-1, order, len(args)-1, len(args)))
+	}
+	args = append(args, limit)
+
+	query = fmt.Sprintf(`SELECT %s FROM servers WHERE %s ORDER BY %s %s, address ASC LIMIT $%d`,
+		coreColumns,
+		strings.Join(where, " AND "),
+		order,
+		direction,
+		len(args))
+
+	return
+}
+
+// listConditions generates the WHERE conditions for the filters, their arguments are appended to
+// args so they can follow any arguments already used by the query
+func listConditions(filters []types.FilterAttribute, args []interface{}) ([]string, []interface{}, error) {
+	where := []string{"active = TRUE"}
+	for _, filter := range filters {
+		switch filter {
+		case types.FilterPassword:
+			where = append(where, "password = FALSE")
+		case types.FilterEmpty:
+			where = append(where, "players > 0")
+		case types.FilterFull:
+			where = append(where, "players < max_players")
+		case types.FilterSuspicious:
+			where = append(where, "cardinality(suspicious) = 0")
+		}
+	}
+
+	platforms, err := types.FilterPlatforms(filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	if platforms != nil {
+		values := make([]string, len(platforms))
+		for i, platform := range platforms {
+			values[i] = string(platform)
+		}
+		args = append(args, pq.Array(values))
+		where = append(where, fmt.Sprintf("platform = ANY($%d)", len(args)))
+	}
+
+	conditions, err := types.FilterConditions(filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, condition := range conditions {
+		var sql string
+		sql, args = conditionSQL(condition, args)
+		where = append(where, sql)
+	}
+	return where, args, nil
+}
+
+// conditionColumns maps the condition fields to the columns of the servers table
+var conditionColumns = map[string]string{
+	"address":    "address",
+	"hostname":   "hostname",
+	"gamemode":   "gamemode",
+	"language":   "language",
+	"version":    "version",
+	"players":    "players",
+	"maxplayers": "max_players",
+	"ping":       "ping",
+	"password":   "password",
+}
+
+// conditionSQL generates the WHERE condition for a filter condition, conditions on rules are
+// subqueries on the rules table
+func conditionSQL(condition types.Condition, args []interface{}) (string, []interface{}) {
+	if condition.Kind != types.FieldText {
+		op := string(condition.Operator)
+		if condition.Operator == types.OpNotEqual {
+			op = "<>"
+		}
+		if condition.Kind == types.FieldBool {
+			args = append(args, condition.Bool)
+		} else {
+			args = append(args, condition.Number)
+		}
+		return fmt.Sprintf("%s %s $%d", conditionColumns[condition.Field], op, len(args)), args
+	}
+
+	if condition.Operator == types.OpContains {
+		args = append(args, "%"+likeEscaper.Replace(condition.Text)+"%")
+	} else {
+		args = append(args, condition.Text)
+	}
+	param := len(args)
+	match := func(column, op string) string {
+		if condition.Operator == types.OpContains {
+			return fmt.Sprintf("%s ILIKE $%d", column, param)
+		}
+		return fmt.Sprintf("lower(%s) %s lower($%d)", column, op, param)
+	}
+
+	if condition.Rule == "" {
+		op := "="
+		if condition.Operator == types.OpNotEqual {
+			op = "<>"
+		}
+		return match(conditionColumns[condition.Field], op), args
+	}
+
+	// a server without the rule is not equal to any value so != is the negation of =
+	exists := "EXISTS"
+	if condition.Operator == types.OpNotEqual {
+		exists = "NOT EXISTS"
+	}
+	args = append(args, condition.Rule)
+	return fmt.Sprintf("%s (SELECT 1 FROM rules WHERE rules.address = servers.address AND rules.name = $%d AND %s)",
+		exists, len(args), match("rules.value", "=")), args
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// jsonColumn stores a value as a nullable JSONB column, a nil pointer is stored as NULL
+type jsonColumn struct {
+	value interface{}
+}
+
+// Value implements driver.Valuer
+func (j jsonColumn) Value() (driver.Value, error) {
+	v := reflect.ValueOf(j.value)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, nil
+	}
+	return json.Marshal(j.value)
+}
+
+// Scan implements sql.Scanner, `value` must be a pointer to the destination
+func (j jsonColumn) Scan(src interface{}) error {
+	switch raw := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(raw, j.value)
+	case string:
+		return json.Unmarshal([]byte(raw), j.value)
+	}
+	return errors.Errorf("cannot scan %T into json column", src)
+}
+
+// expectAffected returns notFound if a statement didn't touch any rows
+func expectAffected(result sql.Result, notFound error) (err error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return
+}
+
+// fromSuspicions converts heuristics flags for storage in a TEXT[] column
+func fromSuspicions(suspicions []types.Suspicion) (result []string) {
+	result = make([]string, len(suspicions))
+	for i, suspicion := range suspicions {
+		result[i] = string(suspicion)
+	}
+	return
+}
+
+// toSuspicions converts a TEXT[] column back into heuristics flags, empty arrays become nil so
+// servers that aren't flagged omit the field
+func toSuspicions(values []string) (result []types.Suspicion) {
+	for _, value := range values {
+		result = append(result, types.Suspicion(value))
+	}
+	return
+}
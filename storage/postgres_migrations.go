@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// migrations is the ordered list of schema changes for the PostgreSQL backend. Entries must never
+// be edited or reordered once released, new changes are appended to the end.
+var migrations = []string{
+	// 1: servers and rules
+	`CREATE TABLE servers (
+		address     TEXT PRIMARY KEY,
+		ip          TEXT NOT NULL DEFAULT '',
+		hostname    TEXT NOT NULL DEFAULT '',
+		players     INTEGER NOT NULL DEFAULT 0,
+		max_players INTEGER NOT NULL DEFAULT 0,
+		gamemode    TEXT NOT NULL DEFAULT '',
+		language    TEXT NOT NULL DEFAULT '',
+		password    BOOLEAN NOT NULL DEFAULT FALSE,
+		version     TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		banner      TEXT NOT NULL DEFAULT '',
+		active      BOOLEAN NOT NULL DEFAULT TRUE
+	);
+	CREATE INDEX servers_active_players ON servers (active, players DESC, address);
+	CREATE TABLE rules (
+		address TEXT NOT NULL REFERENCES servers (address) ON DELETE CASCADE,
+		name    TEXT NOT NULL,
+		value   TEXT NOT NULL,
+		PRIMARY KEY (address, name)
+	);`,
+
+	// 2: time series samples
+	`CREATE TABLE samples (
+		series     TEXT NOT NULL,
+		resolution TEXT NOT NULL,
+		time       TIMESTAMPTZ NOT NULL,
+		value      DOUBLE PRECISION NOT NULL,
+		min        DOUBLE PRECISION NOT NULL,
+		max        DOUBLE PRECISION NOT NULL,
+		count      INTEGER NOT NULL,
+		PRIMARY KEY (series, resolution, time)
+	);
+	CREATE INDEX samples_resolution ON samples (resolution, series);`,
+
+	// 3: player lists
+	`ALTER TABLE servers ADD COLUMN player_list JSONB;`,
+
+	// 4: player name index
+	`CREATE TABLE players (
+		key       TEXT NOT NULL,
+		name      TEXT NOT NULL,
+		address   TEXT NOT NULL,
+		last_seen TIMESTAMPTZ NOT NULL,
+		online    BOOLEAN NOT NULL,
+		PRIMARY KEY (key, address)
+	);
+	CREATE INDEX players_address ON players (address) WHERE online;`,
+
+	// 5: API keys and server ownership claims
+	`CREATE TABLE api_keys (
+		id      TEXT PRIMARY KEY,
+		hash    TEXT NOT NULL UNIQUE,
+		scopes  TEXT[] NOT NULL,
+		active  BOOLEAN NOT NULL,
+		created TIMESTAMPTZ NOT NULL
+	);
+	CREATE TABLE claims (
+		token   TEXT PRIMARY KEY,
+		address TEXT NOT NULL,
+		key_id  TEXT NOT NULL REFERENCES api_keys (id) ON DELETE CASCADE,
+		expires TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX claims_address ON claims (address);`,
+
+	// 6: bans
+	`CREATE TABLE bans (
+		id      TEXT PRIMARY KEY,
+		kind    TEXT NOT NULL,
+		value   TEXT NOT NULL,
+		reason  TEXT NOT NULL DEFAULT '',
+		created TIMESTAMPTZ NOT NULL
+	);`,
+
+	// 7: heuristics flags
+	`ALTER TABLE servers ADD COLUMN suspicious TEXT[] NOT NULL DEFAULT '{}';`,
+
+	// 8: latency
+	`ALTER TABLE servers ADD COLUMN ping INTEGER NOT NULL DEFAULT 0;`,
+
+	// 9: probe agent results
+	`CREATE TABLE regions (
+		address   TEXT NOT NULL REFERENCES servers (address) ON DELETE CASCADE,
+		region    TEXT NOT NULL,
+		reachable BOOLEAN NOT NULL,
+		ping      INTEGER NOT NULL,
+		updated   TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (address, region)
+	);`,
+
+	// 10: replica membership
+	`CREATE TABLE members (
+		id      TEXT PRIMARY KEY,
+		started TIMESTAMPTZ NOT NULL,
+		expires TIMESTAMPTZ NOT NULL
+	);`,
+
+	// 11: platform detection
+	`ALTER TABLE servers ADD COLUMN platform TEXT NOT NULL DEFAULT 'samp';
+	ALTER TABLE servers ADD COLUMN extra JSONB;`,
+
+	// 12: filter conditions
+	`CREATE INDEX rules_name_value ON rules (name, lower(value));
+	CREATE INDEX servers_language ON servers (lower(language));`,
+
+	// 13: sort keys
+	`ALTER TABLE servers ADD COLUMN first_seen TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+	ALTER TABLE servers ADD COLUMN updated TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+	ALTER TABLE servers ADD COLUMN uptime DOUBLE PRECISION NOT NULL DEFAULT 0;`,
+
+	// 14: expired claim sweep
+	`CREATE INDEX claims_expires ON claims (expires);`,
+}
+
+// migrate applies each migration that has not yet been recorded in the schema_migrations table.
+// Every migration runs in its own transaction so a failure leaves the schema at a known version.
+func migrate(db *sql.DB) (err error) {
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create schema_migrations table")
+	}
+
+	var current int
+	err = db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return errors.Wrap(err, "failed to read schema version")
+	}
+
+	for i := current; i < len(migrations); i++ {
+		err = applyMigration(db, i+1, migrations[i])
+		if err != nil {
+			return errors.Wrapf(err, "failed to apply migration %d", i+1)
+		}
+	}
+
+	return
+}
+
+func applyMigration(db *sql.DB, version int, statement string) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() // nolint:errcheck
+		}
+	}()
+
+	_, err = tx.Exec(statement)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+	if err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Southclaws/samp-servers-api/types"
+)
+
+func TestBuildListQuery(t *testing.T) {
+	type args struct {
+		page   int
+		size   types.PageSize
+		sort   types.SortOrder
+		by     types.SortColumn
+		filter []types.FilterAttribute
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantQuery string
+		wantArgs  []interface{}
+		wantErr   bool
+	}{
+		{
+			"defaults",
+			args{0, 0, "", "", nil},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE ORDER BY players DESC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{5000, 0},
+			false,
+		},
+		{
+			"filters",
+			args{3, 10, "asc", "player", []types.FilterAttribute{types.FilterPassword, types.FilterEmpty, types.FilterFull}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND password = FALSE AND players > 0 AND players < max_players ORDER BY players ASC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{10, 20},
+			false,
+		},
+		{
+			"platform",
+			args{0, 0, "", "", []types.FilterAttribute{types.FilterPassword, types.FilterPlatform(types.PlatformOpenMP), types.FilterPlatform(types.PlatformSAMPDL)}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND password = FALSE AND platform = ANY($3) ORDER BY players DESC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{5000, 0, pq.Array([]string{"openmp", "samp-dl"})},
+			false,
+		},
+		{
+			"suspicious",
+			args{0, 50, "desc", "", []types.FilterAttribute{types.FilterSuspicious}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND cardinality(suspicious) = 0 ORDER BY players DESC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{50, 0},
+			false,
+		},
+		{
+			"ping",
+			args{0, 0, "", "ping", nil},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE ORDER BY (CASE WHEN ping > 0 THEN ping ELSE 2147483647 END) ASC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{5000, 0},
+			false,
+		},
+		{
+			"ping desc",
+			args{0, 0, "desc", "ping", nil},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE ORDER BY (CASE WHEN ping > 0 THEN ping ELSE -1 END) DESC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{5000, 0},
+			false,
+		},
+		{
+			"conditions",
+			args{0, 0, "", "", []types.FilterAttribute{"language=English", "gamemode~role_play", "players>=10", "password!=true", "rules.mapname!=San Andreas"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND lower(language) = lower($3) AND gamemode ILIKE $4 AND players >= $5 AND password <> $6 AND NOT EXISTS (SELECT 1 FROM rules WHERE rules.address = servers.address AND rules.name = $8 AND lower(rules.value) = lower($7)) ORDER BY players DESC, address ASC LIMIT $1 OFFSET $2`,
+			[]interface{}{5000, 0, "English", `%role\_play%`, 10, true, "San Andreas", "mapname"},
+			false,
+		},
+		{"invalid condition", args{0, 0, "", "", []types.FilterAttribute{"players~10"}}, "", nil, true},
+		{"invalid sort", args{0, 0, "sideways", "", nil}, "", nil, true},
+		{"invalid by", args{0, 0, "", "hostname; DROP TABLE servers", nil}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery, gotArgs, err := buildListQuery(tt.args.page, tt.args.size, tt.args.sort, tt.args.by, tt.args.filter)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantQuery, gotQuery)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+		})
+	}
+}
+
+func TestBuildPageQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		page          types.ServerQuery
+		wantQuery     string
+		wantArgs      []interface{}
+		wantCount     string
+		wantCountArgs []interface{}
+		wantErr       bool
+	}{
+		{
+			"first page",
+			types.ServerQuery{Limit: 100},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE ORDER BY players DESC, address ASC LIMIT $1`,
+			[]interface{}{100},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE`,
+			nil,
+			false,
+		},
+		{
+			"after cursor",
+			types.ServerQuery{Limit: 100, After: &types.Cursor{Key: types.SortKey{Number: 50}, Address: "s4.example.com"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND (players < $1 OR (players = $1 AND address > $2)) ORDER BY players DESC, address ASC LIMIT $3`,
+			[]interface{}{50.0, "s4.example.com", 100},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE`,
+			nil,
+			false,
+		},
+		{
+			"filters and ping",
+			types.ServerQuery{By: types.ByPing, Filters: []types.FilterAttribute{types.FilterEmpty, types.FilterPlatform(types.PlatformOpenMP)}, After: &types.Cursor{Key: types.SortKey{Number: 30}, Address: "s2.example.com"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND players > 0 AND platform = ANY($1) AND ((CASE WHEN ping > 0 THEN ping ELSE 2147483647 END) > $2 OR ((CASE WHEN ping > 0 THEN ping ELSE 2147483647 END) = $2 AND address > $3)) ORDER BY (CASE WHEN ping > 0 THEN ping ELSE 2147483647 END) ASC, address ASC LIMIT $4`,
+			[]interface{}{pq.Array([]string{"openmp"}), 30.0, "s2.example.com", 5000},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE AND players > 0 AND platform = ANY($1)`,
+			[]interface{}{pq.Array([]string{"openmp"})},
+			false,
+		},
+		{
+			"conditions and cursor",
+			types.ServerQuery{Limit: 10, Filters: []types.FilterAttribute{"maxplayers<500", "rules.weburl~samp"}, After: &types.Cursor{Key: types.SortKey{Number: 4}, Address: "ss.southcla.ws"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND max_players < $1 AND EXISTS (SELECT 1 FROM rules WHERE rules.address = servers.address AND rules.name = $3 AND rules.value ILIKE $2) AND (players < $4 OR (players = $4 AND address > $5)) ORDER BY players DESC, address ASC LIMIT $6`,
+			[]interface{}{500, "%samp%", "weburl", 4.0, "ss.southcla.ws", 10},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE AND max_players < $1 AND EXISTS (SELECT 1 FROM rules WHERE rules.address = servers.address AND rules.name = $3 AND rules.value ILIKE $2)`,
+			[]interface{}{500, "%samp%", "weburl"},
+			false,
+		},
+		{
+			"hostname",
+			types.ServerQuery{Limit: 10, By: types.ByHostname, After: &types.Cursor{Key: types.SortKey{Text: "test server 2"}, Address: "s2.example.com"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND (lower(hostname) COLLATE "C" > $1 OR (lower(hostname) COLLATE "C" = $1 AND address > $2)) ORDER BY lower(hostname) COLLATE "C" ASC, address ASC LIMIT $3`,
+			[]interface{}{"test server 2", "s2.example.com", 10},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE`,
+			nil,
+			false,
+		},
+		{
+			"fill",
+			types.ServerQuery{Limit: 10, By: types.ByFill, After: &types.Cursor{Key: types.SortKey{Number: 0.125}, Address: "ss.southcla.ws"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND ((CASE WHEN max_players > 0 THEN players::float8 / max_players ELSE 0 END) < $1 OR ((CASE WHEN max_players > 0 THEN players::float8 / max_players ELSE 0 END) = $1 AND address > $2)) ORDER BY (CASE WHEN max_players > 0 THEN players::float8 / max_players ELSE 0 END) DESC, address ASC LIMIT $3`,
+			[]interface{}{0.125, "ss.southcla.ws", 10},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE`,
+			nil,
+			false,
+		},
+		{
+			"updated",
+			types.ServerQuery{Limit: 10, By: types.ByUpdated, Sort: types.SortAsc, After: &types.Cursor{Key: types.SortKey{Time: time.Date(2018, 3, 10, 18, 30, 0, 0, time.UTC)}, Address: "ss.southcla.ws"}},
+			`SELECT address, hostname, players, max_players, gamemode, language, password, version, ping, first_seen, updated, uptime FROM servers WHERE active = TRUE AND (updated > $1 OR (updated = $1 AND address > $2)) ORDER BY updated ASC, address ASC LIMIT $3`,
+			[]interface{}{time.Date(2018, 3, 10, 18, 30, 0, 0, time.UTC), "ss.southcla.ws", 10},
+			`SELECT COUNT(*) FROM servers WHERE active = TRUE`,
+			nil,
+			false,
+		},
+		{"invalid by", types.ServerQuery{By: "colour"}, "", nil, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery, gotArgs, gotCount, gotCountArgs, err := buildPageQuery(tt.page)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantQuery, gotQuery)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+			assert.Equal(t, tt.wantCount, gotCount)
+			assert.Equal(t, tt.wantCountArgs, gotCountArgs)
+		})
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	config := PostgresConfig{
+		PostgresHost: "localhost",
+		PostgresPort: "5432",
+		PostgresName: "samplist",
+		PostgresUser: "postgres",
+	}
+	assert.Equal(t, `host='localhost' port='5432' dbname='samplist' user='postgres' sslmode='disable'`, postgresDSN(config))
+
+	config.PostgresPass = `it's a \secret`
+	config.PostgresSSLMode = "require"
+	assert.Equal(t, `host='localhost' port='5432' dbname='samplist' user='postgres' sslmode='require' password='it\'s a \\secret'`, postgresDSN(config))
+}
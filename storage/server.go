@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"strings"
+
+	"github.com/pkg/errors"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
@@ -9,7 +12,16 @@ import (
 
 // GetServer looks up a server via the address
 func (mgr *Manager) GetServer(address string) (server types.Server, found bool, err error) {
-	err = mgr.collection.Find(bson.M{"core.address": address, "active": true}).One(&server)
+	return mgr.getServer(bson.M{"core.address": address, "active": true})
+}
+
+// GetStoredServer looks up a server via the address, including archived servers
+func (mgr *Manager) GetStoredServer(address string) (server types.Server, found bool, err error) {
+	return mgr.getServer(bson.M{"core.address": address})
+}
+
+func (mgr *Manager) getServer(query bson.M) (server types.Server, found bool, err error) {
+	err = mgr.collection.Find(query).One(&server)
 	if err == mgo.ErrNotFound {
 		found = false
 		err = nil // the caller does not need to interpret this as an "error"
@@ -22,19 +34,84 @@ func (mgr *Manager) GetServer(address string) (server types.Server, found bool,
 	return
 }
 
-// UpsertServer creates or updates a server object in the database, implicitly sets `Active` to true
+// UpsertServer creates or updates a server object in the database, implicitly sets `Active` to true.
+// The probe results are left as they are since they're only written by PutRegionStatus.
 func (mgr *Manager) UpsertServer(server types.Server) (err error) {
 	server.Active = true
-	_, err = mgr.collection.Upsert(bson.M{"core.address": server.Core.Address}, server)
+	raw, err := bson.Marshal(server)
+	if err != nil {
+		return
+	}
+	fields := bson.M{}
+	if err = bson.Unmarshal(raw, &fields); err != nil {
+		return
+	}
+	delete(fields, "regions")
+	fields["lower"] = lowerFields(server)
+	_, err = mgr.collection.Upsert(bson.M{"core.address": server.Core.Address}, bson.M{"$set": fields})
 	return
 }
 
 // ArchiveServer marks a server as inactive by setting the `Active` field to false
 func (mgr *Manager) ArchiveServer(address string) (err error) {
-	return mgr.collection.Update(bson.M{"core.address": address}, bson.M{"$set": bson.M{"active": false}})
+	err = mgr.collection.Update(bson.M{"core.address": address}, bson.M{"$set": bson.M{"active": false}})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "server '%s'", address)
+	}
+	return
 }
 
 // RemoveServer deletes a server from the database
 func (mgr *Manager) RemoveServer(address string) (err error) {
-	return mgr.collection.Remove(bson.M{"core.address": address})
+	err = mgr.collection.Remove(bson.M{"core.address": address})
+	if err == mgo.ErrNotFound {
+		return errors.Wrapf(ErrNotFound, "server '%s'", address)
+	}
+	return
+}
+
+// PutRegionStatus stores the result of a probe agent on an active server, the region name must be
+// validated by the caller since it's used as a field name.
+func (mgr *Manager) PutRegionStatus(address, region string, status types.RegionStatus) (found bool, err error) {
+	err = mgr.collection.Update(
+		bson.M{"core.address": address, "active": true},
+		bson.M{"$set": bson.M{"regions." + region: status}})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// lowerFields returns lower cased copies of the text fields and rules of a server, conditions compare
+// these instead of matching the originals with case-insensitive regular expressions
+func lowerFields(server types.Server) bson.M {
+	rules := bson.M{}
+	for name, value := range server.Rules {
+		rules[name] = strings.ToLower(value)
+	}
+	return bson.M{
+		"address":  strings.ToLower(server.Core.Address),
+		"hostname": strings.ToLower(server.Core.Hostname),
+		"gamemode": strings.ToLower(server.Core.Gamemode),
+		"language": strings.ToLower(server.Core.Language),
+		"version":  strings.ToLower(server.Core.Version),
+		"rules":    rules,
+	}
+}
+
+// backfillLower stores the lower cased fields of servers that were stored before they existed
+func (mgr *Manager) backfillLower() (err error) {
+	iter := mgr.collection.Find(bson.M{"lower": bson.M{"$exists": false}}).Iter()
+	var server types.Server
+	for iter.Next(&server) {
+		err = mgr.collection.Update(
+			bson.M{"core.address": server.Core.Address},
+			bson.M{"$set": bson.M{"lower": lowerFields(server)}})
+		if err != nil {
+			iter.Close() // nolint:errcheck
+			return errors.Wrapf(err, "failed to backfill server '%s'", server.Core.Address)
+		}
+		server = types.Server{}
+	}
+	return iter.Close()
 }
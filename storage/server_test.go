@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -81,3 +83,292 @@ func TestManager_GetServer(t *testing.T) {
 		})
 	}
 }
+
+func TestSuspiciousFilter(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		flagged := fixtures[0]
+		flagged.Suspicious = []types.Suspicion{types.SuspicionFlatCurve, types.SuspicionSharedIP}
+		for _, server := range append([]types.Server{flagged}, fixtures[1:]...) {
+			assert.NoError(t, store.UpsertServer(server))
+		}
+
+		server, found, err := store.GetServer(flagged.Core.Address)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, flagged.Suspicious, server.Suspicious)
+
+		all, err := store.GetServers(0, 0, "", "", nil)
+		assert.NoError(t, err)
+		assert.Len(t, all, len(fixtures))
+
+		filtered, err := store.GetServers(0, 0, "", "", []types.FilterAttribute{types.FilterSuspicious})
+		assert.NoError(t, err)
+		assert.Len(t, filtered, len(fixtures)-1)
+		for _, core := range filtered {
+			assert.NotEqual(t, flagged.Core.Address, core.Address)
+		}
+	})
+}
+
+func TestPlatformFilter(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		platforms := []types.Platform{types.PlatformOpenMP, types.PlatformSAMP, "", types.PlatformSAMPDL}
+		for i, server := range fixtures {
+			server.Platform = platforms[i]
+			if server.Platform == types.PlatformOpenMP {
+				server.Extra = map[string]string{types.ExtraDiscord: "discord.gg/samplist"}
+			}
+			assert.NoError(t, store.UpsertServer(server))
+		}
+
+		server, found, err := store.GetServer(fixtures[0].Core.Address)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, types.PlatformOpenMP, server.Platform)
+		assert.Equal(t, map[string]string{types.ExtraDiscord: "discord.gg/samplist"}, server.Extra)
+
+		addresses := func(filters ...types.FilterAttribute) (result []string) {
+			servers, err := store.GetServers(0, 0, "", "", filters)
+			assert.NoError(t, err)
+			for _, core := range servers {
+				result = append(result, core.Address)
+			}
+			sort.Strings(result)
+			return
+		}
+
+		assert.Len(t, addresses(), len(fixtures))
+		assert.Equal(t, []string{fixtures[0].Core.Address}, addresses(types.FilterPlatform(types.PlatformOpenMP)))
+		// servers stored before platforms were detected count as SA:MP
+		assert.Equal(t, []string{fixtures[1].Core.Address, fixtures[2].Core.Address}, addresses(types.FilterPlatform(types.PlatformSAMP)))
+		assert.Equal(t, []string{fixtures[3].Core.Address, fixtures[0].Core.Address}, addresses(
+			types.FilterPlatform(types.PlatformOpenMP),
+			types.FilterPlatform(types.PlatformSAMPDL),
+		))
+
+		_, err = store.GetServers(0, 0, "", "", []types.FilterAttribute{types.FilterPlatform("unknown")})
+		assert.Error(t, err, "platforms that can't be detected are rejected rather than matching nothing")
+	})
+}
+
+func TestFilterConditions(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		for _, server := range fixtures {
+			assert.NoError(t, store.UpsertServer(server))
+		}
+
+		tests := []struct {
+			name    string
+			filters []types.FilterAttribute
+			want    []string
+		}{
+			{"language and players", []types.FilterAttribute{"language=english", "players>=10", "players<=1000", "version=0.3.7-R2"}, []string{"s3.example.com"}},
+			{"gamemode contains", []types.FilterAttribute{"gamemode~LARCENY"}, []string{"s2.example.com", "s3.example.com"}},
+			{"gamemode equals whole value", []types.FilterAttribute{"gamemode=grand"}, nil},
+			{"hostname not equal", []types.FilterAttribute{"hostname!=test server 2", "players>0"}, []string{"s3.example.com", "s4.example.com", "ss.southcla.ws"}},
+			{"rule contains", []types.FilterAttribute{"rules.mapname~SAN "}, []string{"s3.example.com", "ss.southcla.ws"}},
+			{"rule not equal", []types.FilterAttribute{"rules.mapname!=Los Santos"}, []string{"s3.example.com", "s4.example.com", "ss.southcla.ws"}},
+			{"missing rule", []types.FilterAttribute{"rules.weburl!=x"}, []string{"s2.example.com", "s3.example.com", "s4.example.com", "ss.southcla.ws"}},
+			{"password", []types.FilterAttribute{"password=true"}, []string{"s4.example.com"}},
+			{"with other filters", []types.FilterAttribute{types.FilterFull, "maxplayers<100"}, []string{"ss.southcla.ws"}},
+			{"wildcards are literal", []types.FilterAttribute{"hostname~%"}, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				servers, err := store.GetServers(0, 0, "", "", tt.filters)
+				assert.NoError(t, err)
+				var got []string
+				for _, core := range servers {
+					got = append(got, core.Address)
+				}
+				sort.Strings(got)
+				assert.Equal(t, tt.want, got)
+			})
+		}
+
+		_, err := store.GetServers(0, 0, "", "", []types.FilterAttribute{"players~10"})
+		assert.Error(t, err)
+		_, _, err = store.ListServers(types.ServerQuery{Filters: []types.FilterAttribute{"colour=red"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestPingSort(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		pings := []int{120, 30, 0, 30}
+		for i, server := range fixtures {
+			server.Core.Ping = pings[i]
+			assert.NoError(t, store.UpsertServer(server))
+		}
+
+		addresses := func(sort types.SortOrder) (result []string) {
+			servers, err := store.GetServers(0, 0, sort, types.ByPing, nil)
+			assert.NoError(t, err)
+			for _, core := range servers {
+				result = append(result, core.Address)
+			}
+			return
+		}
+
+		// ascending by default, ties are broken by address and the unmeasured server is last in
+		// either direction rather than ranking as the lowest latency
+		assert.Equal(t, []string{"s2.example.com", "s4.example.com", "ss.southcla.ws", "s3.example.com"}, addresses(""))
+		assert.Equal(t, []string{"ss.southcla.ws", "s2.example.com", "s4.example.com", "s3.example.com"}, addresses(types.SortDesc))
+	})
+}
+
+func TestSortColumns(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		day := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+		firstSeen := []time.Time{day.AddDate(-1, 0, 0), day, day.AddDate(-2, 0, 0), day}
+		uptimes := []float64{99, 50, 99, 75}
+		for i, server := range fixtures {
+			server.Core.FirstSeen = firstSeen[i]
+			server.Core.Updated = day.Add(time.Duration(3-i) * time.Hour)
+			server.Core.Uptime = uptimes[i]
+			assert.NoError(t, store.UpsertServer(server))
+		}
+
+		tests := []struct {
+			by   types.SortColumn
+			sort types.SortOrder
+			want []string
+		}{
+			{types.ByHostname, "", []string{"ss.southcla.ws", "s2.example.com", "s3.example.com", "s4.example.com"}},
+			{types.ByHostname, types.SortDesc, []string{"s4.example.com", "s3.example.com", "s2.example.com", "ss.southcla.ws"}},
+			{types.ByMaxPlayers, "", []string{"s3.example.com", "s2.example.com", "s4.example.com", "ss.southcla.ws"}},
+			{types.ByFill, "", []string{"s4.example.com", "s3.example.com", "ss.southcla.ws", "s2.example.com"}},
+			{types.ByFirstSeen, "", []string{"s2.example.com", "s4.example.com", "ss.southcla.ws", "s3.example.com"}},
+			{types.ByFirstSeen, types.SortAsc, []string{"s3.example.com", "ss.southcla.ws", "s2.example.com", "s4.example.com"}},
+			{types.ByUpdated, "", []string{"ss.southcla.ws", "s2.example.com", "s3.example.com", "s4.example.com"}},
+			{types.ByUptime, "", []string{"s3.example.com", "ss.southcla.ws", "s4.example.com", "s2.example.com"}},
+		}
+		for _, tt := range tests {
+			t.Run(string(tt.by)+" "+string(tt.sort), func(t *testing.T) {
+				servers, err := store.GetServers(0, 0, tt.sort, tt.by, nil)
+				assert.NoError(t, err)
+				var got []string
+				for _, core := range servers {
+					got = append(got, core.Address)
+				}
+				assert.Equal(t, tt.want, got)
+
+				// one server per page visits them in the same order
+				got = nil
+				query := types.ServerQuery{Limit: 1, Sort: tt.sort, By: tt.by}
+				column, desc, err := types.SortOptions(tt.sort, tt.by)
+				assert.NoError(t, err)
+				for page := 0; page < len(fixtures)+1; page++ {
+					servers, _, err = store.ListServers(query)
+					assert.NoError(t, err)
+					if len(servers) == 0 {
+						break
+					}
+					got = append(got, servers[0].Address)
+					query.After = &types.Cursor{Key: types.SortValue(servers[0], column, desc), Address: servers[0].Address}
+				}
+				assert.Equal(t, tt.want, got)
+			})
+		}
+	})
+}
+
+func TestListServers(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		pings := []int{120, 30, 0, 30}
+		for i, server := range fixtures {
+			server.Core.Ping = pings[i]
+			assert.NoError(t, store.UpsertServer(server))
+		}
+
+		// pages continue after the cursor, including past servers tied with it
+		var got []string
+		query := types.ServerQuery{Limit: 2, By: types.ByPing}
+		for page := 0; page < 3; page++ {
+			servers, total, err := store.ListServers(query)
+			assert.NoError(t, err)
+			assert.Equal(t, 4, total)
+			if len(servers) == 0 {
+				break
+			}
+			for _, core := range servers {
+				got = append(got, core.Address)
+			}
+			last := servers[len(servers)-1]
+			query.After = &types.Cursor{Key: types.SortValue(last, types.ByPing, false), Address: last.Address}
+		}
+		assert.Equal(t, []string{"s2.example.com", "s4.example.com", "ss.southcla.ws", "s3.example.com"}, got)
+
+		servers, total, err := store.ListServers(types.ServerQuery{
+			Filters: []types.FilterAttribute{types.FilterEmpty},
+			After:   &types.Cursor{Key: types.SortKey{Number: 948}, Address: "s3.example.com"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total, "the total ignores the cursor")
+		if assert.Len(t, servers, 2) {
+			assert.Equal(t, "s4.example.com", servers[0].Address)
+			assert.Equal(t, "ss.southcla.ws", servers[1].Address)
+		}
+
+		_, _, err = store.ListServers(types.ServerQuery{By: "colour"})
+		assert.Error(t, err)
+	})
+}
+
+func TestPutRegionStatus(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		for _, server := range fixtures {
+			assert.NoError(t, store.UpsertServer(server))
+		}
+		assert.NoError(t, store.ArchiveServer(fixtures[1].Core.Address))
+
+		status := types.RegionStatus{}.Example()
+		found, err := store.PutRegionStatus(fixtures[0].Core.Address, "eu-west", status)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		found, err = store.PutRegionStatus(fixtures[0].Core.Address, "us-east", types.RegionStatus{Updated: status.Updated})
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		found, err = store.PutRegionStatus(fixtures[1].Core.Address, "eu-west", status)
+		assert.NoError(t, err)
+		assert.False(t, found)
+		found, err = store.PutRegionStatus("unknown.example.com", "eu-west", status)
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		// updates from the scraper or the owner don't replace the probe results
+		updated := fixtures[0]
+		updated.Regions = map[string]types.RegionStatus{"eu-west": {}}
+		assert.NoError(t, store.UpsertServer(updated))
+
+		server, found, err := store.GetServer(fixtures[0].Core.Address)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, map[string]types.RegionStatus{
+			"eu-west": status,
+			"us-east": {Updated: status.Updated},
+		}, server.Regions)
+	})
+}
+
+func TestGetStoredServer(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		assert.NoError(t, store.UpsertServer(fixtures[0]))
+		assert.NoError(t, store.ArchiveServer(fixtures[0].Core.Address))
+
+		_, found, err := store.GetServer(fixtures[0].Core.Address)
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		server, found, err := store.GetStoredServer(fixtures[0].Core.Address)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.False(t, server.Active)
+		assert.Equal(t, fixtures[0].Core, server.Core)
+
+		_, found, err = store.GetStoredServer("missing.example.com")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+}
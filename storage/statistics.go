@@ -1,10 +1,90 @@
 package storage
 
 import (
+	"math"
+	"time"
+
 	"github.com/pkg/errors"
 	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Southclaws/samp-servers-api/types"
 )
 
+const (
+	seriesServers          = "stats:servers"
+	seriesPlayers          = "stats:players"
+	seriesPlayersPerServer = "stats:players_per_server"
+)
+
+// GetStatistics builds a snapshot of the index statistics from any store
+func GetStatistics(store Store) (stats types.Statistics, err error) {
+	stats.Servers, err = store.GetActiveServers()
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to get servers")
+	}
+	stats.Players, err = store.GetTotalPlayers()
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to get players")
+	}
+
+	if stats.Servers > 0 {
+		stats.PlayersPerServer = float32(stats.Players) / float32(stats.Servers)
+	}
+	return
+}
+
+// RecordStatistics stores a snapshot of the index statistics as raw samples
+func RecordStatistics(store Store, stats types.Statistics, at time.Time) (err error) {
+	for series, value := range map[string]float64{
+		seriesServers:          float64(stats.Servers),
+		seriesPlayers:          float64(stats.Players),
+		seriesPlayersPerServer: float64(stats.PlayersPerServer),
+	} {
+		err = RecordSample(store, series, at, value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to record %s", series)
+		}
+	}
+	return
+}
+
+// GetStatisticsHistory returns the history of the index statistics with the same range and
+// resolution rules as GetHistory.
+func GetStatisticsHistory(store Store, from, to time.Time, resolution types.Resolution) (samples []types.StatisticsSample, err error) {
+	servers, err := GetHistory(store, seriesServers, from, to, resolution)
+	if err != nil {
+		return
+	}
+	players, err := GetHistory(store, seriesPlayers, from, to, resolution)
+	if err != nil {
+		return
+	}
+	perServer, err := GetHistory(store, seriesPlayersPerServer, from, to, resolution)
+	if err != nil {
+		return
+	}
+
+	// the three series are always recorded together so they share timestamps
+	samples = make([]types.StatisticsSample, len(servers))
+	index := make(map[time.Time]int, len(servers))
+	for i, sample := range servers {
+		samples[i].Time = sample.Time
+		samples[i].Servers = int(math.Round(sample.Value))
+		index[sample.Time] = i
+	}
+	for _, sample := range players {
+		if i, ok := index[sample.Time]; ok {
+			samples[i].Players = int(math.Round(sample.Value))
+		}
+	}
+	for _, sample := range perServer {
+		if i, ok := index[sample.Time]; ok {
+			samples[i].PlayersPerServer = float32(sample.Value)
+		}
+	}
+	return
+}
+
 // GetActiveServers returns the number of active servers
 func (mgr *Manager) GetActiveServers() (servers int, err error) {
 	servers, err = mgr.collection.Find(bson.M{"active": true}).Count()
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -12,7 +13,7 @@ func TestManager_GetStatistics(t *testing.T) {
 	wantStatistics := types.Statistics{
 		Servers:          4,
 		Players:          1002,
-		PlayersPerServer: 250,
+		PlayersPerServer: 250.5,
 	}
 
 	gotStatistics := types.Statistics{}
@@ -22,7 +23,7 @@ func TestManager_GetStatistics(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	gotStatistics.Players, err = mgr.GetActiveServers()
+	gotStatistics.Players, err = mgr.GetTotalPlayers()
 	if err != nil {
 		t.Error(err)
 	}
@@ -30,3 +31,19 @@ func TestManager_GetStatistics(t *testing.T) {
 
 	assert.Equal(t, wantStatistics, gotStatistics)
 }
+
+func TestGetStatisticsHistory(t *testing.T) {
+	forEachEmbedded(t, func(t *testing.T, store Store) {
+		at := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.NoError(t, RecordStatistics(store, types.Statistics{Servers: 10, Players: 100, PlayersPerServer: 10}, at))
+		assert.NoError(t, RecordStatistics(store, types.Statistics{Servers: 12, Players: 120, PlayersPerServer: 10}, at.Add(30*time.Minute)))
+		assert.NoError(t, RecordStatistics(store, types.Statistics{Servers: 20, Players: 100, PlayersPerServer: 5}, at.Add(time.Hour)))
+
+		gotSamples, err := GetStatisticsHistory(store, at, at.Add(2*time.Hour), types.ResolutionHour)
+		assert.NoError(t, err)
+		assert.Equal(t, []types.StatisticsSample{
+			{Time: at, Statistics: types.Statistics{Servers: 11, Players: 110, PlayersPerServer: 10}},
+			{Time: at.Add(time.Hour), Statistics: types.Statistics{Servers: 20, Players: 100, PlayersPerServer: 5}},
+		}, gotSamples)
+	})
+}
@@ -2,11 +2,64 @@ package storage
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/mgo.v2"
+
+	"github.com/Southclaws/samp-servers-api/types"
 )
 
+// Store describes a storage backend for the server index. Each backend must provide the same
+// sorting, filtering and pagination semantics so they can be used interchangeably.
+type Store interface {
+	GetServer(address string) (server types.Server, found bool, err error)
+	GetStoredServer(address string) (server types.Server, found bool, err error)
+	UpsertServer(server types.Server) (err error)
+	ArchiveServer(address string) (err error)
+	RemoveServer(address string) (err error)
+	GetServers(pageNum int, pageSize types.PageSize, sort types.SortOrder, by types.SortColumn, filters []types.FilterAttribute) (servers []types.ServerCore, err error)
+	ListServers(query types.ServerQuery) (servers []types.ServerCore, total int, err error)
+	GetActiveServers() (servers int, err error)
+	GetInactiveServers() (servers int, err error)
+	GetTotalPlayers() (players int, err error)
+	LoadAllAddresses() (result []string, err error)
+	GetAllServers() (servers []types.Server, err error)
+	PutRegionStatus(address, region string, status types.RegionStatus) (found bool, err error)
+
+	PutSamples(series string, resolution types.Resolution, samples []types.Sample) (err error)
+	GetSamples(series string, resolution types.Resolution, from, to time.Time) (samples []types.Sample, err error)
+	RemoveSamples(series string, resolution types.Resolution, before time.Time) (err error)
+	GetSeries(resolution types.Resolution) (series []string, err error)
+
+	UpsertPlayers(address string, names []string, at time.Time) (err error)
+	SearchPlayers(name string) (sightings []types.PlayerSighting, err error)
+
+	PutAPIKey(key types.APIKey) (err error)
+	GetAPIKey(hash string) (key types.APIKey, found bool, err error)
+	GetAPIKeys() (keys []types.APIKey, err error)
+	ActivateAPIKey(id string) (err error)
+	RemoveAPIKey(id string) (err error)
+
+	PutClaim(claim types.Claim) (err error)
+	GetClaims(address string) (claims []types.Claim, err error)
+	GetExpiredClaims(before time.Time) (claims []types.Claim, err error)
+	RemoveClaim(token string) (err error)
+
+	PutBan(ban types.Ban) (err error)
+	GetBans() (bans []types.Ban, err error)
+	RemoveBan(id string) (err error)
+
+	PutMember(member types.Member) (err error)
+	GetMembers() (members []types.Member, err error)
+	RemoveMember(id string) (err error)
+}
+
+var _ Store = &Manager{}
+
+// ErrNotFound is the cause of errors for things that don't exist, check for it with errors.Cause
+var ErrNotFound = errors.New("not found")
+
 // Config describes db connection information
 type Config struct {
 	MongoHost       string `split_words:"true" required:"true"`
@@ -17,12 +70,18 @@ type Config struct {
 	MongoCollection string `split_words:"true" required:"true"`
 }
 
-// Manager provides access to collections and predefined CRUD functionality.
+// Manager provides access to collections and predefined CRUD functionality backed by MongoDB.
 type Manager struct {
 	config     Config
 	session    *mgo.Session
 	db         *mgo.Database
 	collection *mgo.Collection
+	history    *mgo.Collection
+	players    *mgo.Collection
+	keys       *mgo.Collection
+	claims     *mgo.Collection
+	bans       *mgo.Collection
+	members    *mgo.Collection
 }
 
 // New sets up a MongoDB connection and ensures it is ready to use
@@ -58,5 +117,91 @@ func New(config Config) (mgr *Manager, err error) {
 		return nil, errors.Wrap(err, "index ensure failed")
 	}
 
+	for _, key := range []string{"lower.hostname", "lower.gamemode", "lower.language", "lower.version"} {
+		err = mgr.collection.EnsureIndexKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "index ensure failed")
+		}
+	}
+
+	err = mgr.backfillLower()
+	if err != nil {
+		return nil, err
+	}
+
+	mgr.history = mgr.session.DB(config.MongoName).C(config.MongoCollection + "_history")
+
+	err = mgr.history.EnsureIndex(mgo.Index{
+		Key:    []string{"series", "resolution", "time"},
+		Unique: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "history index ensure failed")
+	}
+
+	mgr.players = mgr.session.DB(config.MongoName).C(config.MongoCollection + "_players")
+
+	err = mgr.players.EnsureIndex(mgo.Index{
+		Key:    []string{"key", "address"},
+		Unique: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "players index ensure failed")
+	}
+
+	err = mgr.players.EnsureIndexKey("address", "online")
+	if err != nil {
+		return nil, errors.Wrap(err, "players index ensure failed")
+	}
+
+	mgr.keys = mgr.session.DB(config.MongoName).C(config.MongoCollection + "_keys")
+
+	for _, key := range []string{"hash", "id"} {
+		err = mgr.keys.EnsureIndex(mgo.Index{
+			Key:    []string{key},
+			Unique: true,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "keys index ensure failed")
+		}
+	}
+
+	mgr.claims = mgr.session.DB(config.MongoName).C(config.MongoCollection + "_claims")
+
+	err = mgr.claims.EnsureIndex(mgo.Index{
+		Key:    []string{"token"},
+		Unique: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "claims index ensure failed")
+	}
+
+	for _, key := range []string{"address", "expires"} {
+		err = mgr.claims.EnsureIndexKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "claims index ensure failed")
+		}
+	}
+
+	mgr.bans = mgr.session.DB(config.MongoName).C(config.MongoCollection + "_bans")
+
+	err = mgr.bans.EnsureIndex(mgo.Index{
+		Key:    []string{"id"},
+		Unique: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "bans index ensure failed")
+	}
+
+	mgr.members = mgr.session.DB(config.MongoName).C(config.MongoCollection + "_members")
+
+	err = mgr.members.EnsureIndex(mgo.Index{
+		Key:    []string{"id"},
+		Unique: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "members index ensure failed")
+	}
+
 	return
 }
@@ -0,0 +1,37 @@
+package types
+
+// ServerStatus is the moderation view of a server, it includes archived servers and how many
+// queries in a row have failed.
+type ServerStatus struct {
+	Core     ServerCore `json:"core"`
+	IP       string     `json:"ip"`
+	Active   bool       `json:"active"`
+	Failures int        `json:"failures"`
+	Failing  bool       `json:"failing"`
+}
+
+// Example returns an example of ServerStatus
+func (ss ServerStatus) Example() ServerStatus {
+	return ServerStatus{
+		Core:     Server{}.Example().Core,
+		IP:       "198.251.83.150",
+		Active:   false,
+		Failures: 3,
+		Failing:  true,
+	}
+}
+
+// ServerMeta contains the fields of a server that aren't provided by the query API, fields left
+// out of a request are not changed.
+type ServerMeta struct {
+	Description *string `json:"description,omitempty"`
+	Banner      *string `json:"banner,omitempty"`
+}
+
+// Example returns an example of ServerMeta
+func (sm ServerMeta) Example() ServerMeta {
+	description := "Scavenge and Survive is a PvP SA:MP server."
+	return ServerMeta{
+		Description: &description,
+	}
+}
@@ -0,0 +1,264 @@
+package types
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scope represents a permission granted to an API key
+type Scope string
+
+// ScopeServerWritePrefix is the prefix of scopes that allow editing a single server
+const ScopeServerWritePrefix = "server:write:"
+
+// ScopeProbePrefix is the prefix of scopes that allow a probe agent to report for a single region
+const ScopeProbePrefix = "probe:"
+
+const (
+	// ScopeAdmin grants access to every route including the admin routes
+	ScopeAdmin Scope = "admin"
+	// ScopeRead grants access to the public routes when the API is configured to require a key
+	ScopeRead Scope = "read"
+	// ScopeWrite grants adding servers to the index
+	ScopeWrite Scope = "write"
+	// ScopeProbe is used on the routes of probe agents, the handler is responsible for checking the
+	// key grants ScopeProbeRegion for the region named in the request.
+	ScopeProbe Scope = ScopeProbePrefix + "{region}"
+	// ScopeServerOwner is used on routes that edit the server named in the request, the handler is
+	// responsible for checking the key grants ScopeServerWrite for that particular server.
+	ScopeServerOwner Scope = ScopeServerWritePrefix + "{address}"
+)
+
+// Validate checks a scope is one that can be granted to a key
+func (s Scope) Validate() error {
+	switch {
+	case s == ScopeAdmin, s == ScopeRead, s == ScopeWrite:
+		return nil
+	case strings.HasPrefix(string(s), ScopeProbePrefix):
+		return ValidateRegion(strings.TrimPrefix(string(s), ScopeProbePrefix))
+	case strings.HasPrefix(string(s), ScopeServerWritePrefix):
+		address := strings.TrimPrefix(string(s), ScopeServerWritePrefix)
+		normalised, errs := AddressFromString(address)
+		if errs != nil {
+			return errs[0]
+		}
+		if normalised != address {
+			return errors.Errorf("scope address '%s' must be written as '%s'", address, normalised)
+		}
+		return nil
+	}
+	return errors.Errorf("unknown scope '%s'", s)
+}
+
+// ScopeServerWrite returns the scope that allows editing the server with the given address
+func ScopeServerWrite(address string) Scope {
+	return Scope(ScopeServerWritePrefix + address)
+}
+
+// ScopeProbeRegion returns the scope that allows probing from the given region
+func ScopeProbeRegion(region string) Scope {
+	return Scope(ScopeProbePrefix + region)
+}
+
+// APIKey represents a key that grants a set of scopes. The key itself is only ever shown once
+// when it's created, only a hash of it is stored.
+type APIKey struct {
+	ID      string    `json:"id"`
+	Hash    string    `json:"-"`
+	Scopes  []Scope   `json:"scopes"`
+	Active  bool      `json:"active"`
+	Created time.Time `json:"created"`
+}
+
+// Has checks if the key grants a scope
+func (key APIKey) Has(scope Scope) bool {
+	for _, s := range key.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows checks if the key grants access to a route that requires a scope. Admin keys are allowed
+// everything, a key scoped to any server is allowed to use the server owner routes and a key scoped
+// to any region is allowed to use the probe routes.
+func (key APIKey) Allows(scope Scope) bool {
+	if key.Has(ScopeAdmin) || key.Has(scope) {
+		return true
+	}
+	switch scope {
+	case ScopeServerOwner:
+		return key.hasPrefix(ScopeServerWritePrefix)
+	case ScopeProbe:
+		return key.hasPrefix(ScopeProbePrefix)
+	}
+	return false
+}
+
+func (key APIKey) hasPrefix(prefix string) bool {
+	for _, s := range key.Scopes {
+		if strings.HasPrefix(string(s), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedAPIKey is returned when a key is created, it's the only time the plain text key is visible
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Example returns an example of CreatedAPIKey
+func (ck CreatedAPIKey) Example() CreatedAPIKey {
+	return CreatedAPIKey{
+		APIKey: APIKey{
+			ID:      "a3f09c1d27be4e10",
+			Scopes:  []Scope{ScopeRead, ScopeServerWrite("127.0.0.1:7777")},
+			Active:  true,
+			Created: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		Key: "9b0c6f8f0e6a4d2c8a1b3e5f7d9c1a2b4c6e8f0a1b3c5d7e9f0a2b4c6d8e0f1a",
+	}
+}
+
+// KeyFromRequest returns the plain text API key of a request from either the
+// `Authorization: Bearer` or the `X-API-Key` header.
+func KeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+type contextKey int
+
+const apiKeyContextKey contextKey = iota
+
+// WithAPIKey returns a copy of the context carrying the key a request was authenticated with
+func WithAPIKey(ctx context.Context, key APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// APIKeyFromContext returns the key a request was authenticated with, if any
+func APIKeyFromContext(ctx context.Context) (key APIKey, ok bool) {
+	key, ok = ctx.Value(apiKeyContextKey).(APIKey)
+	return
+}
+
+// NewAPIKey generates a random key with the given scopes and returns the plain text key along with
+// the APIKey object to be stored.
+func NewAPIKey(active bool, scopes ...Scope) (plain string, key APIKey, err error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return
+	}
+	plain, err = randomHex(32)
+	if err != nil {
+		return
+	}
+
+	key = APIKey{
+		ID:      id,
+		Hash:    HashAPIKey(plain),
+		Scopes:  scopes,
+		Active:  active,
+		Created: time.Now().UTC().Truncate(time.Second),
+	}
+	return
+}
+
+// HashAPIKey returns the hash of a plain text key that is used to look it up in storage. Keys are
+// long random strings so a single round of SHA-256 is enough.
+func HashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClaimLifetime is how long a server owner has to set the claim token before it expires
+const ClaimLifetime = 24 * time.Hour
+
+// Claim represents a pending request to verify ownership of a server. The owner proves they control
+// the server by setting the token as the value of any server rule (such as `weburl`) and once the
+// scraper sees it, the API key created alongside the claim is activated.
+type Claim struct {
+	Address string    `json:"address"`
+	Token   string    `json:"token"`
+	KeyID   string    `json:"key_id"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewClaim generates a claim with a random token for a server, made at now
+func NewClaim(address, keyID string, now time.Time) (claim Claim, err error) {
+	token, err := randomHex(8)
+	if err != nil {
+		return
+	}
+	return Claim{
+		Address: address,
+		Token:   "samplist-" + token,
+		KeyID:   keyID,
+		Expires: now.UTC().Add(ClaimLifetime).Truncate(time.Second),
+	}, nil
+}
+
+// ClaimResponse is returned when a claim is created, it's the only time the key is visible
+type ClaimResponse struct {
+	Claim
+	Key string `json:"key"`
+}
+
+// Example returns an example of ClaimResponse
+func (cr ClaimResponse) Example() ClaimResponse {
+	return ClaimResponse{
+		Claim: Claim{
+			Address: "127.0.0.1:7777",
+			Token:   "samplist-6f1c2a9be03d7745",
+			KeyID:   "a3f09c1d27be4e10",
+			Expires: time.Date(2018, 1, 2, 12, 0, 0, 0, time.UTC),
+		},
+		Key: "9b0c6f8f0e6a4d2c8a1b3e5f7d9c1a2b4c6e8f0a1b3c5d7e9f0a2b4c6d8e0f1a",
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate random bytes")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// KeyCreateParams is the body accepted when creating a key
+type KeyCreateParams struct {
+	Scopes []Scope `json:"scopes"`
+}
+
+// Validate checks the requested scopes can be granted
+func (kp KeyCreateParams) Validate() (errs []error) {
+	if len(kp.Scopes) == 0 {
+		errs = append(errs, errors.New("at least one scope is required"))
+	}
+	for _, scope := range kp.Scopes {
+		if err := scope.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// Example returns an example of KeyCreateParams
+func (kp KeyCreateParams) Example() KeyCreateParams {
+	return KeyCreateParams{
+		Scopes: []Scope{ScopeRead, ScopeServerWrite("127.0.0.1:7777")},
+	}
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   Scope
+		wantErr bool
+	}{
+		{"admin", ScopeAdmin, false},
+		{"read", ScopeRead, false},
+		{"write", ScopeWrite, false},
+		{"probe", ScopeProbeRegion("eu-west"), false},
+		{"probe.invalid region", ScopeProbeRegion("EU"), true},
+		{"probe placeholder", ScopeProbe, true},
+		{"probe.bare", "probe", true},
+		{"server", ScopeServerWrite("192.168.1.2:7777"), false},
+		{"server.unnormalised", ScopeServerWrite("192.168.1.2"), true},
+		{"server.empty", ScopeServerWrite(""), true},
+		{"owner placeholder", ScopeServerOwner, true},
+		{"unknown", "delete", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scope.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAPIKey_Allows(t *testing.T) {
+	server := APIKey{Scopes: []Scope{ScopeServerWrite("192.168.1.2:7777")}}
+	admin := APIKey{Scopes: []Scope{ScopeAdmin}}
+	read := APIKey{Scopes: []Scope{ScopeRead}}
+	probe := APIKey{Scopes: []Scope{ScopeProbeRegion("eu-west")}}
+
+	tests := []struct {
+		name  string
+		key   APIKey
+		scope Scope
+		want  bool
+	}{
+		{"server.own", server, ScopeServerWrite("192.168.1.2:7777"), true},
+		{"server.other", server, ScopeServerWrite("192.168.1.3:7777"), false},
+		{"server.owner route", server, ScopeServerOwner, true},
+		{"server.admin", server, ScopeAdmin, false},
+		{"admin.server", admin, ScopeServerWrite("192.168.1.3:7777"), true},
+		{"admin.read", admin, ScopeRead, true},
+		{"read.read", read, ScopeRead, true},
+		{"read.owner route", read, ScopeServerOwner, false},
+		{"read.write", read, ScopeWrite, false},
+		{"admin.write", admin, ScopeWrite, true},
+		{"probe.route", probe, ScopeProbe, true},
+		{"probe.own", probe, ScopeProbeRegion("eu-west"), true},
+		{"probe.other", probe, ScopeProbeRegion("us-east"), false},
+		{"read.probe route", read, ScopeProbe, false},
+		{"admin.probe", admin, ScopeProbeRegion("us-east"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.key.Allows(tt.scope))
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BanKind describes how a ban is matched against a server
+type BanKind string
+
+const (
+	// BanAddress matches a single server address exactly
+	BanAddress BanKind = "address"
+	// BanCIDR matches every server with an IP inside a range, a bare IP is treated as a single host
+	BanCIDR BanKind = "cidr"
+	// BanHostname matches servers with a hostname, ignoring case and surrounding whitespace
+	BanHostname BanKind = "hostname"
+	// BanRegex matches servers with a hostname matching a regular expression
+	BanRegex BanKind = "regex"
+)
+
+// Ban prevents a server or a range of servers from being listed
+type Ban struct {
+	ID      string    `json:"id"`
+	Kind    BanKind   `json:"kind"`
+	Value   string    `json:"value"`
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+}
+
+// NewBan creates a ban with a random ID, the value is validated and normalised for the kind
+func NewBan(kind BanKind, value, reason string) (ban Ban, errs []error) {
+	ban = Ban{
+		Kind:    kind,
+		Value:   strings.TrimSpace(value),
+		Reason:  reason,
+		Created: time.Now().UTC().Truncate(time.Second),
+	}
+
+	switch kind {
+	case BanAddress:
+		normalised, addrErrs := AddressFromString(ban.Value)
+		if addrErrs != nil {
+			errs = append(errs, addrErrs...)
+		}
+		ban.Value = normalised
+	case BanCIDR:
+		network, err := parseCIDR(ban.Value)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			ban.Value = network.String()
+		}
+	case BanHostname:
+		if ban.Value == "" {
+			errs = append(errs, errors.New("hostname is empty"))
+		}
+	case BanRegex:
+		if _, err := regexp.Compile(ban.Value); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid hostname pattern"))
+		}
+	default:
+		errs = append(errs, errors.Errorf("unknown ban kind '%s'", kind))
+	}
+	if errs != nil {
+		return
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		errs = append(errs, err)
+		return
+	}
+	ban.ID = id
+	return
+}
+
+// BanMatcher checks if a server with the given address, resolved IP and hostname is banned. The IP
+// and hostname may be empty if they aren't known yet, such as when an address is first submitted.
+type BanMatcher func(address, ip, hostname string) bool
+
+// Matcher prepares a ban for matching against many servers
+func (ban Ban) Matcher() (matcher BanMatcher, err error) {
+	switch ban.Kind {
+	case BanAddress:
+		return func(address, ip, hostname string) bool {
+			normalised, errs := AddressFromString(address)
+			return errs == nil && normalised == ban.Value
+		}, nil
+	case BanCIDR:
+		network, err := parseCIDR(ban.Value)
+		if err != nil {
+			return nil, err
+		}
+		return func(address, ip, hostname string) bool {
+			if host, _, err := net.SplitHostPort(address); err == nil {
+				address = host
+			}
+			for _, candidate := range []string{ip, address} {
+				if parsed := net.ParseIP(candidate); parsed != nil && network.Contains(parsed) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case BanHostname:
+		value := strings.ToLower(ban.Value)
+		return func(address, ip, hostname string) bool {
+			return hostname != "" && strings.ToLower(strings.TrimSpace(hostname)) == value
+		}, nil
+	case BanRegex:
+		pattern, err := regexp.Compile(ban.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid hostname pattern")
+		}
+		return func(address, ip, hostname string) bool {
+			return hostname != "" && pattern.MatchString(hostname)
+		}, nil
+	}
+	return nil, errors.Errorf("unknown ban kind '%s'", ban.Kind)
+}
+
+// Matches checks if a ban applies to a server, see BanMatcher
+func (ban Ban) Matches(address, ip, hostname string) bool {
+	matcher, err := ban.Matcher()
+	return err == nil && matcher(address, ip, hostname)
+}
+
+// BannedError is returned when a server that matches a ban is submitted
+type BannedError struct {
+	Ban Ban
+}
+
+func (e BannedError) Error() string {
+	message := fmt.Sprintf("server is banned from the index by %s ban '%s'", e.Ban.Kind, e.Ban.Value)
+	if e.Ban.Reason != "" {
+		message += ": " + e.Ban.Reason
+	}
+	return message
+}
+
+// Example returns an example of Ban
+func (ban Ban) Example() Ban {
+	return Ban{
+		ID:      "5d0e3b7a9c214f68",
+		Kind:    BanCIDR,
+		Value:   "192.168.1.0/24",
+		Reason:  "fake players",
+		Created: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// BanParams is the body accepted when creating a ban
+type BanParams struct {
+	Kind   BanKind `json:"kind"`
+	Value  string  `json:"value"`
+	Reason string  `json:"reason"`
+}
+
+// Example returns an example of BanParams
+func (bp BanParams) Example() BanParams {
+	return BanParams{
+		Kind:   BanCIDR,
+		Value:  "192.168.1.0/24",
+		Reason: "fake players",
+	}
+}
+
+func parseCIDR(value string) (network *net.IPNet, err error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, errors.Errorf("invalid IP '%s'", value)
+		}
+		if ip.To4() != nil {
+			value += "/32"
+		} else {
+			value += "/128"
+		}
+	}
+	_, network, err = net.ParseCIDR(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid range '%s'", value)
+	}
+	return
+}
+
+// BanResult is returned when a ban is created along with the servers it removed from the index
+type BanResult struct {
+	Ban
+	Removed []string `json:"removed"`
+}
+
+// Example returns an example of BanResult
+func (br BanResult) Example() BanResult {
+	return BanResult{
+		Ban:     Ban{}.Example(),
+		Removed: []string{"192.168.1.2:7777", "192.168.1.3:7777"},
+	}
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBan(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      BanKind
+		value     string
+		wantValue string
+		wantErr   bool
+	}{
+		{"address", BanAddress, "192.168.1.2", "192.168.1.2:7777", false},
+		{"address.port", BanAddress, "192.168.1.2:8888", "192.168.1.2:8888", false},
+		{"cidr", BanCIDR, "192.168.1.7/24", "192.168.1.0/24", false},
+		{"cidr.ip", BanCIDR, "192.168.1.2", "192.168.1.2/32", false},
+		{"cidr.ipv6", BanCIDR, "2001:db8::1", "2001:db8::1/128", false},
+		{"hostname", BanHostname, " Free Money ", "Free Money", false},
+		{"regex", BanRegex, "(?i)free.*money", "(?i)free.*money", false},
+		{"invalid.cidr", BanCIDR, "example.com", "", true},
+		{"invalid.hostname", BanHostname, " ", "", true},
+		{"invalid.regex", BanRegex, "free(", "", true},
+		{"invalid.kind", "server", "example.com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ban, errs := NewBan(tt.kind, tt.value, "")
+			if tt.wantErr {
+				assert.NotEmpty(t, errs)
+				return
+			}
+			assert.Empty(t, errs)
+			assert.NotEmpty(t, ban.ID)
+			assert.Equal(t, tt.wantValue, ban.Value)
+		})
+	}
+}
+
+func TestBan_Matches(t *testing.T) {
+	address := Ban{Kind: BanAddress, Value: "192.168.1.2:7777"}
+	cidr := Ban{Kind: BanCIDR, Value: "192.168.1.0/24"}
+	hostname := Ban{Kind: BanHostname, Value: "Free Money"}
+	regex := Ban{Kind: BanRegex, Value: "(?i)free.*money"}
+
+	tests := []struct {
+		name     string
+		ban      Ban
+		address  string
+		ip       string
+		hostname string
+		want     bool
+	}{
+		{"address", address, "192.168.1.2:7777", "", "", true},
+		{"address.default port", address, "192.168.1.2", "", "", true},
+		{"address.other port", address, "192.168.1.2:7778", "", "", false},
+		{"cidr.address", cidr, "192.168.1.50:7777", "", "", true},
+		{"cidr.resolved", cidr, "s1.example.com:7777", "192.168.1.50", "", true},
+		{"cidr.outside", cidr, "192.168.2.50:7777", "192.168.2.50", "", false},
+		{"cidr.unresolved", cidr, "s1.example.com:7777", "", "", false},
+		{"hostname", hostname, "s1.example.com:7777", "", "free money", true},
+		{"hostname.unknown", hostname, "s1.example.com:7777", "", "", false},
+		{"hostname.partial", hostname, "s1.example.com:7777", "", "Free Money RP", false},
+		{"regex", regex, "s1.example.com:7777", "", "[RP] FREE MONEY every hour", true},
+		{"regex.other", regex, "s1.example.com:7777", "", "Scavenge and Survive", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.ban.Matches(tt.address, tt.ip, tt.hostname))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package types
+
+import "time"
+
+// Member is an API replica taking part in scraping, each replica holds a lease that it renews with
+// a heartbeat and is considered dead once the lease expires.
+type Member struct {
+	ID      string    `json:"id"`
+	Started time.Time `json:"started"`
+	Expires time.Time `json:"expires"`
+}
+
+// Example returns an example of Member
+func (m Member) Example() Member {
+	return Member{
+		ID:      "api-1-3f9a2c",
+		Started: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+		Expires: time.Date(2018, 1, 1, 12, 0, 30, 0, time.UTC),
+	}
+}
@@ -4,16 +4,39 @@ import "time"
 
 // Config stores app global configuration
 type Config struct {
-	Version         string
-	Bind            string        `split_words:"true" required:"true"`
-	MongoHost       string        `split_words:"true" required:"true"`
-	MongoPort       string        `split_words:"true" required:"true"`
-	MongoName       string        `split_words:"true" required:"true"`
-	MongoUser       string        `split_words:"true" required:"true"`
-	MongoPass       string        `split_words:"true" required:"false"`
-	MongoCollection string        `split_words:"true" required:"true"`
-	QueryInterval   time.Duration `split_words:"true" required:"true"`
-	MaxFailedQuery  int           `split_words:"true" required:"true"`
-	VerifyByHost    bool          `split_words:"true" required:"true"`
-	LegacyList      bool          `split_words:"true" required:"true"`
+	Version          string
+	Bind             string            `split_words:"true" required:"true"`
+	Storage          string            `split_words:"true" default:"mongo"`
+	MongoHost        string            `split_words:"true" required:"false"`
+	MongoPort        string            `split_words:"true" required:"false"`
+	MongoName        string            `split_words:"true" required:"false"`
+	MongoUser        string            `split_words:"true" required:"false"`
+	MongoPass        string            `split_words:"true" required:"false"`
+	MongoCollection  string            `split_words:"true" required:"false"`
+	PostgresHost     string            `split_words:"true" required:"false"`
+	PostgresPort     string            `split_words:"true" required:"false"`
+	PostgresName     string            `split_words:"true" required:"false"`
+	PostgresUser     string            `split_words:"true" required:"false"`
+	PostgresPass     string            `split_words:"true" required:"false"`
+	PostgresSSLMode  string            `split_words:"true" required:"false"`
+	BoltPath         string            `split_words:"true" default:"samplist.db"`
+	QueryInterval    time.Duration     `split_words:"true" required:"true"`
+	QueryMinInterval time.Duration     `split_words:"true" required:"false"`
+	QueryMaxInterval time.Duration     `split_words:"true" required:"false"`
+	QueryBudget      float64           `split_words:"true" required:"false"`
+	QueryTimeout     time.Duration     `split_words:"true" default:"10s"`
+	QuerySockets     int               `split_words:"true" default:"4"`
+	QueryPacketRate  float64           `split_words:"true" default:"1000"`
+	QueryHostRate    float64           `split_words:"true" default:"20"`
+	MaxFailedQuery   int               `split_words:"true" required:"true"`
+	QueryPlayers     bool              `split_words:"true" required:"false"`
+	VerifyByHost     bool              `split_words:"true" required:"true"`
+	TrustedProxies   Networks          `split_words:"true" required:"false"`
+	AdminKey         string            `split_words:"true" required:"false"`
+	RequireReadKey   bool              `split_words:"true" required:"false"`
+	LegacyList       bool              `split_words:"true" required:"true"`
+	Masterlists      MasterlistSources `split_words:"true" required:"false"`
+	Cluster          bool              `split_words:"true" required:"false"`
+	ClusterID        string            `split_words:"true" required:"false"`
+	ClusterLease     time.Duration     `split_words:"true" default:"30s"`
 }
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Cursor is the position of the last server of a page. The next page continues after it so servers
+// aren't skipped or repeated when the index changes between requests, as they are with offsets.
+type Cursor struct {
+	Key     SortKey `json:"v"` // value of the sort column
+	Address string  `json:"a"`
+	Query   string  `json:"q,omitempty"` // identifies the sort and filters the cursor was made for
+}
+
+// Encode returns the cursor as an opaque URL safe string
+func (cursor Cursor) Encode() string {
+	encoded, _ := json.Marshal(cursor) // nolint:errcheck
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor reads a cursor made by Encode
+func DecodeCursor(value string) (cursor Cursor, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errors.New("malformed cursor")
+	}
+	if err = json.Unmarshal(decoded, &cursor); err != nil || cursor.Address == "" {
+		return cursor, errors.New("malformed cursor")
+	}
+	return cursor, nil
+}
+
+// MarshalJSON leaves out the time unless it's set to keep cursors for other columns short
+func (k SortKey) MarshalJSON() ([]byte, error) {
+	type key SortKey
+	if k.Time.IsZero() {
+		return json.Marshal(struct {
+			Number float64 `json:"n,omitempty"`
+			Text   string  `json:"s,omitempty"`
+		}{k.Number, k.Text})
+	}
+	return json.Marshal(key(k))
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	for _, key := range []SortKey{{Number: 32}, {Number: 0.75}, {Text: "los santos"}, {Time: time.Date(2018, 3, 10, 18, 30, 0, 123456789, time.UTC)}} {
+		cursor := Cursor{Key: key, Address: "127.0.0.1:7777", Query: "abc"}
+		got, err := DecodeCursor(cursor.Encode())
+		assert.NoError(t, err)
+		assert.Equal(t, cursor, got)
+	}
+
+	for _, value := range []string{"", "!!!", Cursor{Key: SortKey{Number: 1}}.Encode(), "bm90IGpzb24"} {
+		_, err := DecodeCursor(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/dyninc/qstring"
+)
+
+// Envelope wraps every successful v3 response so metadata can be added without changing the data
+type Envelope struct {
+	Data interface{} `json:"data"`
+	Meta Meta        `json:"meta"`
+}
+
+// Meta describes a v3 response
+type Meta struct {
+	Total     *int      `json:"total,omitempty"` // servers that match a listing across every page
+	Next      string    `json:"next,omitempty"`  // cursor for the next page of a listing, empty on the last page
+	Generated time.Time `json:"generated"`
+}
+
+// Example returns an example of Meta for a listing
+func (m Meta) Example() Meta {
+	total := 1000
+	return Meta{
+		Total:     &total,
+		Next:      Cursor{Key: SortKey{Number: 32}, Address: "127.0.0.1:7777", Query: "9f86d081884c7d65"}.Encode(),
+		Generated: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// Problem is an RFC 7807 error response, served as application/problem+json
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// ServerPageParams represents the URL query parameters for cursor paginated server listing
+type ServerPageParams struct {
+	Limit   int
+	Cursor  string
+	Sort    SortOrder
+	By      SortColumn
+	Filters []FilterAttribute
+}
+
+// Example returns an example of ServerPageParams in url.Values format
+func (spp ServerPageParams) Example() (result url.Values) {
+	// nolint
+	result, err := qstring.Marshal(&ServerPageParams{
+		Limit:   100,
+		Cursor:  Meta{}.Example().Next,
+		By:      ByPlayers,
+		Filters: []FilterAttribute{FilterFull, FilterPassword},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return
+}
@@ -0,0 +1,224 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Operator compares a field of a server with the value of a condition
+type Operator string
+
+const (
+	// OpEqual matches text case-insensitively, numbers and booleans exactly
+	OpEqual Operator = "="
+	// OpNotEqual is the opposite of OpEqual, rules a server doesn't have are not equal to anything
+	OpNotEqual Operator = "!="
+	// OpContains matches text that contains the value case-insensitively
+	OpContains Operator = "~"
+	// OpGreater matches numbers greater than the value
+	OpGreater Operator = ">"
+	// OpGreaterEqual matches numbers greater than or equal to the value
+	OpGreaterEqual Operator = ">="
+	// OpLess matches numbers less than the value
+	OpLess Operator = "<"
+	// OpLessEqual matches numbers less than or equal to the value
+	OpLessEqual Operator = "<="
+)
+
+// operators is ordered so the two character operators are matched before their first character
+var operators = []Operator{OpNotEqual, OpGreaterEqual, OpLessEqual, OpEqual, OpContains, OpGreater, OpLess}
+
+// FieldKind is the type of a field that conditions can be written for
+type FieldKind int
+
+const (
+	// FieldText fields support =, != and ~
+	FieldText FieldKind = iota
+	// FieldNumber fields support =, !=, >, >=, < and <=
+	FieldNumber
+	// FieldBool fields support = and != with true or false
+	FieldBool
+)
+
+// FilterFields are the fields of ServerCore that conditions can be written for. Rules are written as
+// `rules.<name>` and are text.
+var FilterFields = map[string]FieldKind{
+	"address":    FieldText,
+	"hostname":   FieldText,
+	"gamemode":   FieldText,
+	"language":   FieldText,
+	"version":    FieldText,
+	"players":    FieldNumber,
+	"maxplayers": FieldNumber,
+	"ping":       FieldNumber,
+	"password":   FieldBool,
+}
+
+// filterRulePrefix is the prefix of conditions on a rule
+const filterRulePrefix = "rules."
+
+// Condition is a filter that compares a field of a server with a value. It's written as the field,
+// an operator then the value, such as `language=English`, `players>=10` or `rules.mapname~andreas`.
+// Unlike the other filters, which exclude servers, only servers matching every condition are kept.
+type Condition struct {
+	Field    string // a key of FilterFields, or the full `rules.<name>` for a rule
+	Rule     string // name of the rule if the condition is on a rule
+	Kind     FieldKind
+	Operator Operator
+	Text     string
+	Number   int
+	Bool     bool
+}
+
+// Condition parses a filter written as a condition, ok is false for the other kinds of filter
+func (filter FilterAttribute) Condition() (condition Condition, ok bool, err error) {
+	s := string(filter)
+	i := strings.IndexAny(s, "=!~<>")
+	if i < 0 {
+		return condition, false, nil
+	}
+	condition.Field = s[:i]
+
+	for _, op := range operators {
+		if strings.HasPrefix(s[i:], string(op)) {
+			condition.Operator = op
+			break
+		}
+	}
+	if condition.Operator == "" {
+		return condition, true, errors.Errorf("filter '%s' has no valid operator", s)
+	}
+	value := s[i+len(condition.Operator):]
+	if value == "" {
+		return condition, true, errors.Errorf("filter '%s' has no value", s)
+	}
+
+	if strings.HasPrefix(condition.Field, filterRulePrefix) {
+		condition.Rule = strings.TrimPrefix(condition.Field, filterRulePrefix)
+		if !validRuleName(condition.Rule) {
+			return condition, true, errors.Errorf("filter '%s' has an invalid rule name", s)
+		}
+		condition.Kind = FieldText
+	} else {
+		kind, exists := FilterFields[condition.Field]
+		if !exists {
+			return condition, true, errors.Errorf("filter '%s' is on an unknown field '%s'", s, condition.Field)
+		}
+		condition.Kind = kind
+	}
+
+	switch condition.Kind {
+	case FieldText:
+		if condition.Operator != OpEqual && condition.Operator != OpNotEqual && condition.Operator != OpContains {
+			return condition, true, errors.Errorf("filter '%s' uses '%s' on text", s, condition.Operator)
+		}
+		condition.Text = value
+	case FieldNumber:
+		if condition.Operator == OpContains {
+			return condition, true, errors.Errorf("filter '%s' uses '~' on a number", s)
+		}
+		if condition.Number, err = strconv.Atoi(value); err != nil {
+			return condition, true, errors.Errorf("filter '%s' value is not a number", s)
+		}
+	case FieldBool:
+		if condition.Operator != OpEqual && condition.Operator != OpNotEqual {
+			return condition, true, errors.Errorf("filter '%s' uses '%s' on true or false", s, condition.Operator)
+		}
+		if condition.Bool, err = strconv.ParseBool(value); err != nil {
+			return condition, true, errors.Errorf("filter '%s' value is not true or false", s)
+		}
+	}
+	return condition, true, nil
+}
+
+// FilterConditions returns the conditions from a list of filters, an error is returned if any of
+// them are malformed so it can be reported instead of silently ignored
+func FilterConditions(filters []FilterAttribute) (conditions []Condition, err error) {
+	for _, filter := range filters {
+		condition, ok, errCondition := filter.Condition()
+		if errCondition != nil {
+			return nil, errCondition
+		}
+		if ok {
+			conditions = append(conditions, condition)
+		}
+	}
+	return
+}
+
+// validRuleName checks a rule name is safe to use as part of a document path in a query
+func validRuleName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Match checks a server against the condition
+func (c Condition) Match(server Server) bool {
+	switch c.Kind {
+	case FieldNumber:
+		value := c.number(server.Core)
+		switch c.Operator {
+		case OpEqual:
+			return value == c.Number
+		case OpNotEqual:
+			return value != c.Number
+		case OpGreater:
+			return value > c.Number
+		case OpGreaterEqual:
+			return value >= c.Number
+		case OpLess:
+			return value < c.Number
+		case OpLessEqual:
+			return value <= c.Number
+		}
+	case FieldBool:
+		return (server.Core.Password == c.Bool) == (c.Operator == OpEqual)
+	case FieldText:
+		value := strings.ToLower(c.text(server))
+		switch c.Operator {
+		case OpEqual:
+			return value == strings.ToLower(c.Text)
+		case OpNotEqual:
+			return value != strings.ToLower(c.Text)
+		case OpContains:
+			return strings.Contains(value, strings.ToLower(c.Text))
+		}
+	}
+	return false
+}
+
+func (c Condition) number(core ServerCore) int {
+	switch c.Field {
+	case "maxplayers":
+		return core.MaxPlayers
+	case "ping":
+		return core.Ping
+	}
+	return core.Players
+}
+
+func (c Condition) text(server Server) string {
+	if c.Rule != "" {
+		return server.Rules[c.Rule]
+	}
+	switch c.Field {
+	case "address":
+		return server.Core.Address
+	case "hostname":
+		return server.Core.Hostname
+	case "gamemode":
+		return server.Core.Gamemode
+	case "language":
+		return server.Core.Language
+	}
+	return server.Core.Version
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterAttribute_Condition(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  FilterAttribute
+		want    Condition
+		wantOk  bool
+		wantErr bool
+	}{
+		{"not a condition", FilterPassword, Condition{}, false, false},
+		{"platform", FilterPlatform(PlatformOpenMP), Condition{}, false, false},
+		{"text", "language=English", Condition{Field: "language", Kind: FieldText, Operator: OpEqual, Text: "English"}, true, false},
+		{"value with operators", "hostname~a=b", Condition{Field: "hostname", Kind: FieldText, Operator: OpContains, Text: "a=b"}, true, false},
+		{"number", "players>=10", Condition{Field: "players", Kind: FieldNumber, Operator: OpGreaterEqual, Number: 10}, true, false},
+		{"not equal", "maxplayers!=50", Condition{Field: "maxplayers", Kind: FieldNumber, Operator: OpNotEqual, Number: 50}, true, false},
+		{"bool", "password=false", Condition{Field: "password", Kind: FieldBool, Operator: OpEqual}, true, false},
+		{"rule", "rules.mapname=San Andreas", Condition{Field: "rules.mapname", Rule: "mapname", Kind: FieldText, Operator: OpEqual, Text: "San Andreas"}, true, false},
+		{"unknown field", "colour=red", Condition{}, true, true},
+		{"no value", "language=", Condition{}, true, true},
+		{"no operator", "language!English", Condition{}, true, true},
+		{"range on text", "language>English", Condition{}, true, true},
+		{"contains on number", "players~1", Condition{}, true, true},
+		{"not a number", "players>ten", Condition{}, true, true},
+		{"not a bool", "password=maybe", Condition{}, true, true},
+		{"invalid rule", "rules.$where=1", Condition{}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := tt.filter.Condition()
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCondition_Match(t *testing.T) {
+	server := Server{
+		Core:  ServerCore{Hostname: "[EN] Los Santos Roleplay", Players: 120, MaxPlayers: 500, Language: "English", Version: "0.3.7-R2"},
+		Rules: map[string]string{"mapname": "San Andreas"},
+	}
+	tests := []struct {
+		filter FilterAttribute
+		want   bool
+	}{
+		{"language=english", true},
+		{"language!=English", false},
+		{"hostname~roleplay", true},
+		{"hostname~freeroam", false},
+		{"version=0.3.7-R2", true},
+		{"players>=10", true},
+		{"players<=100", false},
+		{"players>120", false},
+		{"maxplayers=500", true},
+		{"password=false", true},
+		{"password!=false", false},
+		{"rules.mapname=san andreas", true},
+		{"rules.mapname~andreas", true},
+		{"rules.weburl=x", false},
+		{"rules.weburl!=x", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.filter), func(t *testing.T) {
+			condition, ok, err := tt.filter.Condition()
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, condition.Match(server))
+		})
+	}
+}
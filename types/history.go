@@ -0,0 +1,89 @@
+package types
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/dyninc/qstring"
+	"github.com/pkg/errors"
+)
+
+// Resolution represents the granularity of a time series
+type Resolution string
+
+// ResolutionAuto picks a resolution based on the length of the requested range
+const ResolutionAuto Resolution = ""
+
+// ResolutionRaw is every sample as it was recorded, these are kept for one day
+const ResolutionRaw Resolution = "raw"
+
+// ResolutionHour is one sample per hour, these are kept for 30 days
+const ResolutionHour Resolution = "hour"
+
+// ResolutionDay is one sample per day, these are kept forever
+const ResolutionDay Resolution = "day"
+
+// Resolutions lists each resolution from finest to coarsest
+var Resolutions = []Resolution{ResolutionRaw, ResolutionHour, ResolutionDay}
+
+// Validate checks the resolution is a known value
+func (r Resolution) Validate() error {
+	switch r {
+	case ResolutionAuto, ResolutionRaw, ResolutionHour, ResolutionDay:
+		return nil
+	}
+	return errors.Errorf("invalid 'resolution' argument '%s'", r)
+}
+
+// Bucket returns the start of the period that a point in time falls into at this resolution
+func (r Resolution) Bucket(t time.Time) time.Time {
+	switch r {
+	case ResolutionHour:
+		return t.UTC().Truncate(time.Hour)
+	case ResolutionDay:
+		return t.UTC().Truncate(24 * time.Hour)
+	}
+	return t.UTC()
+}
+
+// Sample is a single point in a time series. Downsampled points hold the mean of the points that
+// were rolled up into them along with the range and the number of original points.
+type Sample struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"n"`
+}
+
+// Example returns an example of Sample
+func (s Sample) Example() Sample {
+	return Sample{
+		Time:  time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+		Value: 28.5,
+		Min:   24,
+		Max:   32,
+		Count: 30,
+	}
+}
+
+// HistoryParams represents the URL query parameters for time series endpoints
+type HistoryParams struct {
+	From       time.Time
+	To         time.Time
+	Resolution Resolution
+}
+
+// Example returns an example of HistoryParams in url.Values format
+func (hp HistoryParams) Example() (result url.Values) {
+	// nolint
+	result, err := qstring.Marshal(&HistoryParams{
+		From:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC),
+		Resolution: ResolutionHour,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return
+}
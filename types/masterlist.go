@@ -0,0 +1,120 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MasterlistFormat is how the addresses in a masterlist are laid out
+type MasterlistFormat string
+
+const (
+	// MasterlistText is one address per line, such as lists.sa-mp.com/0.3.7/servers
+	MasterlistText MasterlistFormat = "text"
+	// MasterlistHosted is a JSON array of address strings, as used for the hosted tab
+	MasterlistHosted MasterlistFormat = "hosted"
+	// MasterlistOpenMP is the open.mp list, a JSON array of servers with the address in "ip"
+	MasterlistOpenMP MasterlistFormat = "openmp"
+	// MasterlistJSON is any other JSON list of servers, `Path` leads to the array and `Field` is
+	// the key of the address in each server
+	MasterlistJSON MasterlistFormat = "json"
+)
+
+// MasterlistSource is a list of server addresses that's periodically imported into the index
+type MasterlistSource struct {
+	Name      string           `json:"name"`
+	URL       string           `json:"url"`
+	Format    MasterlistFormat `json:"format"`
+	Interval  time.Duration    `json:"-"`         // how often the list is fetched, defaults to an hour
+	Tolerance float64          `json:"tolerance"` // fraction of entries that can be invalid before the whole list is rejected
+	Path      string           `json:"path"`      // dot separated keys of the array in a `json` list
+	Field     string           `json:"field"`     // key of the address in each server of a `json` list
+}
+
+// DefaultMasterlistTolerance is the fraction of invalid entries accepted when a source doesn't set one
+const DefaultMasterlistTolerance = 0.1
+
+// DefaultMasterlists is imported when no sources are configured
+var DefaultMasterlists = MasterlistSources{
+	{
+		Name:      "sa-mp",
+		URL:       "http://lists.sa-mp.com/0.3.7/servers",
+		Format:    MasterlistText,
+		Interval:  time.Hour,
+		Tolerance: DefaultMasterlistTolerance,
+	},
+}
+
+// UnmarshalJSON reads the interval as a duration string such as "30m" and fills in the defaults
+func (source *MasterlistSource) UnmarshalJSON(data []byte) (err error) {
+	type plain MasterlistSource
+	aux := struct {
+		*plain
+		Interval  string   `json:"interval"`
+		Tolerance *float64 `json:"tolerance"`
+	}{plain: (*plain)(source)}
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return
+	}
+
+	source.Interval = time.Hour
+	if aux.Interval != "" {
+		if source.Interval, err = time.ParseDuration(aux.Interval); err != nil {
+			return errors.Wrapf(err, "invalid interval for masterlist '%s'", source.Name)
+		}
+	}
+	source.Tolerance = DefaultMasterlistTolerance
+	if aux.Tolerance != nil {
+		source.Tolerance = *aux.Tolerance
+	}
+	return source.Validate()
+}
+
+// Validate checks a source can be imported
+func (source MasterlistSource) Validate() error {
+	if source.Name == "" {
+		return errors.New("masterlist has no name")
+	}
+	if source.URL == "" {
+		return errors.Errorf("masterlist '%s' has no url", source.Name)
+	}
+	switch source.Format {
+	case MasterlistText, MasterlistHosted, MasterlistOpenMP:
+	case MasterlistJSON:
+		if source.Field == "" {
+			return errors.Errorf("masterlist '%s' has no field for its addresses", source.Name)
+		}
+	default:
+		return errors.Errorf("masterlist '%s' has unknown format '%s'", source.Name, source.Format)
+	}
+	if source.Interval <= 0 {
+		return errors.Errorf("masterlist '%s' has no interval", source.Name)
+	}
+	if source.Tolerance < 0 || source.Tolerance > 1 {
+		return errors.Errorf("masterlist '%s' tolerance must be between 0 and 1", source.Name)
+	}
+	return nil
+}
+
+// MasterlistSources is configured as a JSON array of sources
+type MasterlistSources []MasterlistSource
+
+// Decode implements envconfig.Decoder
+func (sources *MasterlistSources) Decode(value string) (err error) {
+	var decoded []MasterlistSource
+	if err = json.Unmarshal([]byte(value), &decoded); err != nil {
+		return errors.Wrap(err, "failed to decode masterlists")
+	}
+
+	names := make(map[string]bool)
+	for _, source := range decoded {
+		if names[source.Name] {
+			return errors.Errorf("masterlist '%s' is configured twice", source.Name)
+		}
+		names[source.Name] = true
+	}
+	*sources = decoded
+	return nil
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasterlistSourcesDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		want      MasterlistSources
+		wantError bool
+	}{
+		{
+			"defaults",
+			`[{"name": "open.mp", "url": "https://api.open.mp/servers", "format": "openmp"}]`,
+			MasterlistSources{{
+				Name:      "open.mp",
+				URL:       "https://api.open.mp/servers",
+				Format:    MasterlistOpenMP,
+				Interval:  time.Hour,
+				Tolerance: DefaultMasterlistTolerance,
+			}},
+			false,
+		},
+		{
+			"json",
+			`[{"name": "other", "url": "http://other/list", "format": "json", "path": "data", "field": "addr", "interval": "15m", "tolerance": 0}]`,
+			MasterlistSources{{
+				Name:     "other",
+				URL:      "http://other/list",
+				Format:   MasterlistJSON,
+				Interval: time.Minute * 15,
+				Path:     "data",
+				Field:    "addr",
+			}},
+			false,
+		},
+		{"json without field", `[{"name": "other", "url": "http://other/list", "format": "json"}]`, nil, true},
+		{"unknown format", `[{"name": "a", "url": "http://a", "format": "xml"}]`, nil, true},
+		{"bad interval", `[{"name": "a", "url": "http://a", "format": "text", "interval": "soon"}]`, nil, true},
+		{"duplicate", `[{"name": "a", "url": "http://a", "format": "text"}, {"name": "a", "url": "http://b", "format": "text"}]`, nil, true},
+		{"malformed", `{`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got MasterlistSources
+			err := got.Decode(tt.value)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
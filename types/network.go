@@ -0,0 +1,66 @@
+package types
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Networks is a set of IP ranges, configured as a comma separated list of ranges or single IPs
+type Networks []*net.IPNet
+
+// Decode implements envconfig.Decoder
+func (networks *Networks) Decode(value string) error {
+	var decoded Networks
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		network, err := parseCIDR(part)
+		if err != nil {
+			return err
+		}
+		decoded = append(decoded, network)
+	}
+	*networks = decoded
+	return nil
+}
+
+// Contains checks whether an IP is inside any of the ranges
+func (networks Networks) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP returns the IP of the client. X-Forwarded-For is only used when the request came from
+// one of the networks, a trusted proxy, it's read from the closest hop back to the first one that
+// isn't a trusted proxy since anything before that could have been sent by the client.
+func (networks Networks) RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !networks.Contains(net.ParseIP(host)) {
+		return host
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !networks.Contains(net.ParseIP(hop)) {
+			return hop
+		}
+	}
+	return host
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworks(t *testing.T) {
+	var networks Networks
+	assert.NoError(t, networks.Decode("10.0.0.0/8, 192.168.1.2,"))
+	assert.Len(t, networks, 2)
+
+	assert.True(t, networks.Contains(net.ParseIP("10.1.2.3")))
+	assert.True(t, networks.Contains(net.ParseIP("192.168.1.2")))
+	assert.False(t, networks.Contains(net.ParseIP("192.168.1.3")))
+	assert.False(t, networks.Contains(nil))
+
+	assert.Error(t, networks.Decode("proxy.example.com"))
+}
+
+func TestNetworks_RemoteIP(t *testing.T) {
+	var networks Networks
+	assert.NoError(t, networks.Decode("10.0.0.0/8"))
+
+	request := func(remote, forwarded string) *http.Request {
+		r := &http.Request{RemoteAddr: remote, Header: http.Header{}}
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		return r
+	}
+
+	assert.Equal(t, "1.2.3.4", networks.RemoteIP(request("1.2.3.4:5000", "")))
+	assert.Equal(t, "1.2.3.4", networks.RemoteIP(request("1.2.3.4:5000", "5.6.7.8")), "untrusted clients can't forward")
+	assert.Equal(t, "5.6.7.8", networks.RemoteIP(request("10.0.0.1:5000", "5.6.7.8")))
+	assert.Equal(t, "5.6.7.8", networks.RemoteIP(request("10.0.0.1:5000", "9.9.9.9, 5.6.7.8, 10.0.0.2")), "spoofed hops are skipped")
+	assert.Equal(t, "10.0.0.1", networks.RemoteIP(request("10.0.0.1:5000", "")))
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Platform is the server software a server runs, detected from its responses to queries
+type Platform string
+
+const (
+	// PlatformSAMP is the original SA:MP server and anything that can't be told apart from it
+	PlatformSAMP Platform = "samp"
+	// PlatformOpenMP is open.mp, which also answers the 'o' query with links for the server
+	PlatformOpenMP Platform = "openmp"
+	// PlatformSAMPDL is SA:MP 0.3.DL, which adds custom models to 0.3.7
+	PlatformSAMPDL Platform = "samp-dl"
+)
+
+// Platforms lists every platform that can be detected
+var Platforms = []Platform{PlatformSAMP, PlatformOpenMP, PlatformSAMPDL}
+
+// Keys of the `Extra` map on a server
+const (
+	ExtraDiscord     = "discord"
+	ExtraBannerLight = "banner_light"
+	ExtraBannerDark  = "banner_dark"
+	ExtraLogo        = "logo"
+)
+
+// Valid checks the platform is one that can be detected
+func (platform Platform) Valid() bool {
+	for _, p := range Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// PlatformOf returns the platform of a server, servers stored before platforms were detected are
+// all SA:MP.
+func PlatformOf(server Server) Platform {
+	if server.Platform == "" {
+		return PlatformSAMP
+	}
+	return server.Platform
+}
+
+// filterPlatformPrefix is the prefix of filters that select servers by platform
+const filterPlatformPrefix = "platform:"
+
+// FilterPlatform returns a filter that only keeps servers running a platform, when several are
+// used servers running any of the platforms are kept.
+func FilterPlatform(platform Platform) FilterAttribute {
+	return FilterAttribute(filterPlatformPrefix + string(platform))
+}
+
+// Platform returns the platform of a `platform:` filter
+func (filter FilterAttribute) Platform() (platform Platform, ok bool) {
+	if !strings.HasPrefix(string(filter), filterPlatformPrefix) {
+		return "", false
+	}
+	return Platform(strings.TrimPrefix(string(filter), filterPlatformPrefix)), true
+}
+
+// FilterPlatforms returns the platforms selected by a set of filters, nil means any platform. An
+// error is returned for platforms that can't be detected since those filters would never match.
+func FilterPlatforms(filters []FilterAttribute) (platforms []Platform, err error) {
+	for _, filter := range filters {
+		platform, ok := filter.Platform()
+		if !ok {
+			continue
+		}
+		if !platform.Valid() {
+			return nil, errors.Errorf("unknown platform '%s'", platform)
+		}
+		platforms = append(platforms, platform)
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterPlatforms(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []FilterAttribute
+		want    []Platform
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"other filters", []FilterAttribute{FilterPassword, FilterEmpty}, nil, false},
+		{"one", []FilterAttribute{FilterPassword, "platform:openmp"}, []Platform{PlatformOpenMP}, false},
+		{"several", []FilterAttribute{FilterPlatform(PlatformSAMP), FilterPlatform(PlatformSAMPDL)}, []Platform{PlatformSAMP, PlatformSAMPDL}, false},
+		{"unknown", []FilterAttribute{FilterPlatform(PlatformSAMP), "platform:foo"}, nil, true},
+		{"empty", []FilterAttribute{"platform:"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FilterPlatforms(tt.filters)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlatformOf(t *testing.T) {
+	assert.Equal(t, PlatformSAMP, PlatformOf(Server{}))
+	assert.Equal(t, PlatformOpenMP, PlatformOf(Server{Platform: PlatformOpenMP}))
+}
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// PlayerSighting records a server that a player name has been seen on. `Online` is true when the
+// name was in the server's most recent player list.
+type PlayerSighting struct {
+	Name     string    `json:"name"`
+	Address  string    `json:"address"`
+	LastSeen time.Time `json:"last_seen"`
+	Online   bool      `json:"online"`
+}
+
+// Example returns an example of PlayerSighting
+func (ps PlayerSighting) Example() PlayerSighting {
+	return PlayerSighting{
+		Name:     "Southclaws",
+		Address:  "127.0.0.1:7777",
+		LastSeen: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+		Online:   true,
+	}
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidateRegion checks a probe region name is a short lowercase identifier such as `eu-west`, the
+// name is used as a key in storage so nothing else is allowed.
+func ValidateRegion(region string) error {
+	if !regionPattern.MatchString(region) {
+		return errors.Errorf("invalid region '%s', must be lowercase letters, digits and dashes", region)
+	}
+	return nil
+}
+
+// RegionStatus is the reachability and latency of a server as last measured by a probe agent in
+// a particular region
+type RegionStatus struct {
+	Reachable bool      `json:"reachable"`
+	Ping      int       `json:"ping"` // median latency in milliseconds, 0 if never reachable
+	Updated   time.Time `json:"updated"`
+}
+
+// Example returns an example of RegionStatus
+func (rs RegionStatus) Example() RegionStatus {
+	return RegionStatus{
+		Reachable: true,
+		Ping:      112,
+		Updated:   time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// ProbeAssignments is the list of addresses a probe agent should query
+type ProbeAssignments struct {
+	Region    string   `json:"region"`
+	Addresses []string `json:"addresses"`
+}
+
+// Example returns an example of ProbeAssignments
+func (pa ProbeAssignments) Example() ProbeAssignments {
+	return ProbeAssignments{
+		Region:    "eu-west",
+		Addresses: []string{"127.0.0.1:7777", "127.0.0.2:7777"},
+	}
+}
+
+// ProbeResult is the outcome of querying one server from a probe agent
+type ProbeResult struct {
+	Address   string `json:"address"`
+	Reachable bool   `json:"reachable"`
+	Ping      int    `json:"ping"`
+}
+
+// ProbeReport is the set of results a probe agent sends back after querying its assignments
+type ProbeReport struct {
+	Region  string        `json:"region"`
+	Results []ProbeResult `json:"results"`
+}
+
+// Validate checks the region name and that the results are sensible
+func (pr ProbeReport) Validate() (errs []error) {
+	if err := ValidateRegion(pr.Region); err != nil {
+		errs = append(errs, err)
+	}
+	for _, result := range pr.Results {
+		if result.Address == "" {
+			errs = append(errs, errors.New("result has no address"))
+		}
+		if result.Ping < 0 {
+			errs = append(errs, errors.Errorf("result for '%s' has a negative ping", result.Address))
+		}
+	}
+	return
+}
+
+// Example returns an example of ProbeReport
+func (pr ProbeReport) Example() ProbeReport {
+	return ProbeReport{
+		Region: "eu-west",
+		Results: []ProbeResult{
+			{Address: "127.0.0.1:7777", Reachable: true, Ping: 112},
+			{Address: "127.0.0.2:7777", Reachable: false, Ping: 0},
+		},
+	}
+}
+
+// ProbeReportResult tells a probe agent which of its results were stored, results for servers that
+// were removed from the index since the assignments were fetched are ignored.
+type ProbeReportResult struct {
+	Accepted int      `json:"accepted"`
+	Ignored  []string `json:"ignored,omitempty"`
+}
+
+// Example returns an example of ProbeReportResult
+func (prr ProbeReportResult) Example() ProbeReportResult {
+	return ProbeReportResult{
+		Accepted: 1,
+		Ignored:  []string{"127.0.0.2:7777"},
+	}
+}
@@ -1,9 +1,13 @@
 package types
 
 import (
+	"math"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/dyninc/qstring"
+	"github.com/pkg/errors"
 )
 
 // -
@@ -35,6 +39,114 @@ const SortDesc SortOrder = "desc"
 // ByPlayers means the list will use the amount of players as a sort key
 const ByPlayers SortColumn = "player"
 
+// ByPing means the list will use the latency from the API host as a sort key, unlike the player
+// count this is sorted in ascending order unless a sort order is specified
+const ByPing SortColumn = "ping"
+
+// ByHostname sorts alphabetically by hostname ignoring case, ascending unless specified
+const ByHostname SortColumn = "hostname"
+
+// ByMaxPlayers sorts by the number of player slots
+const ByMaxPlayers SortColumn = "maxplayers"
+
+// ByFill sorts by the fraction of player slots in use, servers without any slots count as empty
+const ByFill SortColumn = "fill"
+
+// ByFirstSeen sorts by when the server first responded to a query, newest first unless specified
+const ByFirstSeen SortColumn = "firstseen"
+
+// ByUpdated sorts by when the server last responded to a query
+const ByUpdated SortColumn = "updated"
+
+// ByUptime sorts by the percentage of recent queries the server answered
+const ByUptime SortColumn = "uptime"
+
+// SortColumns is every column listings can be sorted by
+var SortColumns = []SortColumn{ByPlayers, ByPing, ByHostname, ByMaxPlayers, ByFill, ByFirstSeen, ByUpdated, ByUptime}
+
+// SortOptions validates the sort arguments of a listing and returns the column and direction. The
+// ping and hostname are sorted in ascending order and every other column in descending order
+// unless specified.
+func SortOptions(sort SortOrder, by SortColumn) (column SortColumn, desc bool, err error) {
+	if by == "" {
+		by = ByPlayers
+	}
+	for _, c := range SortColumns {
+		if by == c {
+			column = c
+		}
+	}
+	if column == "" {
+		err = errors.Errorf("invalid 'by' argument '%s'", by)
+		return
+	}
+
+	switch sort {
+	case "":
+		desc = column != ByPing && column != ByHostname
+	case SortDesc:
+		desc = true
+	case SortAsc:
+		desc = false
+	default:
+		err = errors.Errorf("invalid 'sort' argument '%s'", sort)
+	}
+	return
+}
+
+// SortKey is the value of a server's sort column, only the field for the column's type is set.
+// Ties are always broken by the address so the order is stable.
+type SortKey struct {
+	Number float64   `json:"n,omitempty"` // player counts, ping, fill and uptime
+	Text   string    `json:"s,omitempty"` // lower case hostname
+	Time   time.Time `json:"t"`           // first seen and updated, left out of cursors when zero
+}
+
+// UnmeasuredPing returns the sort key of servers whose latency hasn't been measured yet, it's outside
+// the range of real measurements so those servers come last in either direction.
+func UnmeasuredPing(desc bool) float64 {
+	if desc {
+		return -1
+	}
+	return math.MaxInt32
+}
+
+// SortValue returns the value of a server used to sort by a validated column in a direction
+func SortValue(core ServerCore, column SortColumn, desc bool) SortKey {
+	switch column {
+	case ByPing:
+		if core.Ping <= 0 {
+			return SortKey{Number: UnmeasuredPing(desc)}
+		}
+		return SortKey{Number: float64(core.Ping)}
+	case ByHostname:
+		return SortKey{Text: strings.ToLower(core.Hostname)}
+	case ByMaxPlayers:
+		return SortKey{Number: float64(core.MaxPlayers)}
+	case ByFill:
+		return SortKey{Number: core.Fill()}
+	case ByFirstSeen:
+		return SortKey{Time: core.FirstSeen}
+	case ByUpdated:
+		return SortKey{Time: core.Updated}
+	case ByUptime:
+		return SortKey{Number: core.Uptime}
+	}
+	return SortKey{Number: float64(core.Players)}
+}
+
+// Compare returns -1, 0 or 1 when the key is less than, equal to or greater than another key for
+// the same column
+func (k SortKey) Compare(other SortKey) int {
+	switch {
+	case k.Number < other.Number, k.Text < other.Text, k.Time.Before(other.Time):
+		return -1
+	case k.Number > other.Number, k.Text > other.Text, k.Time.After(other.Time):
+		return 1
+	}
+	return 0
+}
+
 // -
 // Filtering
 // -
@@ -51,6 +163,9 @@ const FilterEmpty FilterAttribute = "empty"
 // FilterFull filters out full servers
 const FilterFull FilterAttribute = "full"
 
+// FilterSuspicious filters out servers flagged as possibly faking their player count
+const FilterSuspicious FilterAttribute = "suspicious"
+
 // -
 // URL Query
 // -
@@ -71,7 +186,7 @@ func (slp ServerListParams) Example() (result url.Values) {
 		Page:     2,
 		PageSize: 100,
 		Sort:     SortAsc,
-		By:       ByPlayers,
+		By:       ByHostname,
 		Filters:  []FilterAttribute{FilterFull, FilterPassword},
 	})
 	if err != nil {
@@ -79,3 +194,13 @@ func (slp ServerListParams) Example() (result url.Values) {
 	}
 	return
 }
+
+// ServerQuery selects a page of servers for cursor pagination, the servers are those that come after
+// the cursor when ordered by the sort column then the address
+type ServerQuery struct {
+	Limit   int
+	Sort    SortOrder
+	By      SortColumn
+	Filters []FilterAttribute
+	After   *Cursor
+}
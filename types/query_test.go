@@ -0,0 +1,58 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortOptions(t *testing.T) {
+	tests := []struct {
+		sort       SortOrder
+		by         SortColumn
+		wantColumn SortColumn
+		wantDesc   bool
+		wantErr    bool
+	}{
+		{"", "", ByPlayers, true, false},
+		{"", ByPing, ByPing, false, false},
+		{"", ByHostname, ByHostname, false, false},
+		{"", ByFill, ByFill, true, false},
+		{SortAsc, ByUptime, ByUptime, false, false},
+		{SortDesc, ByHostname, ByHostname, true, false},
+		{"", "colour", "", false, true},
+		{"sideways", ByFirstSeen, ByFirstSeen, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.sort)+" "+string(tt.by), func(t *testing.T) {
+			column, desc, err := SortOptions(tt.sort, tt.by)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantColumn, column)
+			assert.Equal(t, tt.wantDesc, desc)
+		})
+	}
+}
+
+func TestSortValue(t *testing.T) {
+	seen := time.Date(2018, 3, 10, 18, 30, 0, 0, time.UTC)
+	core := ServerCore{Hostname: "Los Santos RP", Players: 30, MaxPlayers: 120, FirstSeen: seen, Uptime: 98.5}
+
+	assert.Equal(t, SortKey{Number: 30}, SortValue(core, ByPlayers, false))
+	assert.Equal(t, SortKey{Text: "los santos rp"}, SortValue(core, ByHostname, false))
+	assert.Equal(t, SortKey{Number: 0.25}, SortValue(core, ByFill, false))
+	assert.Equal(t, SortKey{Time: seen}, SortValue(core, ByFirstSeen, false))
+	assert.Equal(t, SortKey{Number: 98.5}, SortValue(core, ByUptime, false))
+	assert.Equal(t, SortKey{}, SortValue(ServerCore{}, ByFill, false))
+	assert.Equal(t, SortKey{Number: 48}, SortValue(ServerCore{Ping: 48}, ByPing, false))
+	assert.Equal(t, SortKey{Number: UnmeasuredPing(false)}, SortValue(ServerCore{}, ByPing, false))
+	assert.Equal(t, SortKey{Number: UnmeasuredPing(true)}, SortValue(ServerCore{}, ByPing, true))
+
+	assert.Equal(t, -1, SortKey{Text: "a"}.Compare(SortKey{Text: "b"}))
+	assert.Equal(t, 1, SortKey{Time: seen}.Compare(SortKey{Time: seen.Add(-time.Second)}))
+	assert.Equal(t, 0, SortKey{Number: 2}.Compare(SortKey{Number: 2}))
+}
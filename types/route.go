@@ -5,7 +5,8 @@ import (
 	"net/url"
 )
 
-// Route represents an API route and its associated handler function
+// Route represents an API route and its associated handler function. Routes with a Scope can only
+// be used with an API key that grants it.
 type Route struct {
 	Name        string           `json:"name"`
 	Method      string           `json:"method"`
@@ -14,6 +15,7 @@ type Route struct {
 	Params      url.Values       `json:"params"`
 	Accepts     interface{}      `json:"accepts"`
 	Returns     interface{}      `json:"returns"`
+	Scope       Scope            `json:"scope,omitempty"`
 	Handler     http.HandlerFunc `json:"-"`
 }
 
@@ -22,3 +24,9 @@ type RouteHandler interface {
 	Version() string
 	Routes() []Route
 }
+
+// ErrorWriter is implemented by route groups with their own error format, errors from authenticating
+// requests to the group are written with it too
+type ErrorWriter interface {
+	WriteError(w http.ResponseWriter, r *http.Request, status int, err error)
+}
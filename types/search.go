@@ -0,0 +1,28 @@
+package types
+
+// SearchResult is a server that matched a search, results are ordered by Score which combines how
+// well the server matched with how many players it has
+type SearchResult struct {
+	Core       ServerCore  `json:"core"`
+	Score      float64     `json:"score"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// Example returns an example of SearchResult
+func (sr SearchResult) Example() SearchResult {
+	return SearchResult{
+		Core:  Server{}.Example().Core,
+		Score: 4.2,
+		Highlights: []Highlight{
+			{Field: "gamemode", Text: "Grand Larceny", Matches: [][2]int{{6, 13}}},
+		},
+	}
+}
+
+// Highlight is a field of a server that matched a search. Text is the field with SA:MP colour codes
+// removed and each match is the start and end byte offset of a matched word in Text.
+type Highlight struct {
+	Field   string   `json:"field"`
+	Text    string   `json:"text"`
+	Matches [][2]int `json:"matches"`
+}
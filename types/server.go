@@ -1,31 +1,85 @@
 package types
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 )
 
 // Server contains all the information associated with a game server including the core information, the standard SA:MP
 // "rules" and "players" lists as well as any additional fields to enhance the server browsing experience.
 type Server struct {
-	IP          string            `json:"ip"`
-	Core        ServerCore        `json:"core"`
-	Rules       map[string]string `json:"ru,omitempty"`
-	Description string            `json:"description"`
-	Banner      string            `json:"banner"`
-	Active      bool              `json:"active"`
+	IP          string                  `json:"ip"`
+	Core        ServerCore              `json:"core"`
+	Rules       map[string]string       `json:"ru,omitempty"`
+	PlayerList  *PlayerList             `json:"pl,omitempty"`
+	Description string                  `json:"description"`
+	Banner      string                  `json:"banner"`
+	Active      bool                    `json:"active"`
+	Suspicious  []Suspicion             `json:"suspicious,omitempty"`
+	Regions     map[string]RegionStatus `json:"regions,omitempty"`
+	Platform    Platform                `json:"platform,omitempty"`
+	Extra       map[string]string       `json:"extra,omitempty"` // information only some platforms provide, such as a Discord invite
+}
+
+// PlayerList stores the result of the SA:MP 'd' (detailed players) query. SA:MP refuses to send the
+// list when more than 100 players are online so `Available` is false in that case and the list is
+// empty.
+type PlayerList struct {
+	Available bool     `json:"available"`
+	Players   []Player `json:"players"`
+}
+
+// Player is a single entry in a server's player list
+type Player struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	Ping  int    `json:"ping"`
+}
+
+// Names returns the name of each player in the list
+func (pl PlayerList) Names() (names []string) {
+	for _, player := range pl.Players {
+		names = append(names, player.Name)
+	}
+	return
+}
+
+// Example returns an example of PlayerList
+func (pl PlayerList) Example() PlayerList {
+	return PlayerList{
+		Available: true,
+		Players: []Player{
+			{ID: 0, Name: "Southclaws", Score: 120, Ping: 32},
+			{ID: 1, Name: "Y_Less", Score: 64, Ping: 80},
+		},
+	}
 }
 
 // ServerCore stores the standard SA:MP 'info' query fields necessary for server lists. The json keys are short to cut down on
 // network traffic since these are the objects returned to a listing request which could contain hundreds of objects.
 type ServerCore struct {
-	Address    string `json:"ip"`
-	Hostname   string `json:"hn"`
-	Players    int    `json:"pc"`
-	MaxPlayers int    `json:"pm"`
-	Gamemode   string `json:"gm"`
-	Language   string `json:"la"`
-	Password   bool   `json:"pa"`
-	Version    string `json:"vn"`
+	Address    string    `json:"ip"`
+	Hostname   string    `json:"hn"`
+	Players    int       `json:"pc"`
+	MaxPlayers int       `json:"pm"`
+	Gamemode   string    `json:"gm"`
+	Language   string    `json:"la"`
+	Password   bool      `json:"pa"`
+	Version    string    `json:"vn"`
+	Ping       int       `json:"pg"` // median latency from the API host in milliseconds, 0 until measured
+	FirstSeen  time.Time `json:"fs"` // when the server first responded to a query, kept when it's archived and revived
+	Updated    time.Time `json:"lu"` // when the server last responded to a query
+	Uptime     float64   `json:"ut"` // percentage of recent queries the server answered
+}
+
+// Fill returns the fraction of player slots in use, 0 for servers without any slots
+func (core ServerCore) Fill() float64 {
+	if core.MaxPlayers <= 0 {
+		return 0
+	}
+	return float64(core.Players) / float64(core.MaxPlayers)
 }
 
 // Validate checks the contents of a Server object to ensure all the required fields are valid.
@@ -60,6 +114,10 @@ func (server Server) Example() Server {
 			Language:   "English",
 			Password:   false,
 			Version:    "0.3.7-R2",
+			Ping:       48,
+			FirstSeen:  time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+			Updated:    time.Date(2018, 3, 10, 18, 30, 0, 0, time.UTC),
+			Uptime:     99.5,
 		},
 		Rules: map[string]string{
 			"lagcomp":   "On",
@@ -72,5 +130,9 @@ func (server Server) Example() Server {
 		Description: "An awesome server! Come and play with us.",
 		Banner:      "https://i.imgur.com/Juaezhv.jpg",
 		Active:      true,
+		Regions: map[string]RegionStatus{
+			"eu-west": RegionStatus{}.Example(),
+		},
+		Platform: PlatformSAMP,
 	}
 }
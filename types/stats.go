@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // Statistics represents a set of simple metrics for the entire listing database
 type Statistics struct {
 	Servers          int     `json:"servers"`
@@ -15,3 +17,18 @@ func (s Statistics) Example() Statistics {
 		PlayersPerServer: 10,
 	}
 }
+
+// StatisticsSample is a point in the history of the index statistics, each value is the mean over
+// the period that the sample covers.
+type StatisticsSample struct {
+	Time time.Time `json:"t"`
+	Statistics
+}
+
+// Example returns an example of StatisticsSample
+func (s StatisticsSample) Example() StatisticsSample {
+	return StatisticsSample{
+		Time:       time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		Statistics: Statistics{}.Example(),
+	}
+}
@@ -0,0 +1,13 @@
+package types
+
+// Suspicion is the reason a server has been flagged as possibly faking its player count
+type Suspicion string
+
+const (
+	// SuspicionPlayerList means the player count doesn't match the number of players in the list
+	SuspicionPlayerList Suspicion = "player_list_mismatch"
+	// SuspicionFlatCurve means the player count hasn't changed at all for hours
+	SuspicionFlatCurve Suspicion = "flat_curve"
+	// SuspicionSharedIP means several servers on the same IP report identical player counts
+	SuspicionSharedIP Suspicion = "shared_ip"
+)